@@ -0,0 +1,113 @@
+// Package main is a pre-flight check that verifies the database is reachable
+// (and optionally that all migrations are applied) without starting the server.
+//
+// It loads the same configuration as the server, runs the readiness checks
+// once, prints the result and exits with status 0 if everything is healthy
+// and 1 otherwise. Use it as a CI gate or Kubernetes init container.
+//
+// Usage:
+//
+//	go run ./cmd/check                          # database only
+//	go run ./cmd/check -migrations              # also fail on pending migrations
+//	go run ./cmd/check -wait 60s -migrations    # retry the connection for up to 60s
+//
+// The connection is retried DB_CONNECT_ATTEMPTS times like the server's,
+// unless -wait is given.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"replace-me/internal/config"
+	"replace-me/internal/database"
+	"replace-me/internal/health"
+	"replace-me/migrations"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+)
+
+func main() {
+	checkMigrations := flag.Bool("migrations", false, "also fail if there are pending migrations")
+	wait := flag.Duration("wait", 0, "keep retrying the database connection for this long (e.g. 30s), instead of DB_CONNECT_ATTEMPTS times")
+	timeout := flag.Duration("timeout", 10*time.Second, "time allowed for the checks once connected")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatalf("Invalid configuration:\n%v", err)
+	}
+
+	database.OnConnect(database.SessionStatements(cfg.DBApplicationName, cfg.DBSearchPath)...)
+	db, err := connect(cfg, *wait)
+	if err != nil {
+		fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close(db)
+
+	checkers := []health.Checker{health.NewDatabaseChecker(db)}
+	if *checkMigrations {
+		migrator := migrate.NewMigrator(db, migrations.Migrations)
+		checkers = append(checkers, health.NewMigrationsChecker(migrator, 0))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	report := health.Run(ctx, checkers...)
+	printReport(report)
+
+	if !report.Healthy() {
+		// Run deferred cleanup before exiting with a failure status
+		database.Close(db)
+		os.Exit(1)
+	}
+}
+
+// connect opens the database connection with the server's pool and retry
+// settings. A non-zero wait replaces DB_CONNECT_ATTEMPTS: attempts are made
+// about every second until wait has elapsed.
+func connect(cfg *config.Config, wait time.Duration) (*bun.DB, error) {
+	retry := database.RetryOptions{MaxAttempts: cfg.DBConnectAttempts}
+	if wait > 0 {
+		retry = database.RetryOptions{
+			MaxAttempts: int(wait/time.Second) + 1,
+			BaseDelay:   time.Second,
+			MaxDelay:    time.Second,
+		}
+	}
+	return database.NewWithRetry(cfg.DatabaseURL, database.QueryLogOff, database.PoolOptions{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+	}, retry)
+}
+
+// printReport prints one line per check, in a stable order.
+func printReport(report health.Report) {
+	names := make([]string, 0, len(report.Checks))
+	for name := range report.Checks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		result := report.Checks[name]
+		if result.Status == health.StatusHealthy {
+			fmt.Printf("  ✓ %s\n", name)
+		} else {
+			fmt.Printf("  ✗ %s: %s\n", name, result.Error)
+		}
+	}
+	fmt.Println(report.Status)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(1)
+}
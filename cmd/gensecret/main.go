@@ -0,0 +1,155 @@
+// Package main prints a random secret suitable for SESSION_SECRET.
+//
+// The secret is 32 bytes (or -bytes) from crypto/rand, base64-encoded. With
+// -env it is also written to the .env file as SESSION_SECRET, if the file
+// exists and doesn't already set a real one. The development default from
+// .env.example and values shorter than 32 bytes are replaced; any other
+// existing value is left alone.
+//
+// Usage:
+//
+//	go run ./cmd/gensecret               # print a secret
+//	go run ./cmd/gensecret -bytes 64     # print a longer secret
+//	go run ./cmd/gensecret -env .env     # also write it to .env if unset or insecure
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// minSecretBytes is the shortest secret accepted, matching what config
+// validation requires of SESSION_SECRET in production.
+const minSecretBytes = 32
+
+// secretKey is the variable written to the .env file.
+const secretKey = "SESSION_SECRET"
+
+// devSecret is the SESSION_SECRET shipped in .env.example and used by
+// config when it is unset. A .env copied from the example still has it.
+const devSecret = "dev-secret-key-change-in-production-123"
+
+// envUpdate is what addToEnvFile did to the .env file.
+type envUpdate int
+
+const (
+	secretKept     envUpdate = iota // the file already sets a usable secret
+	secretAdded                     // the file didn't set one; appended
+	secretReplaced                  // the file set an insecure one; replaced
+)
+
+func main() {
+	size := flag.Int("bytes", minSecretBytes, "random bytes in the secret (at least 32)")
+	envFile := flag.String("env", "", "write the secret to this .env file, if it exists and doesn't set a secure "+secretKey)
+	flag.Parse()
+
+	if *size < minSecretBytes {
+		fatalf("-bytes must be at least %d", minSecretBytes)
+	}
+
+	secret, err := generateSecret(*size)
+	if err != nil {
+		fatalf("Failed to generate secret: %v", err)
+	}
+	fmt.Println(secret)
+
+	if *envFile != "" {
+		update, err := addToEnvFile(*envFile, secret)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			fmt.Fprintf(os.Stderr, "%s does not exist; not written\n", *envFile)
+		case err != nil:
+			fatalf("Failed to update %s: %v", *envFile, err)
+		case update == secretAdded:
+			fmt.Fprintf(os.Stderr, "Added %s to %s\n", secretKey, *envFile)
+		case update == secretReplaced:
+			fmt.Fprintf(os.Stderr, "Replaced the insecure %s in %s\n", secretKey, *envFile)
+		default:
+			fmt.Fprintf(os.Stderr, "%s already sets %s; left unchanged\n", *envFile, secretKey)
+		}
+	}
+}
+
+// generateSecret returns size random bytes, base64-encoded.
+func generateSecret(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// addToEnvFile writes SESSION_SECRET=secret to the env file at path. The
+// entry is appended if the file doesn't set SESSION_SECRET, and replaces
+// the existing one if it is insecure (see insecureSecret). A missing file
+// is an fs.ErrNotExist error.
+func addToEnvFile(path, secret string) (envUpdate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return secretKept, err
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		export := strings.HasPrefix(trimmed, "export ")
+		key, value, ok := strings.Cut(strings.TrimPrefix(trimmed, "export "), "=")
+		if !ok || strings.TrimSpace(key) != secretKey {
+			continue
+		}
+		if !insecureSecret(value) {
+			return secretKept, nil
+		}
+
+		entry := secretKey + "=" + secret
+		if export {
+			entry = "export " + entry
+		}
+		if strings.HasSuffix(line, "\n") {
+			entry += "\n"
+		}
+		lines[i] = entry
+		info, err := os.Stat(path)
+		if err != nil {
+			return secretKept, err
+		}
+		return secretReplaced, os.WriteFile(path, []byte(strings.Join(lines, "")), info.Mode().Perm())
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return secretKept, err
+	}
+	entry := secretKey + "=" + secret + "\n"
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		entry = "\n" + entry
+	}
+	if _, err := f.WriteString(entry); err != nil {
+		f.Close()
+		return secretKept, err
+	}
+	return secretAdded, f.Close()
+}
+
+// insecureSecret reports whether value, as written in a .env file, is the
+// development default or shorter than minSecretBytes, so the file is
+// treated as not setting a secret.
+func insecureSecret(value string) bool {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return value == devSecret || len(value) < minSecretBytes
+}
+
+// fatalf prints an error message and exits.
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddToEnvFile(t *testing.T) {
+	const secret = "bmV3LXNlY3JldC0wMTIzNDU2Nzg5YWJjZGVmMDEyMzQ1Ng=="
+	const existing = "ZXhpc3Rpbmctc2VjcmV0LTAxMjM0NTY3ODlhYmNkZWY="
+
+	tests := []struct {
+		name       string
+		content    string
+		want       string
+		wantUpdate envUpdate
+	}{
+		{
+			name:       "unset",
+			content:    "PORT=8090\n",
+			want:       "PORT=8090\nSESSION_SECRET=" + secret + "\n",
+			wantUpdate: secretAdded,
+		},
+		{
+			name:       "unset, no trailing newline",
+			content:    "PORT=8090",
+			want:       "PORT=8090\nSESSION_SECRET=" + secret + "\n",
+			wantUpdate: secretAdded,
+		},
+		{
+			name:       "development default from .env.example",
+			content:    "PORT=8090\nSESSION_SECRET=" + devSecret + "\nLOG_LEVEL=debug\n",
+			want:       "PORT=8090\nSESSION_SECRET=" + secret + "\nLOG_LEVEL=debug\n",
+			wantUpdate: secretReplaced,
+		},
+		{
+			name:       "short secret, quoted and exported",
+			content:    "export SESSION_SECRET=\"changeme\"",
+			want:       "export SESSION_SECRET=" + secret,
+			wantUpdate: secretReplaced,
+		},
+		{
+			name:       "empty value",
+			content:    "SESSION_SECRET=\n",
+			want:       "SESSION_SECRET=" + secret + "\n",
+			wantUpdate: secretReplaced,
+		},
+		{
+			name:       "secure secret kept",
+			content:    "SESSION_SECRET=" + existing + "\n",
+			want:       "SESSION_SECRET=" + existing + "\n",
+			wantUpdate: secretKept,
+		},
+		{
+			name:       "secret file variable is not the secret",
+			content:    "SESSION_SECRET_FILE=/run/secrets/session\n",
+			want:       "SESSION_SECRET_FILE=/run/secrets/session\nSESSION_SECRET=" + secret + "\n",
+			wantUpdate: secretAdded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			update, err := addToEnvFile(path, secret)
+			if err != nil {
+				t.Fatalf("addToEnvFile: %v", err)
+			}
+			if update != tt.wantUpdate {
+				t.Errorf("update = %d, want %d", update, tt.wantUpdate)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("file:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
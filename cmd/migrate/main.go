@@ -1,16 +1,25 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"replace-me/internal/config"
 	"replace-me/internal/database"
 	"replace-me/migrations"
 
+	"github.com/uptrace/bun/driver/pgdriver"
 	"github.com/uptrace/bun/migrate"
 )
 
@@ -20,8 +29,14 @@ func main() {
 		os.Exit(1)
 	}
 
-	cfg := config.Load()
-	db, err := database.New(cfg.DatabaseURL, false)
+	cfg, err := config.Load()
+	if err != nil {
+		fatalf("Invalid configuration:\n%v", err)
+	}
+	database.OnConnect(database.SessionStatements(cfg.DBApplicationName, cfg.DBSearchPath)...)
+	db, err := database.NewWithRetry(cfg.DatabaseURL, database.QueryLogOff, database.PoolOptions{}, database.RetryOptions{
+		MaxAttempts: cfg.DBConnectAttempts,
+	})
 	if err != nil {
 		fatalf("Failed to connect to database: %v", err)
 	}
@@ -30,16 +45,21 @@ func main() {
 	migrator := migrate.NewMigrator(db, migrations.Migrations)
 	ctx := context.Background()
 
-	if err := migrator.Init(ctx); err != nil {
-		fatalf("Failed to initialize migrator: %v", err)
+	// A dry run must not write to the database, and Init creates Bun's
+	// migration tables when they don't exist yet
+	dryRun := slices.ContainsFunc(os.Args[2:], isDryRunFlag)
+	if !dryRun {
+		if err := migrator.Init(ctx); err != nil {
+			fatalf("Failed to initialize migrator: %v", err)
+		}
 	}
 
 	cmd := os.Args[1]
 	switch cmd {
 	case "up":
-		cmdUp(ctx, migrator)
+		cmdUp(ctx, migrator, cfg.MigrateLockTimeout, dryRun)
 	case "down":
-		cmdDown(ctx, migrator)
+		cmdDown(ctx, migrator, dryRun)
 	case "status":
 		cmdStatus(ctx, migrator)
 	case "create":
@@ -47,7 +67,9 @@ func main() {
 	case "delete":
 		cmdDelete(ctx, migrator)
 	case "redo":
-		cmdRedo(ctx, migrator)
+		cmdRedo(ctx, migrator, dryRun)
+	case "goto":
+		cmdGoto(ctx, migrator, cfg.MigrateLockTimeout)
 	case "lock":
 		cmdLock(ctx, migrator)
 	case "unlock":
@@ -66,15 +88,37 @@ func printUsage() {
 	fmt.Println("  up       Apply all pending migrations")
 	fmt.Println("  down     Rollback the last applied migration")
 	fmt.Println("  redo     Rollback and re-apply the last migration")
-	fmt.Println("  status   Show migration status")
-	fmt.Println("  create   Create a new migration (usage: migrate create <name>)")
+	fmt.Println("           up, down and redo accept --dry-run to print the SQL they would run")
+	fmt.Println("  goto     Apply or rollback migrations until the database is at a version")
+	fmt.Println("           (usage: migrate goto <version> [--dry-run]; version 0 rolls back all)")
+	fmt.Println("  status   Show migration status (--json for machine-readable output)")
+	fmt.Println("  create   Create a new migration (usage: migrate create [--go] <name>)")
+	fmt.Println("           --go creates a Go migration instead of .up.sql/.down.sql files")
 	fmt.Println("  delete   Delete an unapplied migration (usage: migrate delete <name>)")
 	fmt.Println("  lock     Show migration lock status")
 	fmt.Println("  unlock   Force unlock migrations (use with caution)")
 }
 
-func cmdUp(ctx context.Context, migrator *migrate.Migrator) {
+func cmdUp(ctx context.Context, migrator *migrate.Migrator, lockTimeout time.Duration, dryRun bool) {
+	if noMigrations() {
+		return
+	}
+	if dryRun {
+		printDryRun(ctx, migrator, "up")
+		return
+	}
+
+	// Hold the migration lock so concurrent runs (parallel CI jobs, replicas
+	// starting together) apply migrations one at a time. A run that had to
+	// wait finds them already applied and exits cleanly.
+	if err := lockMigrations(ctx, migrator, lockTimeout); err != nil {
+		fatalf("Failed to acquire migration lock: %v", err)
+	}
 	group, err := migrator.Migrate(ctx)
+	// Unlock before fatalf, which exits without running deferred calls
+	if unlockErr := migrator.Unlock(ctx); unlockErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to release migration lock: %v\n", unlockErr)
+	}
 	if err != nil {
 		fatalf("Migration failed: %v", err)
 	}
@@ -88,7 +132,47 @@ func cmdUp(ctx context.Context, migrator *migrate.Migrator) {
 	}
 }
 
-func cmdDown(ctx context.Context, migrator *migrate.Migrator) {
+// lockPollInterval is how often lockMigrations retries a held lock.
+const lockPollInterval = time.Second
+
+// lockMigrations acquires the migration lock, waiting up to timeout while
+// another process holds it. Any other error, such as a lost connection or
+// a missing locks table, is returned at once.
+func lockMigrations(ctx context.Context, migrator *migrate.Migrator, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	waiting := false
+	for {
+		err := migrator.Lock(ctx)
+		if err == nil || !isLockHeld(err) {
+			return err
+		}
+		if time.Now().Add(lockPollInterval).After(deadline) {
+			return fmt.Errorf("still locked after %s (if no migration is running, use 'migrate unlock'): %w", timeout, err)
+		}
+		if !waiting {
+			fmt.Println("Another migration is running, waiting for it to finish...")
+			waiting = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// isLockHeld reports whether a failed Lock means another process holds the
+// lock. bun locks by inserting a row into the locks table, so a held lock
+// is a unique violation (SQLSTATE 23505).
+func isLockHeld(err error) bool {
+	var pgErr pgdriver.Error
+	return errors.As(err, &pgErr) && pgErr.Field('C') == "23505"
+}
+
+func cmdDown(ctx context.Context, migrator *migrate.Migrator, dryRun bool) {
+	if noMigrations() {
+		return
+	}
+	if dryRun {
+		printDryRun(ctx, migrator, "down")
+		return
+	}
 	group, err := migrator.Rollback(ctx)
 	if err != nil {
 		fatalf("Rollback failed: %v", err)
@@ -103,7 +187,14 @@ func cmdDown(ctx context.Context, migrator *migrate.Migrator) {
 	}
 }
 
-func cmdRedo(ctx context.Context, migrator *migrate.Migrator) {
+func cmdRedo(ctx context.Context, migrator *migrate.Migrator, dryRun bool) {
+	if noMigrations() {
+		return
+	}
+	if dryRun {
+		printDryRun(ctx, migrator, "redo")
+		return
+	}
 	group, err := migrator.Rollback(ctx)
 	if err != nil {
 		fatalf("Rollback failed: %v", err)
@@ -121,41 +212,317 @@ func cmdRedo(ctx context.Context, migrator *migrate.Migrator) {
 	fmt.Printf("Re-applied: %s\n", group.Migrations[0].Name)
 }
 
+func cmdGoto(ctx context.Context, migrator *migrate.Migrator, lockTimeout time.Duration) {
+	var target string
+	dryRun := false
+	for _, arg := range os.Args[2:] {
+		switch {
+		case isDryRunFlag(arg):
+			dryRun = true
+		case target == "" && !strings.HasPrefix(arg, "-"):
+			target = arg
+		default:
+			fatalf("Usage: migrate goto <version> [--dry-run]")
+		}
+	}
+	if target == "" {
+		fatalf("Usage: migrate goto <version> [--dry-run]")
+	}
+	if noMigrations() {
+		return
+	}
+
+	// A dry run only reads the migration table, so it doesn't need the lock
+	if !dryRun {
+		if err := lockMigrations(ctx, migrator, lockTimeout); err != nil {
+			fatalf("Failed to acquire migration lock: %v", err)
+		}
+	}
+	err := gotoVersion(ctx, migrator, target, dryRun)
+	// Unlock before fatalf, which exits without running deferred calls
+	if !dryRun {
+		if unlockErr := migrator.Unlock(ctx); unlockErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to release migration lock: %v\n", unlockErr)
+		}
+	}
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// migrationStep is one operation of a goto plan.
+type migrationStep struct {
+	up        bool // apply; rollback otherwise
+	migration *migrate.Migration
+}
+
+// gotoVersion prints the steps that bring the database to target and,
+// unless dryRun is set, runs them.
+func gotoVersion(ctx context.Context, migrator *migrate.Migrator, target string, dryRun bool) error {
+	ms, err := migrationsWithStatus(ctx, migrator)
+	if err != nil {
+		return fmt.Errorf("failed to get migration status: %w", err)
+	}
+	steps, err := gotoPlan(ms, target)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		fmt.Printf("Already at %s, nothing to do\n", target)
+		return nil
+	}
+
+	fmt.Printf("Plan to reach %s (%d step(s)):\n", target, len(steps))
+	for _, step := range steps {
+		if step.up {
+			fmt.Printf("  ↑ apply     %s\n", step.migration)
+		} else {
+			fmt.Printf("  ↓ rollback  %s\n", step.migration)
+		}
+	}
+	if dryRun {
+		fmt.Println("\nDry run: no changes made")
+		return nil
+	}
+
+	// Migrations applied by this run form one group, like "migrate up",
+	// so "migrate down" rolls them back together.
+	groupID := ms.LastGroupID() + 1
+	fmt.Println()
+	for _, step := range steps {
+		m := step.migration
+		// Mark before running, as Bun's Migrate and Rollback do, so a
+		// failed step can be resolved with "migrate down" or "migrate up".
+		if step.up {
+			m.GroupID = groupID
+			if err := migrator.MarkApplied(ctx, m); err != nil {
+				return fmt.Errorf("failed to mark %s applied: %w", m.Name, err)
+			}
+			if m.Up != nil {
+				if err := m.Up(ctx, migrator, m); err != nil {
+					return fmt.Errorf("migration %s failed: %w", m.Name, err)
+				}
+			}
+			fmt.Printf("  ✓ %s\n", m)
+		} else {
+			if err := migrator.MarkUnapplied(ctx, m); err != nil {
+				return fmt.Errorf("failed to mark %s unapplied: %w", m.Name, err)
+			}
+			if m.Down != nil {
+				if err := m.Down(ctx, migrator, m); err != nil {
+					return fmt.Errorf("rollback of %s failed: %w", m.Name, err)
+				}
+			}
+			fmt.Printf("  ↩ %s\n", m)
+		}
+	}
+	fmt.Printf("\nDatabase is at %s\n", target)
+	return nil
+}
+
+// gotoPlan returns the steps that leave exactly the migrations up to and
+// including target applied. ms must be sorted oldest first, as returned by
+// MigrationsWithStatus. target is a migration name, with or without its
+// comment ("20240101120000" or "20240101120000_create_users"), or "0" for
+// none.
+//
+// Later migrations are rolled back first, newest applied first, then
+// missing migrations up to target are applied oldest first; this also
+// applies migrations skipped by an out-of-order merge.
+func gotoPlan(ms migrate.MigrationSlice, target string) ([]migrationStep, error) {
+	last := -1
+	if target != "0" {
+		for i, m := range ms {
+			if m.Name == target || m.String() == target {
+				last = i
+				break
+			}
+		}
+		if last < 0 {
+			return nil, fmt.Errorf("unknown migration version %q (see 'migrate status')", target)
+		}
+	}
+
+	var rollback []*migrate.Migration
+	for i := last + 1; i < len(ms); i++ {
+		if ms[i].IsApplied() {
+			rollback = append(rollback, &ms[i])
+		}
+	}
+	slices.SortFunc(rollback, func(a, b *migrate.Migration) int {
+		return cmp.Or(cmp.Compare(b.GroupID, a.GroupID), cmp.Compare(b.ID, a.ID))
+	})
+
+	steps := make([]migrationStep, 0, len(ms))
+	for _, m := range rollback {
+		steps = append(steps, migrationStep{migration: m})
+	}
+	for i := 0; i <= last; i++ {
+		if !ms[i].IsApplied() {
+			steps = append(steps, migrationStep{up: true, migration: &ms[i]})
+		}
+	}
+	return steps, nil
+}
+
+// isDryRunFlag reports whether arg is the --dry-run flag.
+func isDryRunFlag(arg string) bool {
+	return arg == "--dry-run" || arg == "-dry-run"
+}
+
+// printDryRun prints the migrations that command ("up", "down" or "redo")
+// would run, in order, with the SQL of each, without changing anything.
+func printDryRun(ctx context.Context, migrator *migrate.Migrator, command string) {
+	ms, err := migrationsWithStatus(ctx, migrator)
+	if err != nil {
+		fatalf("Failed to get migration status: %v", err)
+	}
+
+	// Mirror what Bun's Migrate and Rollback do: up applies every pending
+	// migration oldest first; down rolls back the last group newest first;
+	// redo does both, so pending migrations are applied too.
+	var steps []migrationStep
+	if command == "down" || command == "redo" {
+		group := ms.LastGroup()
+		for i := len(group.Migrations) - 1; i >= 0; i-- {
+			steps = append(steps, migrationStep{migration: &group.Migrations[i]})
+		}
+	}
+	if command == "up" || command == "redo" {
+		lastGroupID := ms.LastGroupID()
+		for i := range ms {
+			redone := command == "redo" && lastGroupID > 0 && ms[i].GroupID == lastGroupID
+			if !ms[i].IsApplied() || redone {
+				steps = append(steps, migrationStep{up: true, migration: &ms[i]})
+			}
+		}
+	}
+
+	if len(steps) == 0 {
+		fmt.Println("No migrations to run")
+	} else {
+		fmt.Printf("Would run %d migration step(s):\n", len(steps))
+	}
+	for _, step := range steps {
+		action := "rollback"
+		if step.up {
+			action = "apply"
+		}
+		file, contents, err := migrations.SQLFile(*step.migration, step.up)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			fmt.Printf("\n-- %s %s (no SQL file: a Go migration, or nothing to run)\n", action, step.migration)
+		case err != nil:
+			fatalf("Failed to read SQL for %s: %v", step.migration, err)
+		default:
+			fmt.Printf("\n-- %s %s (%s)\n", action, step.migration, file)
+			fmt.Println(strings.TrimRight(string(contents), "\n"))
+		}
+	}
+	fmt.Println("\ndry run, nothing applied")
+}
+
+// migrationsTable is the table Bun records applied migrations in.
+const migrationsTable = "bun_migrations"
+
+// migrationsWithStatus is migrator.MigrationsWithStatus, except that a
+// missing migrations table (a dry run skips Init, which creates it) means
+// nothing has been applied yet rather than an error.
+func migrationsWithStatus(ctx context.Context, migrator *migrate.Migrator) (migrate.MigrationSlice, error) {
+	var exists bool
+	err := migrator.DB().NewRaw("SELECT to_regclass(?) IS NOT NULL", migrationsTable).Scan(ctx, &exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return migrations.Migrations.Sorted(), nil
+	}
+	return migrator.MigrationsWithStatus(ctx)
+}
+
+// noMigrations reports (and prints) whether the migrations directory is still
+// empty. Bun's Migrate and Rollback return an error in that case, which isn't
+// useful on a fresh project.
+func noMigrations() bool {
+	if len(migrations.Migrations.Sorted()) > 0 {
+		return false
+	}
+	fmt.Println("No migrations found. Create one with: make migrate-create name=<name>")
+	return true
+}
+
 func cmdStatus(ctx context.Context, migrator *migrate.Migrator) {
-	ms, err := migrator.MigrationsWithStatus(ctx)
+	asJSON := false
+	for _, arg := range os.Args[2:] {
+		if arg != "--json" && arg != "-json" {
+			fatalf("Usage: migrate status [--json]")
+		}
+		asJSON = true
+	}
+
+	statuses, err := migrations.Status(ctx, migrator)
 	if err != nil {
 		fatalf("Failed to get migration status: %v", err)
 	}
-	if len(ms) == 0 {
+	if asJSON {
+		// An array of {name, applied, migrated_at, group}, for CI scripts:
+		// migrate status --json | jq -e 'all(.applied)'
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(statuses); err != nil {
+			fatalf("Failed to write migration status: %v", err)
+		}
+		return
+	}
+	if len(statuses) == 0 {
 		fmt.Println("No migrations found")
 		return
 	}
 
-	applied := 0
-	pending := 0
 	fmt.Println("Migrations:")
-	for _, m := range ms {
-		if m.MigratedAt.IsZero() {
-			fmt.Printf("  ○ %s (pending)\n", m.Name)
-			pending++
+	for _, s := range statuses {
+		if s.Applied {
+			fmt.Printf("  ● %s (applied %s)\n", s.Name, s.MigratedAt.Format("2006-01-02 15:04:05"))
 		} else {
-			fmt.Printf("  ● %s (applied %s)\n", m.Name, m.MigratedAt.Format("2006-01-02 15:04:05"))
-			applied++
+			fmt.Printf("  ○ %s (pending)\n", s.Name)
 		}
 	}
-	fmt.Printf("\nTotal: %d applied, %d pending\n", applied, pending)
+	pending := migrations.Pending(statuses)
+	fmt.Printf("\nTotal: %d applied, %d pending\n", len(statuses)-pending, pending)
 }
 
 func cmdCreate(ctx context.Context, migrator *migrate.Migrator) {
-	if len(os.Args) < 3 {
-		fatalf("Usage: migrate create <name>")
+	var name string
+	goMigration := false
+	for _, arg := range os.Args[2:] {
+		switch {
+		case arg == "--go" || arg == "-go":
+			goMigration = true
+		case name == "" && !strings.HasPrefix(arg, "-"):
+			name = arg
+		default:
+			fatalf("Usage: migrate create [--go] <name>")
+		}
+	}
+	if name == "" {
+		fatalf("Usage: migrate create [--go] <name>")
 	}
-	name := os.Args[2]
 
-	files, err := migrator.CreateSQLMigrations(ctx, name)
+	var files []*migrate.MigrationFile
+	var err error
+	if goMigration {
+		var file *migrate.MigrationFile
+		file, err = migrator.CreateGoMigration(ctx, name, migrate.WithGoTemplate(goMigrationTemplate))
+		files = []*migrate.MigrationFile{file}
+	} else {
+		files, err = migrator.CreateSQLMigrations(ctx, name)
+	}
 	if err != nil {
 		fatalf("Failed to create migration: %v", err)
 	}
+	if err := ensureUniqueVersion(files); err != nil {
+		fatalf("Failed to create migration: %v", err)
+	}
 	fmt.Println("Created migration files:")
 	for _, f := range files {
 		fmt.Printf("  + %s\n", f.Path)
@@ -223,6 +590,101 @@ func cmdUnlock(ctx context.Context, migrator *migrate.Migrator) {
 	fmt.Println("Migration lock released")
 }
 
+// goMigrationTemplate is the file "migrate create --go" writes; %s is the
+// package name. Go migrations don't run in a transaction unless they use
+// db.RunInTx, which lets a long backfill commit batch by batch.
+const goMigrationTemplate = `package %s
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		// Apply the migration. Backfill large tables in batches so no single
+		// statement locks many rows for long, e.g.:
+		//
+		//	for {
+		//		res, err := db.ExecContext(ctx, "UPDATE users SET slug = lower(name) "+
+		//			"WHERE id IN (SELECT id FROM users WHERE slug IS NULL LIMIT 1000)")
+		//		if err != nil {
+		//			return err
+		//		}
+		//		if n, _ := res.RowsAffected(); n == 0 {
+		//			return nil
+		//		}
+		//	}
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		// Revert what the up function did.
+		return nil
+	})
+}
+`
+
+// migrationVersionFormat is the timestamp layout Bun uses for migration prefixes.
+const migrationVersionFormat = "20060102150405"
+
+// ensureUniqueVersion renames freshly created migration files if their
+// version prefix is not greater than every existing migration's.
+//
+// Bun versions migrations with a second-resolution timestamp, so scripts that
+// create several migrations in a row produce identical prefixes, and the
+// migrations then sort by name instead of creation order. Bumping the new
+// version to one second after the latest existing one keeps ordering strict.
+func ensureUniqueVersion(files []*migrate.MigrationFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+	version, _, _ := strings.Cut(files[0].Name, "_")
+	dir := filepath.Dir(files[0].Path)
+
+	created := make(map[string]bool, len(files))
+	for _, f := range files {
+		created[f.Name] = true
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	latest := ""
+	for _, entry := range entries {
+		if created[entry.Name()] || (!strings.HasSuffix(entry.Name(), ".sql") && !strings.HasSuffix(entry.Name(), ".go")) {
+			continue
+		}
+		if v, _, ok := strings.Cut(entry.Name(), "_"); ok && v > latest {
+			latest = v
+		}
+	}
+	if version > latest {
+		return nil
+	}
+
+	next := nextMigrationVersion(latest)
+	for _, f := range files {
+		name := next + strings.TrimPrefix(f.Name, version)
+		path := filepath.Join(dir, name)
+		if err := os.Rename(f.Path, path); err != nil {
+			return err
+		}
+		f.Name, f.Path = name, path
+	}
+	return nil
+}
+
+// nextMigrationVersion returns the version that sorts immediately after v:
+// one second later for timestamp versions, or v+1 otherwise.
+func nextMigrationVersion(v string) string {
+	if t, err := time.Parse(migrationVersionFormat, v); err == nil {
+		return t.Add(time.Second).Format(migrationVersionFormat)
+	}
+	n, _ := strconv.ParseUint(v, 10, 64)
+	return fmt.Sprintf("%0*d", len(migrationVersionFormat), n+1)
+}
+
 func fatalf(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
 	os.Exit(1)
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uptrace/bun/migrate"
+)
+
+func TestNextMigrationVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"20261017120000", "20261017120001"},
+		{"20261017235959", "20261018000000"},
+		{"00000000000007", "00000000000008"},
+	}
+
+	for _, tt := range tests {
+		if got := nextMigrationVersion(tt.version); got != tt.want {
+			t.Errorf("nextMigrationVersion(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureUniqueVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		created  string
+		want     string
+	}{
+		{name: "same second as the latest", existing: []string{"20261017120000_create_books.up.sql"}, created: "20261017120000_add_index", want: "20261017120001_add_index"},
+		{name: "earlier than the latest", existing: []string{"20261017120000_create_books.up.sql", "20261017130000_seed.go"}, created: "20261017125959_add_index", want: "20261017130001_add_index"},
+		{name: "already the newest", existing: []string{"20261017120000_create_books.up.sql"}, created: "20261017120005_add_index", want: "20261017120005_add_index"},
+		{name: "first migration", created: "20261017120000_init", want: "20261017120000_init"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range append(tt.existing, "migrations.go", "README.md") {
+				writeFile(t, filepath.Join(dir, name))
+			}
+			var files []*migrate.MigrationFile
+			for _, suffix := range []string{".up.sql", ".down.sql"} {
+				f := &migrate.MigrationFile{Name: tt.created + suffix, Path: filepath.Join(dir, tt.created+suffix)}
+				writeFile(t, f.Path)
+				files = append(files, f)
+			}
+
+			if err := ensureUniqueVersion(files); err != nil {
+				t.Fatalf("ensureUniqueVersion: %v", err)
+			}
+
+			for i, suffix := range []string{".up.sql", ".down.sql"} {
+				if files[i].Name != tt.want+suffix {
+					t.Errorf("Name = %q, want %q", files[i].Name, tt.want+suffix)
+				}
+				if _, err := os.Stat(filepath.Join(dir, tt.want+suffix)); err != nil {
+					t.Errorf("renamed file missing: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,184 @@
+// Package main loads sample data into the database for local development.
+//
+// It runs the .sql files in the seeds directory in file name order, each in
+// its own transaction, and records every file that succeeds in the
+// seed_history table. Files already recorded there are skipped, so running
+// it again only picks up new seeds. Seed files should still be idempotent
+// (INSERT ... ON CONFLICT DO NOTHING), since -reset runs them all again.
+//
+// With -reset, every application table is emptied first and the history is
+// cleared, giving a clean development database. Migration bookkeeping
+// (bun_migrations, bun_migration_locks) is left alone, so the schema stays
+// migrated. -reset is refused in production.
+//
+// Usage:
+//
+//	go run ./cmd/seed                  # run new seeds from seeds/
+//	go run ./cmd/seed -reset           # empty app tables, then run all seeds
+//	go run ./cmd/seed -dir testdata    # use another directory
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"replace-me/internal/config"
+	"replace-me/internal/database"
+
+	"github.com/uptrace/bun"
+)
+
+// historyTable records the seed files that have been run.
+const historyTable = "seed_history"
+
+// keepTables are the tables -reset leaves untouched: Bun's migration
+// bookkeeping and the seed history, which is cleared separately.
+var keepTables = []string{"bun_migrations", "bun_migration_locks", historyTable}
+
+func main() {
+	dir := flag.String("dir", "seeds", "directory holding the .sql seed files")
+	reset := flag.Bool("reset", false, "empty all application tables and re-run every seed")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatalf("Invalid configuration:\n%v", err)
+	}
+	if *reset && cfg.IsProduction() {
+		fatalf("Refusing to reset tables in production")
+	}
+
+	files, err := seedFiles(*dir)
+	if err != nil {
+		fatalf("Failed to read seeds: %v", err)
+	}
+
+	database.OnConnect(database.SessionStatements(cfg.DBApplicationName, cfg.DBSearchPath)...)
+	db, err := database.New(cfg.DatabaseURL, database.QueryLogOff, database.PoolOptions{})
+	if err != nil {
+		fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close(db)
+
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+historyTable+` (
+		name TEXT PRIMARY KEY,
+		seeded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`); err != nil {
+		fatal(db, "Failed to create %s: %v", historyTable, err)
+	}
+
+	if *reset {
+		tables, err := resetTables(ctx, db)
+		if err != nil {
+			fatal(db, "Reset failed: %v", err)
+		}
+		fmt.Printf("Emptied %d table(s)\n", tables)
+	}
+
+	if len(files) == 0 {
+		fmt.Printf("No seed files found in %s\n", *dir)
+		return
+	}
+
+	var done []string
+	if err := db.NewRaw("SELECT name FROM "+historyTable).Scan(ctx, &done); err != nil {
+		fatal(db, "Failed to read %s: %v", historyTable, err)
+	}
+
+	ran := 0
+	for _, file := range files {
+		name := filepath.Base(file)
+		if slices.Contains(done, name) {
+			continue
+		}
+		if err := runSeed(ctx, db, file, name); err != nil {
+			fatal(db, "Seed %s failed: %v", name, err)
+		}
+		fmt.Printf("  ✓ %s\n", name)
+		ran++
+	}
+
+	if ran == 0 {
+		fmt.Println("No new seeds to run")
+		return
+	}
+	fmt.Printf("Ran %d seed(s), skipped %d already run\n", ran, len(files)-ran)
+}
+
+// seedFiles returns the .sql files in dir, sorted by name. A missing
+// directory has no seeds.
+func seedFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// runSeed runs one seed file and records it in the history, in a single
+// transaction so a failing seed leaves no partial data behind.
+func runSeed(ctx context.Context, db *bun.DB, path, name string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// Without arguments the query is sent as is, so a file may hold
+		// several statements
+		if _, err := tx.ExecContext(ctx, string(contents)); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO "+historyTable+" (name) VALUES (?)", name)
+		return err
+	})
+}
+
+// resetTables empties every table in the connection's search path except
+// keepTables, resetting their sequences, and clears the seed history. It
+// returns how many tables were emptied.
+func resetTables(ctx context.Context, db *bun.DB) (int, error) {
+	var tables []string
+	err := db.NewRaw(`
+		SELECT quote_ident(schemaname) || '.' || quote_ident(tablename)
+		FROM pg_tables
+		WHERE schemaname = ANY (current_schemas(false))
+		  AND tablename NOT IN (?)
+		ORDER BY 1`, bun.In(keepTables)).Scan(ctx, &tables)
+	if err != nil {
+		return 0, err
+	}
+
+	err = db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if len(tables) > 0 {
+			// CASCADE also empties tables referencing these through foreign
+			// keys, which are all in the list anyway
+			query := "TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE"
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM "+historyTable)
+		return err
+	})
+	return len(tables), err
+}
+
+// fatal closes the database and exits like fatalf, since os.Exit skips
+// deferred calls.
+func fatal(db *bun.DB, format string, args ...interface{}) {
+	database.Close(db)
+	fatalf(format, args...)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(1)
+}
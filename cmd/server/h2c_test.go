@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/http2"
+)
+
+func TestStartServerH2C(t *testing.T) {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Request().Proto)
+	})
+
+	cfg := &config.Config{ListenHost: "127.0.0.1", Port: 0, EnableH2C: true}
+	done := make(chan error, 1)
+	go func() { done <- startServer(e, cfg) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := e.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+		if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("startServer returned %v, want http.ErrServerClosed", err)
+		}
+	})
+
+	var addr net.Addr
+	for deadline := time.Now().Add(5 * time.Second); addr == nil; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("server did not start")
+		}
+		addr = e.ListenerAddr()
+	}
+	url := "http://" + addr.String() + "/"
+
+	// HTTP/2 with prior knowledge over plaintext
+	h2c := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	// Plain HTTP/1.1 still works on the same listener
+	http1 := &http.Client{Transport: &http.Transport{}}
+
+	for name, client := range map[string]*http.Client{"HTTP/2.0": h2c, "HTTP/1.1": http1} {
+		res, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("%s request: %v", name, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK || res.Proto != name {
+			t.Errorf("%s request got %d over %s", name, res.StatusCode, res.Proto)
+		}
+		client.CloseIdleConnections()
+	}
+}
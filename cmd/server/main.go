@@ -17,42 +17,147 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
 	"syscall"
 	"time"
 
+	"replace-me/internal/assets"
 	"replace-me/internal/config"
 	"replace-me/internal/database"
+	"replace-me/internal/errorreport"
 	"replace-me/internal/handlers"
+	"replace-me/internal/health"
 	"replace-me/internal/logger"
 	"replace-me/internal/middleware"
+	"replace-me/internal/tracing"
+	"replace-me/templates/pages"
 
+	"github.com/a-h/templ"
 	"github.com/labstack/echo/v4"
+	"golang.org/x/net/http2"
 )
 
 func main() {
 	// Load configuration from environment variables (and .env file in development).
 	// See internal/config/config.go for available configuration options.
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
 
 	// Initialize the structured logger based on configuration.
 	// In development: human-readable text output
 	// In production: JSON output for log aggregation systems
-	logger.Init(cfg.LogLevel, cfg.IsDevelopment())
+	// Logs go to stdout, or to a rotated LOG_FILE when one is set.
+	// Outside development, repetitive lines can be sampled (LOG_SAMPLE_*)
+	// and sensitive attributes are redacted (LOG_REDACT).
+	logger.InitWithConfig(logger.Config{
+		Level:       cfg.LogLevel,
+		Development: cfg.IsDevelopment(),
+		TimeFormat: logger.TimeFormat{
+			Layout: cfg.LogTimeFormat,
+			UTC:    cfg.LogTimeUTC,
+		},
+		File: logger.FileConfig{
+			Path:       cfg.LogFile,
+			MaxSize:    cfg.LogFileMaxSize,
+			MaxBackups: cfg.LogFileMaxBackups,
+			MaxAgeDays: cfg.LogFileMaxAgeDays,
+			Compress:   cfg.LogFileCompress,
+		},
+		Sampling: logger.SamplingConfig{
+			Initial:    cfg.LogSampleInitial,
+			Thereafter: cfg.LogSampleThereafter,
+		},
+		Redact: cfg.LogRedact,
+	})
+
+	// ctx is cancelled on SIGINT (Ctrl+C) or SIGTERM (kill), which starts
+	// the graceful shutdown in run. The first signal also restores the
+	// default handling, so a second Ctrl+C kills a shutdown that hangs.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := run(ctx, cfg); err != nil {
+		logger.Error("server failed", "error", err.Error())
+		os.Exit(1)
+	}
+}
+
+// run starts the server and blocks until ctx is cancelled or the server
+// fails, then shuts everything down in order. It returns the error that
+// stopped the server early, if any.
+//
+// Cancelling ctx is how shutdown starts, so tests can run the whole server
+// and stop it without sending signals.
+func run(ctx context.Context, cfg *config.Config) error {
+	// Initialize error reporting (no-op unless ERROR_REPORT_DSN is set).
+	// Server errors and panics are reported with duplicates suppressed.
+	errorreport.Init(cfg.ErrorReportDSN, cfg.ErrorReportWindow)
+
+	// Send queued error reports and buffered spans however run returns,
+	// including the early returns when startup fails
+	defer flushTelemetry()
+
+	// Initialize tracing (no-op unless OTEL_EXPORTER_OTLP_ENDPOINT is set).
+	// This must run before the database connects and the middleware is set
+	// up, which both check whether tracing is enabled.
+	if err := tracing.Init(ctx, cfg.OTelServiceName, cfg.OTelEndpoint); err != nil {
+		return fmt.Errorf("initialize tracing: %w", err)
+	}
 
 	logger.Info("starting server",
 		"port", cfg.Port,
 		"environment", cfg.Environment,
+		"max_header_bytes", cfg.MaxHeaderBytes,
+		"read_header_timeout", cfg.ReadHeaderTimeout.String(),
+		"read_timeout", cfg.ReadTimeout.String(),
+		"write_timeout", cfg.WriteTimeout.String(),
+		"idle_timeout", cfg.IdleTimeout.String(),
 	)
 
 	// Connect to the PostgreSQL database.
-	// Query logging is enabled in development to help debug queries.
-	db, err := database.New(cfg.DatabaseURL, cfg.IsDevelopment())
+	// Query logging is controlled by DB_LOG_QUERY_MODE (full in development by default).
+	// Startup waits for a database that isn't up yet (DB_CONNECT_ATTEMPTS).
+	// DB_APPLICATION_NAME and DB_SEARCH_PATH are applied to every connection.
+	// Read replicas (DATABASE_REPLICA_URLS) are connected the same way.
+	database.OnConnect(database.SessionStatements(cfg.DBApplicationName, cfg.DBSearchPath)...)
+	replicatedDB, err := database.NewWithReplicas(cfg.DatabaseURL, cfg.DatabaseReplicaURLs, database.QueryLogMode(cfg.DBLogQueryMode), database.PoolOptions{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+	}, database.RetryOptions{
+		MaxAttempts: cfg.DBConnectAttempts,
+	})
 	if err != nil {
-		logger.Error("failed to connect to database", "error", err.Error())
-		os.Exit(1)
+		return fmt.Errorf("connect to database: %w", err)
+	}
+	// db is the primary, for the session store, monitor and workers, which
+	// write or must read their own writes. Read paths (handlers, and
+	// services they create) get replicatedDB, which sends NewSelect queries
+	// to the replicas and everything else to the primary.
+	db := replicatedDB.Primary()
+
+	// In development, warn about queries that run without the request
+	// context (e.g. context.Background()) and so ignore request timeouts,
+	// and allow database.Explain for inspecting query plans.
+	if cfg.IsDevelopment() {
+		database.CheckQueryContexts(db)
+		for _, replica := range replicatedDB.Replicas() {
+			database.CheckQueryContexts(replica)
+		}
+		database.EnableExplain()
 	}
 
 	// Create the Echo web server instance.
@@ -63,17 +168,167 @@ func main() {
 	e.HideBanner = true
 	e.HidePort = true
 
+	// Harden the underlying http.Server. net/http's zero values mean "no
+	// limit", which leaves the server open to slowloris-style attacks.
+	e.Server.MaxHeaderBytes = cfg.MaxHeaderBytes
+	e.Server.ReadHeaderTimeout = cfg.ReadHeaderTimeout
+	e.Server.ReadTimeout = cfg.ReadTimeout
+	e.Server.WriteTimeout = cfg.WriteTimeout
+	e.Server.IdleTimeout = cfg.IdleTimeout
+
+	// Route net/http's own error log (TLS handshake errors, malformed
+	// requests) through the structured logger. Echo assigns StdLogger to
+	// e.Server.ErrorLog and e.TLSServer.ErrorLog when the server starts.
+	e.StdLogger = logger.NewStdLogger("http.server")
+
+	// With SESSION_BACKEND=postgres, sessions are kept in the sessions
+	// table (created by a migration). With redis, they are kept in
+	// REDIS_URL, shared by all replicas.
+	var sessionRedis io.Closer
+	switch cfg.SessionBackend {
+	case "postgres":
+		if err := middleware.UsePostgresSessions(ctx, db); err != nil {
+			replicatedDB.Close()
+			return fmt.Errorf("set up session store: %w", err)
+		}
+	case "redis":
+		client, err := middleware.UseRedisSessions(ctx, cfg.RedisURL)
+		if err != nil {
+			replicatedDB.Close()
+			return fmt.Errorf("connect to redis: %w", err)
+		}
+		sessionRedis = client
+	}
+
 	// Configure all middleware (logging, recovery, CORS, timeout, sessions, etc.)
 	// See internal/middleware/middleware.go for details on each middleware.
 	middleware.Setup(e, cfg)
 
+	// Each request takes a connection from the primary's pool up front and
+	// is answered with 503 when none frees up within DB_ACQUIRE_TIMEOUT,
+	// rather than every request queueing on the pool until it times out.
+	middleware.SetRequestDB(db)
+
+	// Browsers get a dedicated page for server errors, showing the request
+	// ID so users can quote it when they contact support.
+	middleware.SetServerErrorPage(func(code int, message, requestID string) templ.Component {
+		return pages.ServerError(code, message, requestID, cfg.SupportEmail)
+	})
+
+	// Per-route request body limits. Everything else is capped at
+	// MAX_REQUEST_BODY_SIZE. For example:
+	//
+	//	middleware.SetBodyLimits(middleware.BodyLimits{
+	//	    "POST /uploads": 50 << 20,
+	//	    "/api/*":        64 << 10,
+	//	})
+	middleware.SetBodyLimits(middleware.BodyLimits{
+		"/api/*": 64 << 10, // JSON API bodies are small
+	})
+
+	// Routes that stream their response (Server-Sent Events, CSV exports)
+	// must be listed here so they skip the request timeout, whose buffered
+	// writer can't flush. For example:
+	//
+	//	middleware.SetStreamingRoutes("/events", "GET /books/export.csv")
+
+	// Background workers, stopped at shutdown before the database closes.
+	// Every goroutine that outlives a request must be registered here, or
+	// it leaks on exit and may still be using db when it is closed:
+	//
+	//   - Start a loop with startWorker. Its context is cancelled when
+	//     shutdown begins, so return once ctx.Done() is closed:
+	//
+	//	workers = append(workers, startWorker(ctx, "flash cleanup", func(ctx context.Context) {
+	//	    ticker := time.NewTicker(time.Hour)
+	//	    defer ticker.Stop()
+	//	    for {
+	//	        select {
+	//	        case <-ticker.C:
+	//	            cleanUpFlashes(ctx, db)
+	//	        case <-ctx.Done():
+	//	            return
+	//	        }
+	//	    }
+	//	}))
+	//
+	//   - For components with their own Start/Stop, append their Stop:
+	//
+	//	workers = append(workers, worker{name: "database monitor", stop: dbMonitor.Stop})
+	var workers []worker
+
+	// Delete expired sessions from the sessions table every
+	// SESSION_GC_INTERVAL (postgres session backend only).
+	if cfg.SessionBackend == "postgres" {
+		workers = append(workers, startWorker(ctx, "session cleanup", func(ctx context.Context) {
+			middleware.CleanupSessions(ctx, db, cfg.SessionGCInterval)
+		}))
+	}
+
+	// Limit each client IP to RATE_LIMIT_RPS requests per second (bursts of
+	// RATE_LIMIT_BURST). Static assets and health probes are exempt: a page
+	// load fetches many assets, and probes come from the orchestrator.
+	if cfg.RateLimitRPS > 0 {
+		// Behind a load balancer every request arrives from the balancer's
+		// address, so without TRUSTED_PROXIES all users share one bucket
+		// and are rate limited together
+		if len(cfg.TrustedProxies) == 0 && !cfg.IsDevelopment() {
+			logger.Warn("rate limiting by connection address because TRUSTED_PROXIES is unset; behind a load balancer all clients share one limit",
+				"rps", cfg.RateLimitRPS,
+				"burst", cfg.RateLimitBurst,
+			)
+		}
+		rateLimitStore := middleware.NewMemoryRateLimitStore(cfg.RateLimitRPS, cfg.RateLimitBurst, cfg.RateLimitMaxKeys, cfg.RateLimitIdleTTL)
+		rateLimitStore.Start()
+		workers = append(workers, worker{name: "rate limiter sweeper", stop: rateLimitStore.Stop})
+		middleware.MetricsRegistry.MustRegister(rateLimitStore.BucketsGauge())
+		// kill -HUP re-reads RATE_LIMIT_RPS and RATE_LIMIT_BURST, e.g. to
+		// tighten limits during an attack. The process environment can't
+		// change, so set them through RATE_LIMIT_RPS_FILE and
+		// RATE_LIMIT_BURST_FILE to change them at runtime.
+		workers = append(workers, startWorker(ctx, "rate limit reload", func(ctx context.Context) {
+			reloadRateLimitOnHangup(ctx, rateLimitStore)
+		}))
+		middleware.SetRateLimit(middleware.RateLimitConfig{
+			Store: rateLimitStore,
+			Skipper: func(c echo.Context) bool {
+				switch path := c.Path(); path {
+				case "/static/*", "/health", "/readyz":
+					return true
+				default:
+					return path != "" && path == cfg.MetricsPath
+				}
+			},
+		})
+	}
+
 	// Serve static files (CSS, JS, images) from the static directory.
-	// Files are served at /static/* (e.g., /static/css/output.css)
-	e.Static("/static", "static")
+	// Files are served at /static/* (e.g., /static/css/output.css).
+	// Precompressed siblings (output.css.br, output.css.gz) are sent instead
+	// when present and the client accepts them.
+	e.GET("/static/*", handlers.Static("static"))
 
-	// Initialize handlers with database connection.
+	// Resolve fingerprinted asset names for templates. In development the
+	// manifest is watched so a rebuilt asset is picked up without a restart.
+	if err := assets.Load("static/manifest.json"); err != nil {
+		logger.Error("failed to load asset manifest", "error", err.Error())
+	}
+	if cfg.IsDevelopment() {
+		workers = append(workers, worker{name: "asset watcher", stop: assets.Watch(time.Second).Stop})
+	}
+
+	// Ping the database in the background so readiness probes can read
+	// the cached result instead of hitting the database on every probe.
+	dbMonitor := database.NewMonitor(db, cfg.DBMonitorInterval)
+	dbMonitor.Start()
+	workers = append(workers, worker{name: "database monitor", stop: dbMonitor.Stop})
+
+	// Initialize handlers with database connection and configuration.
 	// Handlers delegate to services for business logic.
-	h := handlers.New(db)
+	// Extra readiness checks for your own dependencies can be passed here,
+	// e.g. services.NewUpstreamChecker("payments", "https://payments.internal/healthz").
+	// Each read replica gets its own readiness check.
+	h := handlers.New(replicatedDB, cfg, dbMonitor, health.NewReplicaCheckers(replicatedDB.Replicas())...)
 
 	// =========================================================================
 	// Routes
@@ -87,55 +342,232 @@ func main() {
 	// Greeting demo - shows HTMX form handling
 	e.POST("/greet", h.Greet)
 
+	// JSON API, versioned under /api/{version}. Errors are always JSON and
+	// request bodies must be JSON. To retire a version, set Deprecated (and
+	// optionally Sunset) and its responses will carry deprecation headers.
+	v1 := handlers.MountAPIVersion(e, handlers.APIVersion{Name: "v1"})
+	v1.GET("/health", h.Health)
+
 	// Health check endpoint - useful for load balancers, Kubernetes probes,
 	// and monitoring systems to verify the server is running.
 	e.GET("/health", h.Health)
 
+	// Readiness endpoint - runs all readiness checks (database, pending
+	// migrations). Point Kubernetes readiness probes here.
+	e.GET("/readyz", h.Readyz)
+
+	// Prometheus metrics (request counts and durations per route, Go
+	// runtime). Set METRICS_TOKEN so only your Prometheus can read them.
+	if cfg.MetricsPath != "" {
+		e.GET(cfg.MetricsPath, middleware.MetricsHandler(cfg.MetricsToken))
+	}
+
 	// =========================================================================
 	// Graceful Shutdown
 	// =========================================================================
-	// The server handles SIGINT (Ctrl+C) and SIGTERM (kill) signals gracefully.
-	// When a signal is received:
-	// 1. Stop accepting new connections
-	// 2. Wait for in-flight requests to complete (up to 10 seconds)
-	// 3. Close database connections
-	// 4. Exit cleanly
+	// The server runs until ctx is cancelled (SIGINT or SIGTERM, see main)
+	// or it fails to serve. Then:
+	// 1. Tell open streams (SSE) to reconnect elsewhere and wait for them to
+	//    close (up to STREAM_DRAIN_TIMEOUT)
+	// 2. Stop accepting new connections
+	// 3. Wait for in-flight requests to complete (up to 10 seconds)
+	// 4. Stop background workers (see workers), waiting up to
+	//    WORKER_STOP_TIMEOUT for them to finish their current job
+	// 5. Close the session Redis connection (SESSION_BACKEND=redis)
+	// 6. Close database connections: every component that can run a
+	//    query has stopped by now
+	// 7. Flush pending error reports and traces (deferred, so this also
+	//    happens when startup fails)
 	//
 	// This prevents data corruption and ensures clients get proper responses.
 
-	// Start server in a goroutine so it doesn't block signal handling
+	// Start server in a goroutine so run can wait for shutdown
+	serveErr := make(chan error, 1)
 	go func() {
-		addr := ":" + cfg.Port
-		logger.Info("server listening", "addr", addr)
-
-		if err := e.Start(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.Error("server error", "error", err.Error())
-			os.Exit(1)
-		}
+		addr := cfg.Addr()
+		logger.Info("server listening", "addr", addr, "h2c", cfg.EnableH2C)
+		serveErr <- startServer(e, cfg)
 	}()
 
-	// Wait for interrupt signal (Ctrl+C or kill command)
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-quit
+	// Wait for a shutdown signal, or for the server to fail (e.g. the port
+	// is taken); shut down cleanly either way.
+	var runErr error
+	select {
+	case <-ctx.Done():
+		logger.Info("shutting down server")
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			runErr = fmt.Errorf("serve: %w", err)
+		}
+	}
 
-	logger.Info("shutting down server", "signal", sig.String())
+	// End long-lived streams first; e.Shutdown would wait on them until
+	// its deadline
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.StreamDrainTimeout)
+	if err := h.DrainStreams(drainCtx); err != nil {
+		logger.Warn("streams did not close in time", "error", err.Error())
+	}
+	cancelDrain()
 
 	// Create a deadline for shutdown (10 seconds should be enough for most requests)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Gracefully shutdown the Echo server
 	// This waits for all in-flight requests to complete
-	if err := e.Shutdown(ctx); err != nil {
+	if err := e.Shutdown(shutdownCtx); err != nil {
 		logger.Error("server shutdown error", "error", err.Error())
 	}
 
-	// Close database connection after server is shut down
-	// This ensures no queries are in progress when we close
-	if err := database.Close(db); err != nil {
+	// Stop background workers, letting each finish its current job (up to
+	// WORKER_STOP_TIMEOUT). They may be mid-query, so this must happen
+	// before the database is closed.
+	workerCtx, cancelWorkers := context.WithTimeout(context.Background(), cfg.WorkerStopTimeout)
+	if running := stopWorkers(workerCtx, workers); len(running) > 0 {
+		logger.Warn("background workers did not stop in time", "workers", running)
+	}
+	cancelWorkers()
+
+	// Close the session Redis connection; requests, its only users, have
+	// stopped above
+	if sessionRedis != nil {
+		if err := sessionRedis.Close(); err != nil {
+			logger.Error("redis close error", "error", err.Error())
+		}
+	}
+
+	// Close the database last. Requests and workers, the only users of db,
+	// have stopped above; keep any new component that holds connections
+	// ahead of this step.
+	if err := replicatedDB.Close(); err != nil {
 		logger.Error("database close error", "error", err.Error())
 	}
 
 	logger.Info("server stopped")
+	return runErr
+}
+
+// flushTelemetry sends the error reports still queued and exports the
+// spans still buffered, giving up after 10 seconds.
+func flushTelemetry() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := errorreport.Flush(ctx); err != nil {
+		logger.Error("error report flush error", "error", err.Error())
+	}
+	if err := tracing.Shutdown(ctx); err != nil {
+		logger.Error("tracing shutdown error", "error", err.Error())
+	}
+}
+
+// reloadRateLimitOnHangup loads the configuration again on every SIGHUP
+// until ctx is done and applies its rate limit to store. An invalid
+// configuration is logged and the current limit is kept.
+func reloadRateLimitOnHangup(ctx context.Context, store *middleware.MemoryRateLimitStore) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-hangup:
+			cfg, err := config.Load()
+			if err != nil {
+				logger.Error("config reload failed, keeping the current rate limit", "error", err.Error())
+				continue
+			}
+			if cfg.RateLimitRPS <= 0 {
+				// A zero rate would block every client once its burst is used
+				logger.Warn("RATE_LIMIT_RPS is 0 after reload; restart the server to disable rate limiting")
+				continue
+			}
+			store.SetLimit(cfg.RateLimitRPS, cfg.RateLimitBurst)
+			logger.Info("rate limit reloaded", "rps", cfg.RateLimitRPS, "burst", cfg.RateLimitBurst)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// worker is a background goroutine stopped during shutdown.
+type worker struct {
+	name string
+	stop func() // signals the worker to exit and waits until it has
+}
+
+// startWorker runs fn in a goroutine and returns it as a worker. Stopping
+// the worker cancels fn's context and waits for fn to return; ctx (the
+// context of run) also cancels it as soon as shutdown begins. fn must
+// return promptly once its context is done.
+func startWorker(ctx context.Context, name string, fn func(ctx context.Context)) worker {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+	return worker{name: name, stop: func() {
+		cancel()
+		<-done
+	}}
+}
+
+// stopWorkers stops all workers concurrently and waits until they have
+// exited or ctx is done. It returns the names of workers still running at
+// the deadline; shutdown continues without them rather than hang.
+func stopWorkers(ctx context.Context, workers []worker) []string {
+	var (
+		mu      sync.Mutex
+		running = make(map[string]bool, len(workers))
+		wg      sync.WaitGroup
+	)
+	for _, w := range workers {
+		running[w.name] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.stop()
+			mu.Lock()
+			delete(running, w.name)
+			mu.Unlock()
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(running))
+	for name := range running {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// startServer starts e on cfg.Addr() and blocks until it stops.
+//
+// With ENABLE_H2C the plaintext listener also accepts HTTP/2 with prior
+// knowledge. ConfigureServer registers the HTTP/2 server with e.Server so
+// e.Shutdown sends GOAWAY to h2c connections and lets their in-flight
+// streams finish, just as it drains HTTP/1.1 connections.
+func startServer(e *echo.Echo, cfg *config.Config) error {
+	if !cfg.EnableH2C {
+		return e.Start(cfg.Addr())
+	}
+
+	h2s := &http2.Server{IdleTimeout: cfg.IdleTimeout}
+	if err := http2.ConfigureServer(e.Server, h2s); err != nil {
+		return err
+	}
+	return e.StartH2CServer(cfg.Addr(), h2s)
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStopWorkers(t *testing.T) {
+	var stopped atomic.Int32
+	quick := func() { stopped.Add(1) }
+
+	release := make(chan struct{})
+	defer close(release)
+	stuck := func() { <-release }
+
+	workers := []worker{
+		{name: "database monitor", stop: quick},
+		{name: "rate limiter sweeper", stop: stuck},
+		{name: "asset watcher", stop: quick},
+		{name: "exporter", stop: stuck},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	running := stopWorkers(ctx, workers)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stopWorkers took %v, want it to give up at the deadline", elapsed)
+	}
+	if want := []string{"exporter", "rate limiter sweeper"}; !slices.Equal(running, want) {
+		t.Errorf("still running = %q, want %q", running, want)
+	}
+	if got := stopped.Load(); got != 2 {
+		t.Errorf("%d quick workers stopped, want 2", got)
+	}
+}
+
+func TestStopWorkersAllStop(t *testing.T) {
+	// Stopping is concurrent: two workers that each take 30ms finish well
+	// before they would one after the other
+	slow := func() { time.Sleep(30 * time.Millisecond) }
+	workers := []worker{{name: "a", stop: slow}, {name: "b", stop: slow}, {name: "c", stop: slow}}
+
+	start := time.Now()
+	if running := stopWorkers(context.Background(), workers); len(running) != 0 {
+		t.Errorf("still running = %q, want none", running)
+	}
+	if elapsed := time.Since(start); elapsed >= 90*time.Millisecond {
+		t.Errorf("stopWorkers took %v, want the workers stopped concurrently", elapsed)
+	}
+}
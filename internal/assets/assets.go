@@ -0,0 +1,160 @@
+// Package assets resolves static asset names to the URLs templates link to.
+//
+// A frontend build step can fingerprint files for long-term caching and write
+// a manifest mapping each asset to its fingerprinted name:
+//
+//	// static/manifest.json
+//	{"css/output.css": "css/output.3f2a1b9c.css"}
+//
+// URL("css/output.css") then returns "/static/css/output.3f2a1b9c.css".
+// Without a manifest (or for names it doesn't list) URL returns the plain
+// "/static/<name>" path, so templates work the same with or without one.
+//
+// In development the manifest can change while the server runs (a watch
+// build re-fingerprints a file). Watch polls it and reloads on change, so the
+// new name is picked up without a restart. In production the manifest is
+// read once at startup and never watched.
+//
+// Usage:
+//
+//	if err := assets.Load("static/manifest.json"); err != nil {
+//	    logger.Error("failed to load asset manifest", "error", err.Error())
+//	}
+//	watcher := assets.Watch(time.Second) // development only
+//	defer watcher.Stop()
+//
+//	// In templates
+//	<link href={ assets.URL("css/output.css") } rel="stylesheet"/>
+package assets
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"replace-me/internal/logger"
+)
+
+// The loaded manifest. entries stays nil until Load finds a file.
+var (
+	mu       sync.RWMutex
+	path     string
+	entries  map[string]string
+	loadedAt time.Time // modification time of the loaded file
+)
+
+// Load reads the manifest at manifestPath. A missing file is not an error:
+// URL then returns unfingerprinted paths until the file appears (and, in
+// development, Watch picks it up).
+func Load(manifestPath string) error {
+	mu.Lock()
+	path = manifestPath
+	mu.Unlock()
+
+	_, err := reload()
+	return err
+}
+
+// URL returns the URL of the named asset, e.g. "css/output.css" →
+// "/static/css/output.3f2a1b9c.css".
+func URL(name string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if fingerprinted, ok := entries[name]; ok {
+		return "/static/" + fingerprinted
+	}
+	return "/static/" + name
+}
+
+// reload re-reads the manifest if it changed since it was last loaded and
+// reports whether it did.
+func reload() (bool, error) {
+	mu.RLock()
+	manifestPath, lastMod := path, loadedAt
+	mu.RUnlock()
+
+	if manifestPath == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(manifestPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(lastMod) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return false, err
+	}
+	var loaded map[string]string
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return false, err
+	}
+
+	mu.Lock()
+	entries = loaded
+	loadedAt = info.ModTime()
+	mu.Unlock()
+	return true, nil
+}
+
+// Watcher reloads the manifest when it changes on disk.
+type Watcher struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Watch checks the manifest for changes every interval in a background
+// goroutine until Stop is called. Meant for development only.
+func Watch(interval time.Duration) *Watcher {
+	w := &Watcher{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				changed, err := reload()
+				if err != nil {
+					// Keep serving the previous manifest; a half-written
+					// file is usually complete by the next tick.
+					logger.Warn("failed to reload asset manifest", "error", err.Error())
+				} else if changed {
+					logger.Debug("asset manifest reloaded")
+				}
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+
+	return w
+}
+
+// Stop stops watching and waits for the goroutine to exit. It is safe to
+// call on a nil *Watcher, so callers that only watch in development can
+// stop unconditionally.
+func (w *Watcher) Stop() {
+	if w == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+}
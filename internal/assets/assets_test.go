@@ -0,0 +1,101 @@
+package assets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// resetManifest clears the package state so each test starts unloaded.
+func resetManifest(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		mu.Lock()
+		path, entries, loadedAt = "", nil, time.Time{}
+		mu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func writeManifest(t *testing.T, file, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestURL(t *testing.T) {
+	resetManifest(t)
+	file := filepath.Join(t.TempDir(), "manifest.json")
+
+	// A missing manifest is not an error and leaves names unfingerprinted.
+	if err := Load(file); err != nil {
+		t.Fatalf("Load(missing) = %v, want nil", err)
+	}
+	if got := URL("css/output.css"); got != "/static/css/output.css" {
+		t.Errorf("URL without manifest = %q", got)
+	}
+
+	writeManifest(t, file, `{"css/output.css": "css/output.3f2a1b9c.css"}`, time.Now())
+	if err := Load(file); err != nil {
+		t.Fatalf("Load = %v", err)
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "css/output.css", want: "/static/css/output.3f2a1b9c.css"},
+		{name: "js/app.js", want: "/static/js/app.js"},
+	}
+	for _, tt := range tests {
+		if got := URL(tt.name); got != tt.want {
+			t.Errorf("URL(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadInvalidManifest(t *testing.T) {
+	resetManifest(t)
+	file := filepath.Join(t.TempDir(), "manifest.json")
+	writeManifest(t, file, `{not json`, time.Now())
+
+	if err := Load(file); err == nil {
+		t.Fatal("Load(invalid JSON) = nil, want an error")
+	}
+	if got := URL("css/output.css"); got != "/static/css/output.css" {
+		t.Errorf("URL after failed load = %q", got)
+	}
+}
+
+func TestWatchReloadsChangedManifest(t *testing.T) {
+	resetManifest(t)
+	file := filepath.Join(t.TempDir(), "manifest.json")
+	start := time.Now().Add(-time.Minute)
+	writeManifest(t, file, `{"css/output.css": "css/output.aaaa.css"}`, start)
+	if err := Load(file); err != nil {
+		t.Fatalf("Load = %v", err)
+	}
+
+	watcher := Watch(5 * time.Millisecond)
+	defer watcher.Stop()
+
+	writeManifest(t, file, `{"css/output.css": "css/output.bbbb.css"}`, start.Add(time.Second))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for URL("css/output.css") != "/static/css/output.bbbb.css" {
+		if time.Now().After(deadline) {
+			t.Fatalf("URL = %q, watcher never picked up the new manifest", URL("css/output.css"))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNilWatcherStop(t *testing.T) {
+	var w *Watcher
+	w.Stop() // must not panic
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShouldLoadDotenv(t *testing.T) {
+	tests := []struct {
+		name          string
+		environment   string
+		dotenvDisable string
+		want          bool
+	}{
+		{name: "unset environment", want: true},
+		{name: "development", environment: "development", want: true},
+		{name: "production", environment: "production", want: false},
+		{name: "staging", environment: "staging", want: false},
+		{name: "disabled in development", environment: "development", dotenvDisable: "true", want: false},
+		{name: "disable flag off", dotenvDisable: "false", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT", tt.environment)
+			t.Setenv("DOTENV_DISABLE", tt.dotenvDisable)
+			if got := shouldLoadDotenv(); got != tt.want {
+				t.Errorf("shouldLoadDotenv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDotenvPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	dotenv := "LOG_LEVEL=debug\nSESSION_COOKIE_PATH=/from-dotenv\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotenv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	// Unset rather than empty: only a missing ENVIRONMENT defaults to
+	// development
+	t.Setenv("ENVIRONMENT", "")
+	os.Unsetenv("ENVIRONMENT")
+	t.Setenv("DOTENV_DISABLE", "")
+	t.Setenv("LOG_LEVEL", "warn")
+	// Cleared again after the test, since .env sets it in the process environment
+	t.Setenv("SESSION_COOKIE_PATH", "")
+	os.Unsetenv("SESSION_COOKIE_PATH")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.LogLevel != "warn" {
+		t.Errorf("LogLevel = %q, want the OS value %q", cfg.LogLevel, "warn")
+	}
+	if cfg.SessionCookiePath != "/from-dotenv" {
+		t.Errorf("SessionCookiePath = %q, want the .env value %q", cfg.SessionCookiePath, "/from-dotenv")
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import "testing"
+
+func TestAddr(t *testing.T) {
+	tests := []struct {
+		host string
+		port int
+		want string
+	}{
+		{"", 8080, ":8080"},
+		{"127.0.0.1", 3000, "127.0.0.1:3000"},
+		{"::1", 443, "[::1]:443"},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{ListenHost: tt.host, Port: tt.port}
+		if got := cfg.Addr(); got != tt.want {
+			t.Errorf("Addr() with host %q, port %d = %q, want %q", tt.host, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPort(t *testing.T) {
+	t.Setenv("DOTENV_DISABLE", "true")
+	t.Setenv("PORT", "9090")
+	t.Setenv("LISTEN_HOST", "127.0.0.1")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != 9090 {
+		t.Errorf("Port = %d, want 9090", cfg.Port)
+	}
+	if got := cfg.Addr(); got != "127.0.0.1:9090" {
+		t.Errorf("Addr() = %q, want %q", got, "127.0.0.1:9090")
+	}
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Acquire takes a connection from db's pool for the rest of a request,
+// waiting at most timeout for one to become free. When every connection is
+// busy for that long it gives up with ErrPoolExhausted, which the error
+// handler answers with 503 Service Unavailable and a Retry-After header.
+//
+// Without it, a request waits for a connection until its own deadline, so
+// under database pressure requests pile up and time out together. Failing
+// fast sheds the excess load while the requests already holding a
+// connection finish normally.
+//
+// The connection must be closed (returned to the pool) when done. The
+// middleware takes one per request this way (see middleware.SetRequestDB)
+// and stores it with WithConn, where DB and RunInTx pick it up:
+//
+//	conn, err := database.Acquire(ctx, db, cfg.DBAcquireTimeout)
+//	if err != nil {
+//	    return err
+//	}
+//	defer conn.Close()
+//
+//	ctx = database.WithConn(ctx, db, conn)
+func Acquire(ctx context.Context, db *bun.DB, timeout time.Duration) (bun.Conn, error) {
+	acquireCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := db.Conn(acquireCtx)
+	if err != nil {
+		// Only our own deadline means the pool was full; the request's
+		// context ending is a cancellation like any other.
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return bun.Conn{}, ErrPoolExhausted
+		}
+		return bun.Conn{}, TranslateError(err)
+	}
+	return conn, nil
+}
+
+// connKey is the context key under which WithConn stores a connection.
+type connKey struct{}
+
+// heldConn is a connection stored by WithConn and the pool it came from.
+type heldConn struct {
+	db   *bun.DB
+	conn bun.Conn
+}
+
+// WithConn returns a copy of ctx carrying conn, a connection taken from
+// db's pool with Acquire. DB and RunInTx called with db then use conn
+// rather than taking another connection, so a request holding one never
+// waits on the pool a second time.
+func WithConn(ctx context.Context, db *bun.DB, conn bun.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, heldConn{db: db, conn: conn})
+}
+
+// connFromContext returns the connection stored by WithConn, if it was
+// taken from db's pool.
+func connFromContext(ctx context.Context, db bun.IDB) (bun.Conn, bool) {
+	held, ok := ctx.Value(connKey{}).(heldConn)
+	if !ok || bun.IDB(held.db) != db {
+		return bun.Conn{}, false
+	}
+	return held.conn, true
+}
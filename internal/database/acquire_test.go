@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"replace-me/internal/database/dbtest"
+
+	"github.com/uptrace/bun"
+)
+
+func TestAcquire(t *testing.T) {
+	db := dbtest.Open(t, &dbtest.Server{})
+	db.SetMaxOpenConns(1)
+
+	conn, err := Acquire(context.Background(), db, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Acquire with a free pool = %v", err)
+	}
+
+	t.Run("pool saturated", func(t *testing.T) {
+		start := time.Now()
+		_, err := Acquire(context.Background(), db, 20*time.Millisecond)
+		if !errors.Is(err, ErrPoolExhausted) {
+			t.Fatalf("Acquire = %v, want ErrPoolExhausted", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Acquire waited %v, want about the acquire timeout", elapsed)
+		}
+	})
+
+	t.Run("request canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := Acquire(ctx, db, 20*time.Millisecond)
+		if errors.Is(err, ErrPoolExhausted) {
+			t.Fatal("Acquire after the request ended = ErrPoolExhausted, want a cancellation")
+		}
+		if !errors.Is(err, ErrQueryCanceled) {
+			t.Errorf("Acquire = %v, want ErrQueryCanceled", err)
+		}
+	})
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("connection returned", func(t *testing.T) {
+		conn, err := Acquire(context.Background(), db, 50*time.Millisecond)
+		if err != nil {
+			t.Fatalf("Acquire after release = %v", err)
+		}
+		conn.Close()
+	})
+}
+
+func TestWithConn(t *testing.T) {
+	srv := &dbtest.Server{}
+	db := dbtest.Open(t, srv)
+	other := dbtest.Open(t, &dbtest.Server{})
+	// A second connection from the pool would wait forever, so every query
+	// below must run on the held one
+	db.SetMaxOpenConns(1)
+
+	conn, err := Acquire(context.Background(), db, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(WithConn(context.Background(), db, conn), time.Second)
+	defer cancel()
+
+	if got := DB(ctx, other); got != bun.IDB(other) {
+		t.Errorf("DB with another pool = %T, want that pool", got)
+	}
+	if _, err := DB(ctx, db).NewSelect().TableExpr("books").Column("id").Exec(ctx); err != nil {
+		t.Fatalf("select on the held connection: %v", err)
+	}
+	err = RunInTx(ctx, db, func(ctx context.Context, tx bun.IDB) error {
+		_, err := DB(ctx, db).NewDelete().TableExpr("books").Where("id = 1").Exec(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("transaction on the held connection: %v", err)
+	}
+
+	want := []string{`SELECT "id" FROM books`, "BEGIN", `DELETE FROM books WHERE (id = 1)`, "COMMIT"}
+	if got := srv.Statements(); !slices.Equal(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+	if got := srv.Connections(); got != 1 {
+		t.Errorf("connections opened = %d, want 1", got)
+	}
+}
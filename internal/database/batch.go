@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/uptrace/bun"
+)
+
+// batchGetChunkSize is the maximum number of IDs in a single IN (...) list.
+// Large lists are split over several queries to keep each one a reasonable
+// size for the server to parse and plan.
+const batchGetChunkSize = 1000
+
+// BatchGet loads the rows of T with the given primary keys in one query per
+// 1000 IDs, instead of one query per ID (the N+1 problem), and returns them
+// keyed by ID. IDs without a row are simply absent from the map; duplicate
+// IDs are looked up once.
+//
+// T must be a model with a single integer primary key.
+//
+// Usage (loading each book's author for a list page):
+//
+//	authorIDs := make([]int64, len(books))
+//	for i, b := range books {
+//	    authorIDs[i] = b.AuthorID
+//	}
+//	authors, err := database.BatchGet[models.Author](ctx, database.DB(ctx, s.db), authorIDs)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, b := range books {
+//	    author, ok := authors[b.AuthorID]
+//	    if !ok {
+//	        // deleted author
+//	    }
+//	}
+func BatchGet[T any](ctx context.Context, db bun.IDB, ids []int64) (map[int64]T, error) {
+	table := db.Dialect().Tables().Get(reflect.TypeFor[T]())
+	if len(table.PKs) != 1 {
+		return nil, fmt.Errorf("batch get %s: need exactly one primary key, have %d", table.TypeName, len(table.PKs))
+	}
+	pk := table.PKs[0]
+
+	unique := make([]int64, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	found := make(map[int64]T, len(unique))
+	for start := 0; start < len(unique); start += batchGetChunkSize {
+		chunk := unique[start:min(start+batchGetChunkSize, len(unique))]
+
+		var rows []T
+		err := db.NewSelect().Model(&rows).Where("?TablePKs IN (?)", bun.In(chunk)).Scan(ctx)
+		if err != nil {
+			return nil, TranslateError(err)
+		}
+
+		for _, row := range rows {
+			v := pk.Value(reflect.ValueOf(&row).Elem())
+			if !v.CanInt() {
+				return nil, fmt.Errorf("batch get %s: primary key %s is not an integer", table.TypeName, pk.GoName)
+			}
+			found[v.Int()] = row
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+
+	"github.com/uptrace/bun"
+)
+
+type batchBook struct {
+	bun.BaseModel `bun:"table:books,alias:b"`
+
+	ID    int64  `bun:"id,pk,autoincrement"`
+	Title string `bun:"title"`
+}
+
+func TestBatchGet(t *testing.T) {
+	srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+		if strings.Contains(query, "IN (1, 2, 3,") {
+			return dbtest.Rows([]string{"id", "title"}, []any{1, "Dune"}, []any{3, "Emma"}), nil
+		}
+		return dbtest.Rows([]string{"id", "title"}), nil
+	}}
+	db := dbtest.Open(t, srv)
+
+	// 2500 distinct IDs plus duplicates, which are looked up once
+	ids := make([]int64, 0, 2600)
+	for id := int64(1); id <= 2500; id++ {
+		ids = append(ids, id)
+	}
+	ids = append(ids, 1, 2, 3)
+
+	books, err := BatchGet[batchBook](context.Background(), db, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(books) != 2 || books[1].Title != "Dune" || books[3].Title != "Emma" {
+		t.Errorf("BatchGet() = %+v, want books 1 and 3", books)
+	}
+	if _, ok := books[2]; ok {
+		t.Error("missing ID 2 is present in the result")
+	}
+
+	statements := srv.Statements()
+	if len(statements) != 3 {
+		t.Fatalf("ran %d queries, want 3 chunks of at most %d IDs", len(statements), batchGetChunkSize)
+	}
+	for i, want := range []string{"IN (1, 2, 3,", "IN (1001, 1002,", "IN (2001, 2002,"} {
+		if !strings.Contains(statements[i], want) {
+			t.Errorf("query %d = %q, want it to contain %q", i, statements[i], want)
+		}
+	}
+	if !strings.Contains(statements[2], "2500)") || strings.Contains(statements[2], "2501") {
+		t.Errorf("last chunk = %q, want it to end at 2500", statements[2])
+	}
+}
+
+func TestBatchGetNoIDs(t *testing.T) {
+	srv := &dbtest.Server{}
+	db := dbtest.Open(t, srv)
+
+	books, err := BatchGet[batchBook](context.Background(), db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 0 || len(srv.Statements()) != 0 {
+		t.Errorf("BatchGet(nil) = %v after %d queries, want an empty map and no queries", books, len(srv.Statements()))
+	}
+}
+
+func TestBatchGetRequiresSinglePK(t *testing.T) {
+	type noPK struct {
+		bun.BaseModel `bun:"table:notes"`
+		Body          string `bun:"body"`
+	}
+	db := dbtest.Open(t, &dbtest.Server{})
+
+	if _, err := BatchGet[noPK](context.Background(), db, []int64{1}); err == nil {
+		t.Error("BatchGet on a model without a primary key succeeded")
+	}
+}
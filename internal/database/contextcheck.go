@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+
+	"replace-me/internal/logger"
+
+	"github.com/uptrace/bun"
+)
+
+// requestContextKey marks a context as belonging to an HTTP request.
+type requestContextKey struct{}
+
+// WithRequestContext marks ctx as a request context, so the context check
+// (see CheckQueryContexts) knows queries run with it are properly scoped.
+// The request context middleware calls it for every request.
+func WithRequestContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, true)
+}
+
+// backgroundContextKey marks a context as belonging to deliberate
+// background work.
+type backgroundContextKey struct{}
+
+// WithBackgroundContext marks ctx as background work that runs without a
+// request on purpose, such as a cleanup loop or a startup check, so the
+// context check doesn't flag its queries. Handlers must pass their request
+// context instead.
+func WithBackgroundContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backgroundContextKey{}, true)
+}
+
+// CheckQueryContexts makes db log a warning for every query run with a
+// context that has no deadline and comes neither from a request nor from
+// background work marked with WithBackgroundContext, which almost
+// always means context.Background() (or context.TODO()) was passed where
+// c.Request().Context() was meant. Such a query ignores the request timeout
+// and keeps running after the client has gone away.
+//
+// It is a development aid: register it only in development. Background jobs
+// that deliberately run without a deadline must mark their context with
+// WithBackgroundContext, or they are flagged too.
+//
+// Usage:
+//
+//	if cfg.IsDevelopment() {
+//	    database.CheckQueryContexts(db)
+//	}
+func CheckQueryContexts(db *bun.DB) {
+	db.AddQueryHook(queryContextHook{})
+}
+
+// queryContextHook implements bun.QueryHook for CheckQueryContexts.
+type queryContextHook struct{}
+
+// BeforeQuery warns when the query context is unbounded and not a request's.
+func (queryContextHook) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	if isScopedContext(ctx) {
+		return ctx
+	}
+	logger.Warn("database query without request context",
+		"query", QueryLogTruncated.format(event.Query),
+		"hint", "pass c.Request().Context() instead of context.Background()",
+	)
+	return ctx
+}
+
+// isScopedContext reports whether ctx is fine to query with: it has a
+// deadline, or is marked as a request's or as deliberate background work.
+func isScopedContext(ctx context.Context) bool {
+	if _, ok := ctx.Deadline(); ok {
+		return true
+	}
+	request, _ := ctx.Value(requestContextKey{}).(bool)
+	background, _ := ctx.Value(backgroundContextKey{}).(bool)
+	return request || background
+}
+
+// AfterQuery is a no-op; the check happens before the query runs.
+func (queryContextHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {}
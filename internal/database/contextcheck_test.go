@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIsScopedContext(t *testing.T) {
+	withDeadline, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{name: "background", ctx: context.Background()},
+		{name: "cancellable without deadline", ctx: func() context.Context {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			return ctx
+		}()},
+		{name: "deadline", ctx: withDeadline, want: true},
+		{name: "request", ctx: WithRequestContext(context.Background()), want: true},
+		{name: "marked background work", ctx: WithBackgroundContext(context.Background()), want: true},
+		{name: "derived from a request", ctx: context.WithoutCancel(WithRequestContext(context.Background())), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isScopedContext(tt.ctx); got != tt.want {
+				t.Errorf("isScopedContext = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
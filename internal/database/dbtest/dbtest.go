@@ -0,0 +1,254 @@
+// Package dbtest provides an in-memory stand-in for PostgreSQL, so tests can
+// run Bun queries without a database server.
+//
+// A Server records every statement it receives and answers each one with its
+// Respond function. It implements database/sql's driver interfaces rather than
+// the PostgreSQL wire protocol: queries are still built by Bun with the
+// PostgreSQL dialect, so the recorded SQL is what the application would send.
+//
+// Usage:
+//
+//	srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+//	    return dbtest.Rows([]string{"id", "title"}, []any{1, "Dune"}), nil
+//	}}
+//	db := dbtest.Open(t, srv)
+//	err := db.NewSelect().Model(&books).Scan(ctx)
+//	// srv.Statements() == []string{`SELECT "book"."id", "book"."title" FROM "books" AS "book"`}
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// Result is the response to one statement.
+type Result struct {
+	// Columns and Rows are returned to queries. Each row holds one value
+	// per column.
+	Columns []string
+	Rows    [][]driver.Value
+
+	// RowsAffected is returned to Exec calls (INSERT, UPDATE, DELETE).
+	RowsAffected int64
+}
+
+// Rows builds a Result with the given columns and rows. Go ints are
+// converted to int64, the type database/sql drivers return.
+func Rows(columns []string, rows ...[]any) Result {
+	res := Result{Columns: columns}
+	for _, row := range rows {
+		values := make([]driver.Value, len(row))
+		for i, v := range row {
+			if n, ok := v.(int); ok {
+				v = int64(n)
+			}
+			values[i] = v
+		}
+		res.Rows = append(res.Rows, values)
+	}
+	return res
+}
+
+// Error is a PostgreSQL error with a SQLSTATE code. Like pgdriver.Error it
+// exposes the error fields through Field: 'C' is the code and 'M' the message.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e Error) Error() string {
+	return "ERROR: " + e.Message + " (SQLSTATE=" + e.Code + ")"
+}
+
+// Field returns the value of a PostgreSQL error field.
+func (e Error) Field(k byte) string {
+	switch k {
+	case 'C':
+		return e.Code
+	case 'M':
+		return e.Message
+	}
+	return ""
+}
+
+// Server is a fake PostgreSQL server. The zero value answers every
+// statement with an empty result.
+type Server struct {
+	// Respond answers a statement. It must be safe for concurrent use.
+	// Transaction control (BEGIN or BEGIN READ ONLY, COMMIT, ROLLBACK) is
+	// recorded but not passed to Respond.
+	Respond func(ctx context.Context, query string) (Result, error)
+
+	mu          sync.Mutex
+	statements  []string
+	connections int
+	down        error
+}
+
+// Open returns a Bun database connected to srv. It is closed when the test
+// finishes.
+func Open(t testing.TB, srv *Server) *bun.DB {
+	t.Helper()
+	db := bun.NewDB(sql.OpenDB(srv.Connector()), pgdialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// Connector returns a database/sql connector for srv.
+func (s *Server) Connector() driver.Connector {
+	return connector{s}
+}
+
+// Statements returns every statement received so far, in order.
+func (s *Server) Statements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.statements...)
+}
+
+// Connections returns the number of connections opened so far.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// SetDown makes new connections and pings fail with err, as if the server
+// were unreachable. SetDown(nil) brings it back.
+func (s *Server) SetDown(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = err
+}
+
+func (s *Server) downErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.down
+}
+
+func (s *Server) record(query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statements = append(s.statements, query)
+}
+
+func (s *Server) respond(ctx context.Context, query string) (Result, error) {
+	s.record(query)
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	if s.Respond == nil {
+		return Result{}, nil
+	}
+	return s.Respond(ctx, query)
+}
+
+type connector struct{ s *Server }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	if err := c.s.downErr(); err != nil {
+		return nil, err
+	}
+	c.s.mu.Lock()
+	c.s.connections++
+	c.s.mu.Unlock()
+	return &conn{s: c.s}, nil
+}
+
+func (c connector) Driver() driver.Driver { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("dbtest: use Server.Connector")
+}
+
+type conn struct{ s *Server }
+
+var (
+	_ driver.ExecerContext  = (*conn)(nil)
+	_ driver.QueryerContext = (*conn)(nil)
+	_ driver.ConnBeginTx    = (*conn)(nil)
+	_ driver.Pinger         = (*conn)(nil)
+)
+
+func (c *conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("dbtest: prepared statements are not supported")
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		c.s.record("BEGIN READ ONLY")
+	} else {
+		c.s.record("BEGIN")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return tx{c.s}, nil
+}
+
+func (c *conn) Ping(context.Context) error {
+	return c.s.downErr()
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	res, err := c.s.respond(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.RowsAffected), nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.s.respond(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: res.Columns, values: res.Rows}, nil
+}
+
+type tx struct{ s *Server }
+
+func (t tx) Commit() error {
+	t.s.record("COMMIT")
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.s.record("ROLLBACK")
+	return nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// ErrQueryCanceled is returned (wrapped) by TranslateError when a query was
+// stopped because its context ended, usually because the request timed out
+// or the client went away. The error handler answers it with 504 Gateway
+// Timeout instead of a generic 500.
+var ErrQueryCanceled = errors.New("database query canceled")
+
+// ErrPoolExhausted is returned by Acquire when no pooled connection became
+// free within the acquire timeout. The error handler answers it with 503
+// Service Unavailable and a Retry-After header.
+var ErrPoolExhausted = errors.New("database connection pool exhausted")
+
+// ErrStaleObject is returned by UpdateWithVersion when the record was changed
+// by someone else after it was loaded, so the update was not applied.
+var ErrStaleObject = errors.New("database record was modified concurrently")
+
+// ErrNotFound is returned by services.Repository when the requested row
+// doesn't exist. The error handler answers it with 404 Not Found.
+var ErrNotFound = errors.New("record not found")
+
+// ErrInvalidSort is returned by services.Repository.List when the requested
+// sort names a column that isn't sortable. The error handler answers it with
+// 400 Bad Request.
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// TranslateError maps driver errors to errors the rest of the application
+// can recognize. Cancelled or timed-out queries become ErrQueryCanceled
+// (the original error stays in the chain); other errors are returned as is.
+//
+// Usage:
+//
+//	err := db.NewSelect().Model(&books).Scan(ctx)
+//	if err != nil {
+//	    return nil, database.TranslateError(err)
+//	}
+func TranslateError(err error) error {
+	if err == nil || errors.Is(err, ErrQueryCanceled) || !isCanceled(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrQueryCanceled, err)
+}
+
+// isCanceled reports whether err means the query was cancelled: either its
+// context ended, or PostgreSQL cancelled the statement (SQLSTATE 57014).
+func isCanceled(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pgErr pgdriver.Error
+	return errors.As(err, &pgErr) && pgErr.StatementTimeout()
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"replace-me/internal/database/dbtest"
+)
+
+func TestTranslateError(t *testing.T) {
+	errOther := errors.New("relation \"books\" does not exist")
+
+	tests := []struct {
+		name         string
+		err          error
+		wantCanceled bool
+	}{
+		{name: "nil", err: nil},
+		{name: "other error", err: errOther},
+		{name: "context canceled", err: fmt.Errorf("query: %w", context.Canceled), wantCanceled: true},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, wantCanceled: true},
+		{name: "already translated", err: fmt.Errorf("%w: %w", ErrQueryCanceled, context.Canceled), wantCanceled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TranslateError(tt.err)
+
+			if canceled := errors.Is(got, ErrQueryCanceled); canceled != tt.wantCanceled {
+				t.Errorf("errors.Is(%v, ErrQueryCanceled) = %v, want %v", got, canceled, tt.wantCanceled)
+			}
+			if tt.err != nil && !errors.Is(got, tt.err) {
+				t.Errorf("original error %v lost from %v", tt.err, got)
+			}
+			if !tt.wantCanceled && got != tt.err {
+				t.Errorf("TranslateError(%v) = %v, want it unchanged", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestQueryTimeoutIsQueryCanceled(t *testing.T) {
+	srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+		// A slow query: runs until the caller gives up
+		<-ctx.Done()
+		return dbtest.Result{}, ctx.Err()
+	}}
+	db := dbtest.Open(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	q := db.NewSelect().Model((*pagedBook)(nil))
+	_, err := Paginate[pagedBook](ctx, q, 1, 10)
+
+	if !errors.Is(err, ErrQueryCanceled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want ErrQueryCanceled wrapping context.DeadlineExceeded", err)
+	}
+}
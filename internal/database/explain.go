@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/uptrace/bun"
+)
+
+// ErrExplainDisabled is returned by Explain unless EnableExplain was called.
+var ErrExplainDisabled = errors.New("database: Explain is only enabled in development")
+
+// explainEnabled is set by EnableExplain.
+var explainEnabled atomic.Bool
+
+// EnableExplain allows Explain to run. It is a development aid: query plans
+// reveal schema details and EXPLAIN ANALYZE runs the query, so enable it
+// only in development.
+//
+// Usage:
+//
+//	if cfg.IsDevelopment() {
+//	    database.EnableExplain()
+//	}
+func EnableExplain() {
+	explainEnabled.Store(true)
+}
+
+// Explain returns the query plan of q as PostgreSQL prints it, one plan
+// line per text line, to see why a query is slow without leaving the app.
+//
+// With analyze the query is actually executed (EXPLAIN (ANALYZE, BUFFERS))
+// to report real row counts and timings. It runs in a read-only transaction
+// that is always rolled back, so a select with a data-modifying CTE fails
+// instead of writing.
+//
+// Usage (in development):
+//
+//	q := db.NewSelect().Model(&books).Where("author_id = ?", id).Order("title")
+//	plan, err := database.Explain(ctx, db, q, true)
+//	if err == nil {
+//	    logger.Debug("query plan", "plan", plan)
+//	}
+func Explain(ctx context.Context, db *bun.DB, q *bun.SelectQuery, analyze bool) (string, error) {
+	if !explainEnabled.Load() {
+		return "", ErrExplainDisabled
+	}
+
+	prefix := "EXPLAIN "
+	if analyze {
+		prefix = "EXPLAIN (ANALYZE, BUFFERS) "
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", TranslateError(err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, prefix+q.String())
+	if err != nil {
+		return "", TranslateError(err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		plan = append(plan, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", TranslateError(err)
+	}
+	return strings.Join(plan, "\n"), nil
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+
+	"github.com/uptrace/bun"
+)
+
+type explainedBook struct {
+	bun.BaseModel `bun:"table:books,alias:b"`
+
+	ID    int64  `bun:"id,pk,autoincrement"`
+	Title string `bun:"title"`
+}
+
+func TestExplain(t *testing.T) {
+	plan := []string{
+		"Index Scan using books_author_id_idx on books b  (cost=0.29..8.30 rows=1 width=40)",
+		"  Index Cond: (author_id = 7)",
+	}
+	srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+		if !strings.HasPrefix(query, "EXPLAIN") {
+			return dbtest.Result{}, errors.New("not an EXPLAIN")
+		}
+		return dbtest.Rows([]string{"QUERY PLAN"}, []any{plan[0]}, []any{plan[1]}), nil
+	}}
+	db := dbtest.Open(t, srv)
+	q := db.NewSelect().Model((*explainedBook)(nil)).Where("author_id = ?", 7)
+
+	if _, err := Explain(context.Background(), db, q, false); !errors.Is(err, ErrExplainDisabled) {
+		t.Fatalf("Explain() before EnableExplain = %v, want ErrExplainDisabled", err)
+	}
+	if len(srv.Statements()) != 0 {
+		t.Fatalf("disabled Explain ran %q", srv.Statements())
+	}
+
+	EnableExplain()
+	t.Cleanup(func() { explainEnabled.Store(false) })
+
+	tests := []struct {
+		name    string
+		analyze bool
+		prefix  string
+	}{
+		{name: "plan only", prefix: "EXPLAIN SELECT "},
+		{name: "analyze", analyze: true, prefix: "EXPLAIN (ANALYZE, BUFFERS) SELECT "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(srv.Statements())
+
+			got, err := Explain(context.Background(), db, q, tt.analyze)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := strings.Join(plan, "\n"); got != want {
+				t.Errorf("Explain() = %q, want %q", got, want)
+			}
+
+			// The query runs in a read-only transaction that is rolled back
+			statements := srv.Statements()[before:]
+			if len(statements) != 3 || statements[0] != "BEGIN READ ONLY" || statements[2] != "ROLLBACK" {
+				t.Fatalf("statements = %q, want BEGIN READ ONLY, EXPLAIN, ROLLBACK", statements)
+			}
+			if !strings.HasPrefix(statements[1], tt.prefix) || !slices.Contains(strings.Fields(statements[1]), `"books"`) {
+				t.Errorf("query = %q, want %q followed by the select", statements[1], tt.prefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"replace-me/internal/logger"
+
+	"github.com/uptrace/bun"
+)
+
+// Monitor pings the database in the background and keeps the latest result,
+// so readiness probes can read the database health without pinging on every
+// request. It also logs a warning whenever a ping fails, giving continuous
+// database health telemetry.
+//
+// Monitor implements health.Checker, so it can be registered directly as the
+// "database" readiness check.
+//
+// Usage:
+//
+//	monitor := database.NewMonitor(db, cfg.DBMonitorInterval)
+//	monitor.Start()
+//	defer monitor.Stop()
+type Monitor struct {
+	db       *bun.DB
+	interval time.Duration
+
+	mu        sync.RWMutex
+	err       error
+	latency   time.Duration
+	checkedAt time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// errNotChecked is reported before the first ping completes.
+var errNotChecked = errors.New("database not checked yet")
+
+// NewMonitor creates a monitor that pings db every interval, which must be
+// greater than 0 (config.Validate checks DB_MONITOR_INTERVAL).
+func NewMonitor(db *bun.DB, interval time.Duration) *Monitor {
+	return &Monitor{
+		db:       db,
+		interval: interval,
+		err:      errNotChecked,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start pings the database once and then keeps pinging every interval in
+// a background goroutine until Stop is called.
+func (m *Monitor) Start() {
+	m.ping()
+
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.ping()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background pings and waits for the goroutine to exit.
+func (m *Monitor) Stop() {
+	close(m.stop)
+	<-m.done
+}
+
+// ping pings the database once and records the result.
+func (m *Monitor) ping() {
+	// A ping should never take longer than the interval between pings
+	ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+	defer cancel()
+
+	start := time.Now()
+	err := m.db.PingContext(ctx)
+	latency := time.Since(start)
+
+	if err != nil {
+		logger.Warn("database ping failed", "error", err.Error(), "latency", latency.String())
+	} else {
+		logger.Debug("database ping", "latency", latency.String())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+	m.latency = latency
+	m.checkedAt = time.Now()
+}
+
+// Healthy reports whether the most recent ping succeeded.
+func (m *Monitor) Healthy() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.err == nil
+}
+
+// Latency returns the duration of the most recent ping.
+func (m *Monitor) Latency() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.latency
+}
+
+// Name implements health.Checker.
+func (m *Monitor) Name() string { return "database" }
+
+// Check implements health.Checker by returning the most recent ping result.
+// It never touches the database itself.
+func (m *Monitor) Check(context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.err
+}
+
+// CheckedAt returns when the database was last pinged.
+func (m *Monitor) CheckedAt() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.checkedAt
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// onConnect holds the statements registered with OnConnect.
+var onConnect []string
+
+// OnConnect registers SQL statements that run on every new pooled
+// connection, before it is used for any query. Use it for session settings
+// that must hold on every connection, such as search_path for schema-based
+// multi-tenancy; a SET run through db would only change the one connection
+// that happened to execute it.
+//
+// Call it during startup, before New. If a statement fails, the connection
+// is discarded and the query that needed it gets the error.
+//
+// Usage:
+//
+//	database.OnConnect(database.SessionStatements(cfg.DBApplicationName, cfg.DBSearchPath)...)
+//	database.OnConnect("SET statement_timeout = '5s'")
+//	db, err := database.New(cfg.DatabaseURL, database.QueryLogOff, database.PoolOptions{})
+func OnConnect(statements ...string) {
+	onConnect = append(onConnect, statements...)
+}
+
+// SessionStatements returns the statements setting application_name and
+// search_path, for OnConnect. Empty values are left at the server default.
+//
+// application_name identifies the app in pg_stat_activity and server logs.
+// Each schema in searchPath is quoted, so names are taken literally
+// (including "$user").
+func SessionStatements(applicationName string, searchPath []string) []string {
+	var statements []string
+	if applicationName != "" {
+		statements = append(statements, "SET application_name = "+quoteLiteral(applicationName))
+	}
+	if len(searchPath) > 0 {
+		schemas := make([]string, len(searchPath))
+		for i, schema := range searchPath {
+			schemas[i] = quoteIdentifier(schema)
+		}
+		statements = append(statements, "SET search_path = "+strings.Join(schemas, ", "))
+	}
+	return statements
+}
+
+// quoteLiteral quotes s as an SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quoteIdentifier quotes s as an SQL identifier.
+func quoteIdentifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// onConnectConnector runs statements on each connection its Connector opens.
+type onConnectConnector struct {
+	driver.Connector
+	statements []string
+}
+
+// Connect implements driver.Connector.
+func (c onConnectConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("on-connect statements: %T does not support ExecContext", conn)
+	}
+	for _, statement := range c.statements {
+		if _, err := execer.ExecContext(ctx, statement, nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("on-connect statement %q: %w", statement, err)
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+)
+
+func TestSessionStatements(t *testing.T) {
+	tests := []struct {
+		name       string
+		appName    string
+		searchPath []string
+		want       []string
+	}{
+		{name: "nothing set"},
+		{name: "application name", appName: "books-api", want: []string{`SET application_name = 'books-api'`}},
+		{name: "quote in application name", appName: "o'reilly", want: []string{`SET application_name = 'o''reilly'`}},
+		{
+			name:       "search path",
+			searchPath: []string{"tenant_7", "$user", "public"},
+			want:       []string{`SET search_path = "tenant_7", "$user", "public"`},
+		},
+		{
+			name:       "both",
+			appName:    "books-api",
+			searchPath: []string{`we"ird`},
+			want:       []string{`SET application_name = 'books-api'`, `SET search_path = "we""ird"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SessionStatements(tt.appName, tt.searchPath); !slices.Equal(got, tt.want) {
+				t.Errorf("SessionStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnConnectConnector(t *testing.T) {
+	statements := []string{`SET application_name = 'books-api'`, `SET search_path = "tenant_7"`}
+
+	t.Run("runs statements on each new connection", func(t *testing.T) {
+		srv := &dbtest.Server{}
+		db := sql.OpenDB(onConnectConnector{Connector: srv.Connector(), statements: statements})
+		defer db.Close()
+
+		ctx := context.Background()
+		conn1, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn2, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn1.ExecContext(ctx, "SELECT 1"); err != nil {
+			t.Fatal(err)
+		}
+		conn1.Close()
+		conn2.Close()
+
+		// A reused pooled connection doesn't repeat them
+		conn3, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn3.Close()
+
+		want := slices.Concat(statements, statements, []string{"SELECT 1"})
+		if got := srv.Statements(); !slices.Equal(got, want) {
+			t.Errorf("statements = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("failing statement discards the connection", func(t *testing.T) {
+		errSearchPath := dbtest.Error{Code: "3F000", Message: `schema "tenant_7" does not exist`}
+		srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+			if strings.HasPrefix(query, "SET search_path") {
+				return dbtest.Result{}, errSearchPath
+			}
+			return dbtest.Result{}, nil
+		}}
+		db := sql.OpenDB(onConnectConnector{Connector: srv.Connector(), statements: statements})
+		defer db.Close()
+
+		_, err := db.ExecContext(context.Background(), "SELECT 1")
+		if !errors.As(err, new(dbtest.Error)) {
+			t.Fatalf("ExecContext() = %v, want the search_path error", err)
+		}
+		if slices.Contains(srv.Statements(), "SELECT 1") {
+			t.Error("query ran on a connection whose setup failed")
+		}
+	})
+}
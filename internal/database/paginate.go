@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// PageInfo describes one page of a paginated result set.
+type PageInfo struct {
+	Page    int `json:"page"`     // 1-based page number
+	PerPage int `json:"per_page"` // Maximum items per page
+	Total   int `json:"total"`    // Total items across all pages
+}
+
+// TotalPages returns the number of pages, at least 1.
+func (p PageInfo) TotalPages() int {
+	if p.PerPage <= 0 || p.Total == 0 {
+		return 1
+	}
+	return (p.Total + p.PerPage - 1) / p.PerPage
+}
+
+// HasPrev reports whether there is a page before this one.
+func (p PageInfo) HasPrev() bool {
+	return p.Page > 1
+}
+
+// HasNext reports whether there is a page after this one.
+func (p PageInfo) HasNext() bool {
+	return p.Page < p.TotalPages()
+}
+
+// Page is a page of results together with its pagination info.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	PageInfo
+}
+
+// Paginate runs q for the given 1-based page and returns that page of
+// results along with the total count. Page numbers below 1 are treated as 1.
+//
+// Usage:
+//
+//	q := db.NewSelect().Model((*models.Book)(nil)).Order("id")
+//	page, err := database.Paginate[models.Book](ctx, q, 2, 20)
+func Paginate[T any](ctx context.Context, q *bun.SelectQuery, page, perPage int) (*Page[T], error) {
+	if page < 1 {
+		page = 1
+	}
+
+	var items []T
+	total, err := q.Limit(perPage).Offset((page-1)*perPage).ScanAndCount(ctx, &items)
+	if err != nil {
+		return nil, TranslateError(err)
+	}
+
+	return &Page[T]{
+		Items: items,
+		PageInfo: PageInfo{
+			Page:    page,
+			PerPage: perPage,
+			Total:   total,
+		},
+	}, nil
+}
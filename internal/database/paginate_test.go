@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+)
+
+type pagedBook struct {
+	ID    int64
+	Title string
+}
+
+func TestPaginate(t *testing.T) {
+	srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+		if strings.HasPrefix(query, "SELECT count(*)") {
+			return dbtest.Rows([]string{"count"}, []any{45}), nil
+		}
+		return dbtest.Rows([]string{"id", "title"}, []any{11, "Dune"}, []any{12, "Emma"}), nil
+	}}
+	db := dbtest.Open(t, srv)
+
+	q := db.NewSelect().Model((*pagedBook)(nil)).Order("id")
+	page, err := Paginate[pagedBook](context.Background(), q, 2, 10)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+
+	if len(page.Items) != 2 || page.Items[0].Title != "Dune" {
+		t.Errorf("Items = %+v, want the two returned rows", page.Items)
+	}
+	if page.PageInfo != (PageInfo{Page: 2, PerPage: 10, Total: 45}) {
+		t.Errorf("PageInfo = %+v", page.PageInfo)
+	}
+	if page.TotalPages() != 5 || !page.HasPrev() || !page.HasNext() {
+		t.Errorf("TotalPages = %d, HasPrev = %v, HasNext = %v, want 5, true, true", page.TotalPages(), page.HasPrev(), page.HasNext())
+	}
+
+	var selected bool
+	for _, query := range srv.Statements() {
+		if strings.HasSuffix(query, "LIMIT 10 OFFSET 10") {
+			selected = true
+		}
+	}
+	if !selected {
+		t.Errorf("no query selected page 2, statements: %q", srv.Statements())
+	}
+}
+
+func TestPageInfo(t *testing.T) {
+	tests := []struct {
+		info      PageInfo
+		wantPages int
+		wantPrev  bool
+		wantNext  bool
+	}{
+		{PageInfo{Page: 1, PerPage: 10, Total: 0}, 1, false, false},
+		{PageInfo{Page: 1, PerPage: 10, Total: 10}, 1, false, false},
+		{PageInfo{Page: 1, PerPage: 10, Total: 11}, 2, false, true},
+		{PageInfo{Page: 2, PerPage: 10, Total: 11}, 2, true, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.info.TotalPages(); got != tt.wantPages {
+			t.Errorf("%+v: TotalPages() = %d, want %d", tt.info, got, tt.wantPages)
+		}
+		if tt.info.HasPrev() != tt.wantPrev || tt.info.HasNext() != tt.wantNext {
+			t.Errorf("%+v: HasPrev, HasNext = %v, %v, want %v, %v", tt.info, tt.info.HasPrev(), tt.info.HasNext(), tt.wantPrev, tt.wantNext)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/uptrace/bun"
+)
+
+// queryCountKey is the context key under which the per-request query counter
+// is stored.
+type queryCountKey struct{}
+
+// WithQueryCounter returns a context that counts the database queries issued
+// with it (or any context derived from it). Read the count with QueryCount.
+//
+// The query budget middleware installs a counter for every request, so
+// handlers only need to pass c.Request().Context() to their queries.
+func WithQueryCounter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryCountKey{}, new(atomic.Int64))
+}
+
+// QueryCount returns the number of queries issued with ctx so far,
+// or 0 if ctx has no counter (see WithQueryCounter).
+func QueryCount(ctx context.Context) int {
+	counter, ok := ctx.Value(queryCountKey{}).(*atomic.Int64)
+	if !ok {
+		return 0
+	}
+	return int(counter.Load())
+}
+
+// queryCountHook implements bun.QueryHook to count queries per request.
+// Queries whose context has no counter are ignored.
+type queryCountHook struct{}
+
+// BeforeQuery increments the counter in the query context, if there is one.
+func (queryCountHook) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	if counter, ok := ctx.Value(queryCountKey{}).(*atomic.Int64); ok {
+		counter.Add(1)
+	}
+	return ctx
+}
+
+// AfterQuery is a no-op; counting happens before the query runs.
+func (queryCountHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+)
+
+func TestQueryCount(t *testing.T) {
+	db := dbtest.Open(t, &dbtest.Server{})
+	db.AddQueryHook(queryCountHook{})
+
+	ctx := WithQueryCounter(context.Background())
+	for range 3 {
+		if _, err := db.NewSelect().ColumnExpr("1").Exec(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Contexts derived from the request context share its counter
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if _, err := db.NewSelect().ColumnExpr("1").Exec(child); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := QueryCount(ctx); got != 4 {
+		t.Errorf("QueryCount = %d, want 4", got)
+	}
+
+	// Queries without a counter are not counted anywhere
+	if _, err := db.NewSelect().ColumnExpr("1").Exec(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := QueryCount(ctx); got != 4 {
+		t.Errorf("QueryCount after an uncounted query = %d, want 4", got)
+	}
+	if got := QueryCount(context.Background()); got != 0 {
+		t.Errorf("QueryCount without a counter = %d, want 0", got)
+	}
+}
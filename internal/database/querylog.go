@@ -0,0 +1,98 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// QueryLogMode controls how the query logging hook records query text.
+type QueryLogMode string
+
+const (
+	// QueryLogFull logs the complete query, including literal values.
+	// Best for development.
+	QueryLogFull QueryLogMode = "full"
+
+	// QueryLogTruncated logs the query cut off at maxLoggedQueryLength.
+	QueryLogTruncated QueryLogMode = "truncated"
+
+	// QueryLogHashed logs a stable fingerprint of the normalized query instead
+	// of its text. Queries that differ only in literal values share a
+	// fingerprint, so logs can be grouped by query shape without leaking data.
+	QueryLogHashed QueryLogMode = "hashed"
+
+	// QueryLogOff disables query logging entirely.
+	QueryLogOff QueryLogMode = "off"
+)
+
+// maxLoggedQueryLength is the number of bytes kept in QueryLogTruncated mode.
+const maxLoggedQueryLength = 1024
+
+// queryKey returns the log attribute name used for the query in this mode.
+func (m QueryLogMode) queryKey() string {
+	if m == QueryLogHashed {
+		return "query_fingerprint"
+	}
+	return "query"
+}
+
+// format returns the representation of query to log in this mode.
+func (m QueryLogMode) format(query string) string {
+	switch m {
+	case QueryLogTruncated:
+		if len(query) > maxLoggedQueryLength {
+			return query[:maxLoggedQueryLength] + "...(truncated)"
+		}
+		return query
+	case QueryLogHashed:
+		return fingerprintQuery(query)
+	default:
+		return query
+	}
+}
+
+var (
+	// stringLiteralPattern matches single-quoted SQL strings, including '' escapes.
+	stringLiteralPattern = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+	// numberLiteralPattern matches numeric literals that aren't part of an identifier.
+	numberLiteralPattern = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+
+	// placeholderPattern matches positional ($1) and anonymous (?) placeholders.
+	placeholderPattern = regexp.MustCompile(`\$\d+|\?`)
+
+	// inListPattern matches an IN list made only of placeholders after normalization.
+	inListPattern = regexp.MustCompile(`(?i)\bIN\s*\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+
+	// whitespacePattern matches runs of whitespace.
+	whitespacePattern = regexp.MustCompile(`\s+`)
+
+	// punctuationPattern matches punctuation with any surrounding whitespace.
+	punctuationPattern = regexp.MustCompile(`\s*([=<>!,()])\s*`)
+)
+
+// normalizeQuery reduces a query to its shape by replacing literal values and
+// placeholders with "?", collapsing IN lists to a single "IN (?)", and
+// squashing whitespace.
+//
+// Example:
+//
+//	SELECT * FROM users WHERE id IN (1, 2, 3) AND name = 'bob'
+//	-> select * from users where id in(?)and name=?
+func normalizeQuery(query string) string {
+	q := stringLiteralPattern.ReplaceAllString(query, "?")
+	q = placeholderPattern.ReplaceAllString(q, "?")
+	q = numberLiteralPattern.ReplaceAllString(q, "?")
+	q = inListPattern.ReplaceAllString(q, "IN (?)")
+	q = whitespacePattern.ReplaceAllString(q, " ")
+	q = punctuationPattern.ReplaceAllString(q, "$1")
+	return strings.ToLower(strings.TrimSpace(q))
+}
+
+// fingerprintQuery returns a short, stable hash of the normalized query.
+func fingerprintQuery(query string) string {
+	sum := sha256.Sum256([]byte(normalizeQuery(query)))
+	return hex.EncodeToString(sum[:8])
+}
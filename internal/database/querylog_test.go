@@ -0,0 +1,78 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			"SELECT * FROM users WHERE id IN (1, 2, 3) AND name = 'bob'",
+			"select * from users where id in(?)and name=?",
+		},
+		{
+			"SELECT * FROM users WHERE name = 'o''brien'",
+			"select * from users where name=?",
+		},
+		{
+			"UPDATE  items\n\tSET price = 9.99 WHERE id = $1",
+			"update items set price=? where id=?",
+		},
+		{
+			"SELECT * FROM table2 WHERE col_1 > 10",
+			"select * from table2 where col_1>?",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeQuery(tt.query); got != tt.want {
+			t.Errorf("normalizeQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryLogModeFormat(t *testing.T) {
+	t.Run("hashed groups queries by shape", func(t *testing.T) {
+		a := QueryLogHashed.format("SELECT * FROM users WHERE email = 'alice@example.com'")
+		b := QueryLogHashed.format("SELECT * FROM users WHERE email = 'bob@example.com'")
+		c := QueryLogHashed.format("SELECT * FROM orders WHERE email = 'alice@example.com'")
+
+		if a != b {
+			t.Errorf("fingerprints differ for the same query shape: %q vs %q", a, b)
+		}
+		if a == c {
+			t.Errorf("fingerprints match for different query shapes: %q", a)
+		}
+		if strings.Contains(a, "alice") || len(a) != 16 {
+			t.Errorf("fingerprint = %q, want 16 hex characters without literals", a)
+		}
+		if got := QueryLogHashed.queryKey(); got != "query_fingerprint" {
+			t.Errorf("queryKey() = %q, want query_fingerprint", got)
+		}
+	})
+
+	t.Run("truncated cuts long queries", func(t *testing.T) {
+		long := "SELECT '" + strings.Repeat("x", 2*maxLoggedQueryLength) + "'"
+		got := QueryLogTruncated.format(long)
+		if want := long[:maxLoggedQueryLength] + "...(truncated)"; got != want {
+			t.Errorf("format() returned %d bytes, want %d", len(got), len(want))
+		}
+		if short := "SELECT 1"; QueryLogTruncated.format(short) != short {
+			t.Errorf("short query was modified")
+		}
+	})
+
+	t.Run("full keeps the query", func(t *testing.T) {
+		query := "SELECT * FROM users WHERE id = 1"
+		if got := QueryLogFull.format(query); got != query {
+			t.Errorf("format() = %q, want %q", got, query)
+		}
+		if got := QueryLogFull.queryKey(); got != "query" {
+			t.Errorf("queryKey() = %q, want query", got)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"replace-me/internal/logger"
+
+	"github.com/uptrace/bun"
+)
+
+// ReplicatedDB is a primary connection plus read replicas, created by
+// NewWithReplicas.
+//
+// It embeds the primary *bun.DB, so inserts, updates, deletes, raw queries
+// and transactions all run on the primary. Only NewSelect is overridden: it
+// builds the query on the next replica in round-robin order.
+//
+// Replicas lag behind the primary, so a select right after a write may not
+// see it. When a handler needs to read its own writes, use Primary:
+//
+//	db.Primary().NewSelect().Model(&order).WherePK().Scan(ctx)
+//
+// Selects inside RunInTx always use the transaction, and so the primary.
+// Pass the embedded primary to helpers that take a *bun.DB:
+//
+//	database.RunInTx(ctx, db.Primary(), fn)
+type ReplicatedDB struct {
+	*bun.DB
+
+	replicas []*bun.DB
+	next     atomic.Uint64
+}
+
+// NewWithReplicas connects to the primary and every replica with
+// NewWithRetry, using the same query log mode, pool and retry options for
+// all of them. With no replicaDSNs, selects go to the primary.
+//
+// To size the pools per node, adjust them after creation, e.g. to give
+// replicas a bigger pool than the primary:
+//
+//	db, err := database.NewWithReplicas(cfg.DatabaseURL, cfg.DatabaseReplicaURLs, database.QueryLogOff, database.PoolOptions{}, database.RetryOptions{})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer db.Close()
+//
+//	db.Primary().SetMaxOpenConns(10)
+//	for _, replica := range db.Replicas() {
+//	    replica.SetMaxOpenConns(40)
+//	}
+func NewWithReplicas(primaryDSN string, replicaDSNs []string, queryLogMode QueryLogMode, pool PoolOptions, retry RetryOptions) (*ReplicatedDB, error) {
+	primary, err := NewWithRetry(primaryDSN, queryLogMode, pool, retry)
+	if err != nil {
+		return nil, fmt.Errorf("primary: %w", err)
+	}
+
+	db := &ReplicatedDB{DB: primary}
+	for i, dsn := range replicaDSNs {
+		replica, err := NewWithRetry(dsn, queryLogMode, pool, retry)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("replica %d (%s): %w", i+1, sanitizeDSN(dsn), err)
+		}
+		db.replicas = append(db.replicas, replica)
+	}
+	return db, nil
+}
+
+// Primary returns the primary connection, for reads that must see the
+// latest writes.
+func (db *ReplicatedDB) Primary() *bun.DB {
+	return db.DB
+}
+
+// Replica returns the next replica in round-robin order, or the primary if
+// there are no replicas.
+func (db *ReplicatedDB) Replica() *bun.DB {
+	if len(db.replicas) == 0 {
+		return db.DB
+	}
+	n := db.next.Add(1) - 1
+	return db.replicas[n%uint64(len(db.replicas))]
+}
+
+// Replicas returns every replica connection, in the order of the DSNs
+// passed to NewWithReplicas.
+func (db *ReplicatedDB) Replicas() []*bun.DB {
+	return db.replicas
+}
+
+// NewSelect builds a select query on the next replica.
+func (db *ReplicatedDB) NewSelect() *bun.SelectQuery {
+	return db.Replica().NewSelect()
+}
+
+// HealthCheck pings the primary and every replica. The error names each
+// connection that is down, so one failing replica can be told apart from
+// the primary being unreachable.
+func (db *ReplicatedDB) HealthCheck(ctx context.Context) error {
+	var errs []error
+	if err := db.DB.PingContext(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("primary: %w", err))
+	}
+	for i, replica := range db.replicas {
+		if err := replica.PingContext(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("replica %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the primary and every replica connection.
+func (db *ReplicatedDB) Close() error {
+	logger.Info("closing database connections", "replicas", len(db.replicas))
+	errs := []error{db.DB.Close()}
+	for _, replica := range db.replicas {
+		errs = append(errs, replica.Close())
+	}
+	return errors.Join(errs...)
+}
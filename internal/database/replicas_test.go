@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+
+	"github.com/uptrace/bun"
+)
+
+func TestReplicatedDBRouting(t *testing.T) {
+	primary, replica1, replica2 := &dbtest.Server{}, &dbtest.Server{}, &dbtest.Server{}
+	db := &ReplicatedDB{
+		DB:       dbtest.Open(t, primary),
+		replicas: []*bun.DB{dbtest.Open(t, replica1), dbtest.Open(t, replica2)},
+	}
+	ctx := context.Background()
+
+	for _, table := range []string{"first", "second", "third"} {
+		if _, err := db.NewSelect().TableExpr(table).Column("id").Exec(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.NewDelete().TableExpr("sessions").Where("expires_at < now()").Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	err := RunInTx(ctx, db.Primary(), func(ctx context.Context, tx bun.IDB) error {
+		_, err := DB(ctx, db).NewSelect().TableExpr("stock").Column("id").For("UPDATE").Exec(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		srv  *dbtest.Server
+		want []string
+	}{
+		{name: "replica 1", srv: replica1, want: []string{`SELECT "id" FROM first`, `SELECT "id" FROM third`}},
+		{name: "replica 2", srv: replica2, want: []string{`SELECT "id" FROM second`}},
+		{
+			name: "primary",
+			srv:  primary,
+			want: []string{
+				`DELETE FROM sessions WHERE (expires_at < now())`,
+				"BEGIN",
+				`SELECT "id" FROM stock FOR UPDATE`,
+				"COMMIT",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.srv.Statements(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplicatedDBWithoutReplicas(t *testing.T) {
+	primary := &dbtest.Server{}
+	db := &ReplicatedDB{DB: dbtest.Open(t, primary)}
+
+	if _, err := db.NewSelect().TableExpr("books").Column("id").Exec(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := primary.Statements(), []string{`SELECT "id" FROM books`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
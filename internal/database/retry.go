@@ -0,0 +1,80 @@
+package database
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"replace-me/internal/logger"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// RetryOptions controls how NewWithRetry waits for the database.
+//
+// Attempt n waits BaseDelay * 2^(n-1), capped at MaxDelay, before trying
+// again. The wait is jittered down to as little as half of that, so
+// instances started together don't retry in lockstep.
+type RetryOptions struct {
+	// MaxAttempts is the total number of pings, including the first.
+	// Values below 1 mean a single attempt.
+	MaxAttempts int
+
+	// BaseDelay is the wait after the first failed attempt (default: 500ms).
+	BaseDelay time.Duration
+
+	// MaxDelay caps the wait between attempts (default: 10s).
+	MaxDelay time.Duration
+}
+
+// delay returns how long to wait after the given failed attempt (1-based).
+func (o RetryOptions) delay(attempt int) time.Duration {
+	base, maxDelay := o.BaseDelay, o.MaxDelay
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+
+	d := maxDelay
+	if shift := attempt - 1; shift < 32 && base<<shift > 0 && base<<shift < maxDelay {
+		d = base << shift
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// pingWithRetry pings db until it answers or the attempts are used up,
+// returning the last error. Errors reported by PostgreSQL itself (wrong
+// password, unknown database) are returned at once: waiting won't fix them.
+func pingWithRetry(db *bun.DB, retry RetryOptions) error {
+	attempts := max(retry.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		err := db.Ping()
+		if err == nil || attempt >= attempts || !isTransientConnectError(err) {
+			return err
+		}
+
+		delay := retry.delay(attempt)
+		logger.Warn("database not ready, retrying",
+			"attempt", attempt,
+			"max_attempts", attempts,
+			"retry_in", delay.String(),
+			"error", err.Error(),
+		)
+		time.Sleep(delay)
+	}
+}
+
+// isTransientConnectError reports whether a failed ping is worth retrying:
+// the server couldn't be reached, or it is still starting up or shutting
+// down (SQLSTATE class 57P, e.g. "the database system is starting up").
+func isTransientConnectError(err error) bool {
+	var pgErr pgdriver.Error
+	if errors.As(err, &pgErr) {
+		code := pgErr.Field('C')
+		return len(code) == 5 && code[:3] == "57P"
+	}
+	return true
+}
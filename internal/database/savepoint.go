@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/uptrace/bun"
+)
+
+// savepointSeq makes savepoint names unique, so nested savepoints never
+// shadow each other.
+var savepointSeq atomic.Uint64
+
+// WithSavepoint runs fn inside a savepoint of the transaction tx, so that
+// fn's changes can be undone without aborting the whole transaction. The
+// savepoint is released if fn returns nil and rolled back to if fn returns
+// an error or panics; the transaction itself stays usable either way. fn's
+// error is returned as is.
+//
+// tx must be a transaction, e.g. the one passed to a RunInTx callback.
+// Savepoints nest: fn may call WithSavepoint again.
+//
+// Usage:
+//
+//	err := database.RunInTx(ctx, s.db, func(ctx context.Context, tx bun.IDB) error {
+//	    if err := s.orders.Create(ctx, order); err != nil {
+//	        return err
+//	    }
+//	    // Best effort: a failed reward doesn't cancel the order
+//	    if err := database.WithSavepoint(ctx, tx, func(ctx context.Context) error {
+//	        return s.rewards.Grant(ctx, order.UserID)
+//	    }); err != nil {
+//	        logger.WarnContext(ctx, "reward not granted", "error", err.Error())
+//	    }
+//	    return nil
+//	})
+func WithSavepoint(ctx context.Context, tx bun.IDB, fn func(ctx context.Context) error) (err error) {
+	name := "sp_" + strconv.FormatUint(savepointSeq.Add(1), 10)
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return TranslateError(err)
+	}
+
+	panicked := true
+	defer func() {
+		if !panicked && err == nil {
+			return
+		}
+		// Roll back even if ctx is done, or the transaction stays aborted
+		_, rbErr := tx.ExecContext(context.WithoutCancel(ctx), "ROLLBACK TO SAVEPOINT "+name)
+		if rbErr != nil && !panicked {
+			err = errors.Join(err, rbErr)
+		}
+	}()
+
+	err = fn(ctx)
+	panicked = false
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return TranslateError(err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+
+	"github.com/uptrace/bun"
+)
+
+func TestWithSavepoint(t *testing.T) {
+	errFailed := errors.New("failed")
+	insert := func(ctx context.Context, db bun.IDB, table string) error {
+		_, err := db.NewRaw("INSERT INTO ? DEFAULT VALUES", bun.Ident(table)).Exec(ctx)
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		fn      func(ctx context.Context) error
+		wantErr error
+		want    []string
+	}{
+		{
+			name: "released on success",
+			fn:   func(ctx context.Context) error { return nil },
+			want: []string{"RELEASE SAVEPOINT sp"},
+		},
+		{
+			name:    "rolled back on error",
+			fn:      func(ctx context.Context) error { return errFailed },
+			wantErr: errFailed,
+			want:    []string{"ROLLBACK TO SAVEPOINT sp"},
+		},
+		{
+			name: "rolled back on panic",
+			fn:   func(ctx context.Context) error { panic("boom") },
+			want: []string{"ROLLBACK TO SAVEPOINT sp"},
+		},
+	}
+
+	// Savepoint names carry a global sequence number; compare without it
+	savepointName := regexp.MustCompile(`sp_\d+`)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &dbtest.Server{}
+			db := dbtest.Open(t, srv)
+
+			var spErr error
+			err := RunInTx(context.Background(), db, func(ctx context.Context, tx bun.IDB) error {
+				func() {
+					defer func() { recover() }()
+					spErr = WithSavepoint(ctx, tx, func(ctx context.Context) error {
+						if err := insert(ctx, tx, "rewards"); err != nil {
+							return err
+						}
+						return tt.fn(ctx)
+					})
+				}()
+				// The outer transaction is still usable afterwards
+				return insert(ctx, tx, "orders")
+			})
+			if err != nil {
+				t.Fatalf("RunInTx = %v, want the outer transaction to commit", err)
+			}
+			if !errors.Is(spErr, tt.wantErr) {
+				t.Errorf("WithSavepoint = %v, want %v", spErr, tt.wantErr)
+			}
+
+			var got []string
+			for _, stmt := range srv.Statements() {
+				got = append(got, savepointName.ReplaceAllString(stmt, "sp"))
+			}
+			want := slices.Concat(
+				[]string{"BEGIN", "SAVEPOINT sp", `INSERT INTO "rewards" DEFAULT VALUES`},
+				tt.want,
+				[]string{`INSERT INTO "orders" DEFAULT VALUES`, "COMMIT"},
+			)
+			if !slices.Equal(got, want) {
+				t.Errorf("statements = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWithSavepointNested(t *testing.T) {
+	srv := &dbtest.Server{}
+	db := dbtest.Open(t, srv)
+
+	err := RunInTx(context.Background(), db, func(ctx context.Context, tx bun.IDB) error {
+		return WithSavepoint(ctx, tx, func(ctx context.Context) error {
+			return WithSavepoint(ctx, tx, func(ctx context.Context) error { return nil })
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// BEGIN, SAVEPOINT outer, SAVEPOINT inner, RELEASE inner, RELEASE outer, COMMIT
+	statements := srv.Statements()
+	if len(statements) != 6 {
+		t.Fatalf("statements = %q", statements)
+	}
+	if statements[1] == statements[2] {
+		t.Errorf("nested savepoints share a name: %q", statements[1])
+	}
+	if statements[2] != "SAVEPOINT "+statements[3][len("RELEASE SAVEPOINT "):] {
+		t.Errorf("inner savepoint released out of order: %q", statements)
+	}
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"replace-me/internal/logger"
+
+	"github.com/uptrace/bun"
+)
+
+// maxSerializationAttempts is how many times RetryOnSerialization runs a
+// transaction, including the first attempt.
+const maxSerializationAttempts = 3
+
+// RetryOnSerialization runs fn in a transaction like RunInTx, and runs it
+// again when PostgreSQL aborts the transaction because it conflicted with a
+// concurrent one: a serialization failure (SQLSTATE 40001, common under
+// REPEATABLE READ and SERIALIZABLE) or a deadlock (40P01). Any other error
+// is returned at once. fn must be safe to run more than once.
+//
+// Each retry also takes one from the request's retry budget (see
+// WithRetryBudget). Once the budget is used up, a conflict is returned to
+// the caller instead of retried, so a request that keeps conflicting can't
+// keep adding load to an already contended database.
+//
+// Inside an enclosing transaction fn runs once: the conflict aborted the
+// outer transaction too, so only its owner can retry.
+//
+// Usage:
+//
+//	err := database.RetryOnSerialization(ctx, s.db, func(ctx context.Context, tx bun.IDB) error {
+//	    return s.transfer(ctx, tx, from, to, amount)
+//	})
+func RetryOnSerialization(ctx context.Context, db *bun.DB, fn func(ctx context.Context, tx bun.IDB) error) error {
+	if _, ok := TxFromContext(ctx); ok {
+		return RunInTx(ctx, db, fn)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := RunInTx(ctx, db, fn)
+		if err == nil || !isSerializationFailure(err) || attempt >= maxSerializationAttempts {
+			return err
+		}
+		if !takeRetry(ctx) {
+			logger.WarnContext(ctx, "retry budget exhausted, not retrying transaction",
+				"attempt", attempt,
+				"error", err.Error(),
+			)
+			return err
+		}
+
+		// Back off briefly so the conflicting transaction can finish
+		delay := time.Duration(attempt) * 10 * time.Millisecond
+		select {
+		case <-time.After(delay/2 + rand.N(delay/2+1)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// fieldError is implemented by PostgreSQL errors that expose their error
+// fields, such as pgdriver.Error, whose 'C' field is the SQLSTATE code.
+type fieldError interface {
+	Field(k byte) string
+}
+
+// isSerializationFailure reports whether err is a transaction conflict
+// that a retry can resolve (SQLSTATE 40001 or 40P01).
+func isSerializationFailure(err error) bool {
+	var pgErr fieldError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	code := pgErr.Field('C')
+	return code == "40001" || code == "40P01"
+}
+
+// retryBudgetKey is the context key under which the per-request retry
+// budget is stored.
+type retryBudgetKey struct{}
+
+// WithRetryBudget returns a context that allows at most budget transaction
+// retries in total across every RetryOnSerialization call made with it (or
+// any context derived from it).
+//
+// The retry budget middleware installs one for every request
+// (REQUEST_RETRY_BUDGET). Without a budget in the context, retries are only
+// limited per call.
+func WithRetryBudget(ctx context.Context, budget int) context.Context {
+	remaining := new(atomic.Int64)
+	remaining.Store(int64(budget))
+	return context.WithValue(ctx, retryBudgetKey{}, remaining)
+}
+
+// RetriesLeft returns how many retries the budget in ctx still allows, or
+// false if ctx has no budget (see WithRetryBudget).
+func RetriesLeft(ctx context.Context) (int, bool) {
+	remaining, ok := ctx.Value(retryBudgetKey{}).(*atomic.Int64)
+	if !ok {
+		return 0, false
+	}
+	return int(remaining.Load()), true
+}
+
+// takeRetry takes one retry from the budget in ctx, reporting false if it
+// is used up. A context without a budget always allows the retry.
+func takeRetry(ctx context.Context) bool {
+	remaining, ok := ctx.Value(retryBudgetKey{}).(*atomic.Int64)
+	if !ok {
+		return true
+	}
+	for {
+		n := remaining.Load()
+		if n <= 0 {
+			return false
+		}
+		if remaining.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+
+	"github.com/uptrace/bun"
+)
+
+func TestRetryOnSerialization(t *testing.T) {
+	errSerialization := dbtest.Error{Code: "40001", Message: "could not serialize access due to concurrent update"}
+	errDeadlock := dbtest.Error{Code: "40P01", Message: "deadlock detected"}
+	errUnique := dbtest.Error{Code: "23505", Message: "duplicate key value violates unique constraint"}
+
+	tests := []struct {
+		name         string
+		budget       int // -1 means no budget in the context
+		failures     []error
+		wantErr      error
+		wantAttempts int
+		wantLeft     int
+	}{
+		{name: "succeeds first time", budget: -1, wantAttempts: 1},
+		{name: "retries a serialization failure", budget: -1, failures: []error{errSerialization}, wantAttempts: 2},
+		{name: "retries a deadlock", budget: -1, failures: []error{errDeadlock}, wantAttempts: 2},
+		{name: "other errors are not retried", budget: -1, failures: []error{errUnique}, wantErr: errUnique, wantAttempts: 1},
+		{
+			name:         "gives up after the last attempt",
+			budget:       -1,
+			failures:     []error{errSerialization, errSerialization, errSerialization},
+			wantErr:      errSerialization,
+			wantAttempts: maxSerializationAttempts,
+		},
+		{name: "retry takes from the budget", budget: 3, failures: []error{errSerialization}, wantAttempts: 2, wantLeft: 2},
+		{
+			name:         "spent budget stops retries",
+			budget:       1,
+			failures:     []error{errSerialization, errSerialization},
+			wantErr:      errSerialization,
+			wantAttempts: 2,
+			wantLeft:     0,
+		},
+		{name: "zero budget disables retries", budget: 0, failures: []error{errSerialization}, wantErr: errSerialization, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+				if !strings.HasPrefix(query, "UPDATE") {
+					return dbtest.Result{}, nil
+				}
+				n := int(attempts.Add(1))
+				if n <= len(tt.failures) {
+					return dbtest.Result{}, tt.failures[n-1]
+				}
+				return dbtest.Result{RowsAffected: 1}, nil
+			}}
+			db := dbtest.Open(t, srv)
+
+			ctx := context.Background()
+			if tt.budget >= 0 {
+				ctx = WithRetryBudget(ctx, tt.budget)
+			}
+
+			err := RetryOnSerialization(ctx, db, func(ctx context.Context, tx bun.IDB) error {
+				_, err := tx.ExecContext(ctx, "UPDATE accounts SET balance = balance - 10 WHERE id = 1")
+				return err
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RetryOnSerialization() = %v, want %v", err, tt.wantErr)
+			}
+			if got := int(attempts.Load()); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if left, ok := RetriesLeft(ctx); ok != (tt.budget >= 0) || left != tt.wantLeft {
+				t.Errorf("RetriesLeft() = %d, %v, want %d, %v", left, ok, tt.wantLeft, tt.budget >= 0)
+			}
+		})
+	}
+}
+
+func TestRetryOnSerializationInsideTx(t *testing.T) {
+	var attempts atomic.Int32
+	srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+		attempts.Add(1)
+		return dbtest.Result{}, dbtest.Error{Code: "40001", Message: "could not serialize access"}
+	}}
+	db := dbtest.Open(t, srv)
+
+	// Only the owner of the outer transaction could retry it
+	err := RunInTx(context.Background(), db, func(ctx context.Context, tx bun.IDB) error {
+		return RetryOnSerialization(ctx, db, func(ctx context.Context, tx bun.IDB) error {
+			_, err := tx.ExecContext(ctx, "UPDATE accounts SET balance = 0")
+			return err
+		})
+	})
+
+	if !isSerializationFailure(err) {
+		t.Errorf("err = %v, want the serialization failure", err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// StreamRows runs a select query and calls fn once per result row, without
+// loading the whole result set into memory. Use it instead of Scan for large
+// exports and batch jobs.
+//
+// Iteration stops at the first error returned by fn, on a scan error, or when
+// ctx is cancelled; that error is returned. Rows are always closed, even on
+// early return, so the connection goes back to the pool.
+//
+// Usage:
+//
+//	q := db.NewSelect().Model((*models.Book)(nil)).Order("id")
+//	err := database.StreamRows(ctx, q, func(b models.Book) error {
+//	    return csvWriter.Write([]string{b.Title, b.Author})
+//	})
+func StreamRows[T any](ctx context.Context, q *bun.SelectQuery, fn func(T) error) error {
+	rows, err := q.Rows(ctx)
+	if err != nil {
+		return TranslateError(err)
+	}
+	defer rows.Close()
+
+	db := q.DB()
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var row T
+		if err := db.ScanRow(ctx, rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return TranslateError(rows.Err())
+}
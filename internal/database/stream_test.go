@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+)
+
+type streamedBook struct {
+	ID    int64
+	Title string
+}
+
+func TestStreamRows(t *testing.T) {
+	srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+		return dbtest.Rows([]string{"id", "title"},
+			[]any{1, "Dune"},
+			[]any{2, "Emma"},
+			[]any{3, "Ulysses"},
+		), nil
+	}}
+	db := dbtest.Open(t, srv)
+	ctx := context.Background()
+
+	t.Run("visits every row", func(t *testing.T) {
+		var titles []string
+		q := db.NewSelect().Model((*streamedBook)(nil)).Order("id")
+		err := StreamRows(ctx, q, func(b streamedBook) error {
+			titles = append(titles, b.Title)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("StreamRows: %v", err)
+		}
+		if len(titles) != 3 || titles[0] != "Dune" || titles[2] != "Ulysses" {
+			t.Errorf("titles = %v, want [Dune Emma Ulysses]", titles)
+		}
+	})
+
+	t.Run("stops at the first callback error", func(t *testing.T) {
+		errStop := errors.New("stop")
+		var seen int
+		q := db.NewSelect().Model((*streamedBook)(nil))
+		err := StreamRows(ctx, q, func(b streamedBook) error {
+			seen++
+			if b.ID == 2 {
+				return errStop
+			}
+			return nil
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("err = %v, want %v", err, errStop)
+		}
+		if seen != 2 {
+			t.Errorf("callback ran %d times, want 2", seen)
+		}
+	})
+
+	t.Run("stops when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var seen int
+		q := db.NewSelect().Model((*streamedBook)(nil))
+		err := StreamRows(ctx, q, func(streamedBook) error {
+			seen++
+			cancel()
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+		if seen != 1 {
+			t.Errorf("callback ran %d times, want 1", seen)
+		}
+	})
+
+	if got := db.Stats().InUse; got != 0 {
+		t.Errorf("%d connections still in use, want 0", got)
+	}
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// txKey is the context key under which RunInTx stores the current transaction.
+type txKey struct{}
+
+// RunInTx runs fn inside a transaction, committing if fn returns nil and
+// rolling back otherwise.
+//
+// The transaction is stored in the context passed to fn, so service methods
+// called from fn can join it with DB or TxFromContext. If ctx already carries
+// a transaction, fn runs inside that one instead of opening a nested one; the
+// outermost RunInTx decides whether everything commits. If ctx carries a
+// connection taken from db (see WithConn), the transaction runs on it.
+//
+// Usage:
+//
+//	err := database.RunInTx(ctx, s.db, func(ctx context.Context, tx bun.IDB) error {
+//	    if err := s.orders.Create(ctx, order); err != nil { // joins tx
+//	        return err
+//	    }
+//	    _, err := tx.NewUpdate().Model(stock).WherePK().Exec(ctx)
+//	    return err
+//	})
+func RunInTx(ctx context.Context, db *bun.DB, fn func(ctx context.Context, tx bun.IDB) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return fn(ctx, tx)
+	}
+	run := func(ctx context.Context, tx bun.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx), tx)
+	}
+	if conn, ok := connFromContext(ctx, db); ok {
+		return conn.RunInTx(ctx, nil, run)
+	}
+	return db.RunInTx(ctx, nil, run)
+}
+
+// TxFromContext returns the transaction started by an enclosing RunInTx,
+// or false if ctx is not inside one.
+func TxFromContext(ctx context.Context) (bun.IDB, bool) {
+	tx, ok := ctx.Value(txKey{}).(bun.Tx)
+	if !ok {
+		return nil, false
+	}
+	return tx, true
+}
+
+// DB returns the transaction from ctx if there is one, then the
+// connection the request took from db's pool (see WithConn), and db
+// otherwise. Service methods use it so they work both standalone and as
+// part of a caller's transaction. db may also be a *ReplicatedDB, so
+// selects outside a transaction go to a replica; writes that should use
+// the request's connection pass its Primary().
+//
+// Usage:
+//
+//	func (s *OrderService) Create(ctx context.Context, order *models.Order) error {
+//	    _, err := database.DB(ctx, s.db).NewInsert().Model(order).Exec(ctx)
+//	    return err
+//	}
+func DB(ctx context.Context, db bun.IDB) bun.IDB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	if conn, ok := connFromContext(ctx, db); ok {
+		return conn
+	}
+	return db
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+
+	"github.com/uptrace/bun"
+)
+
+func TestRunInTx(t *testing.T) {
+	errFailed := errors.New("failed")
+	insert := func(ctx context.Context, db bun.IDB, table string) error {
+		_, err := db.NewRaw("INSERT INTO ? DEFAULT VALUES", bun.Ident(table)).Exec(ctx)
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		fn      func(ctx context.Context, db *bun.DB) error
+		wantErr error
+		want    []string
+	}{
+		{
+			name: "commits on success",
+			fn: func(ctx context.Context, db *bun.DB) error {
+				return RunInTx(ctx, db, func(ctx context.Context, tx bun.IDB) error {
+					return insert(ctx, tx, "orders")
+				})
+			},
+			want: []string{"BEGIN", `INSERT INTO "orders" DEFAULT VALUES`, "COMMIT"},
+		},
+		{
+			name: "rolls back on error",
+			fn: func(ctx context.Context, db *bun.DB) error {
+				return RunInTx(ctx, db, func(ctx context.Context, tx bun.IDB) error {
+					if err := insert(ctx, tx, "orders"); err != nil {
+						return err
+					}
+					return errFailed
+				})
+			},
+			wantErr: errFailed,
+			want:    []string{"BEGIN", `INSERT INTO "orders" DEFAULT VALUES`, "ROLLBACK"},
+		},
+		{
+			name: "nested call joins the outer transaction",
+			fn: func(ctx context.Context, db *bun.DB) error {
+				return RunInTx(ctx, db, func(ctx context.Context, tx bun.IDB) error {
+					if err := insert(ctx, tx, "orders"); err != nil {
+						return err
+					}
+					return RunInTx(ctx, db, func(ctx context.Context, inner bun.IDB) error {
+						if inner != tx {
+							t.Error("nested RunInTx got a different transaction")
+						}
+						return insert(ctx, inner, "stock")
+					})
+				})
+			},
+			want: []string{"BEGIN", `INSERT INTO "orders" DEFAULT VALUES`, `INSERT INTO "stock" DEFAULT VALUES`, "COMMIT"},
+		},
+		{
+			name: "nested error rolls back everything",
+			fn: func(ctx context.Context, db *bun.DB) error {
+				return RunInTx(ctx, db, func(ctx context.Context, tx bun.IDB) error {
+					if err := insert(ctx, tx, "orders"); err != nil {
+						return err
+					}
+					return RunInTx(ctx, db, func(ctx context.Context, inner bun.IDB) error {
+						return errFailed
+					})
+				})
+			},
+			wantErr: errFailed,
+			want:    []string{"BEGIN", `INSERT INTO "orders" DEFAULT VALUES`, "ROLLBACK"},
+		},
+		{
+			name: "DB joins the transaction in ctx",
+			fn: func(ctx context.Context, db *bun.DB) error {
+				if err := insert(ctx, DB(ctx, db), "audit"); err != nil {
+					return err
+				}
+				return RunInTx(ctx, db, func(ctx context.Context, _ bun.IDB) error {
+					return insert(ctx, DB(ctx, db), "orders")
+				})
+			},
+			want: []string{`INSERT INTO "audit" DEFAULT VALUES`, "BEGIN", `INSERT INTO "orders" DEFAULT VALUES`, "COMMIT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &dbtest.Server{}
+			db := dbtest.Open(t, srv)
+
+			err := tt.fn(context.Background(), db)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got := srv.Statements(); !slices.Equal(got, tt.want) {
+				t.Errorf("statements = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTxFromContext(t *testing.T) {
+	db := dbtest.Open(t, &dbtest.Server{})
+	ctx := context.Background()
+
+	if _, ok := TxFromContext(ctx); ok {
+		t.Error("TxFromContext found a transaction outside RunInTx")
+	}
+	if DB(ctx, db) != bun.IDB(db) {
+		t.Error("DB outside a transaction did not return db")
+	}
+
+	err := RunInTx(ctx, db, func(ctx context.Context, tx bun.IDB) error {
+		got, ok := TxFromContext(ctx)
+		if !ok || got != tx {
+			t.Errorf("TxFromContext = %v, %v, want the transaction", got, ok)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
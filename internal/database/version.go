@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// VersionedModel is a model with a version column, such as one embedding
+// models.Versioned.
+type VersionedModel interface {
+	CurrentVersion() int64
+	SetVersion(version int64)
+}
+
+// UpdateWithVersion updates model by primary key only if its version column
+// still matches the version it was loaded with, and increments the version.
+// If another request updated the row first, nothing is written and
+// ErrStaleObject is returned; the caller should reload the record and let
+// the user retry, rather than silently overwrite the other change.
+//
+// The query is equivalent to:
+//
+//	UPDATE books SET ..., version = 4 WHERE id = ? AND version = 3
+//
+// Usage (in a service's Update method):
+//
+//	book.Title = title
+//	if err := database.UpdateWithVersion(ctx, database.DB(ctx, s.db), book); err != nil {
+//	    if errors.Is(err, database.ErrStaleObject) {
+//	        return ErrBookChanged // e.g. mapped to 409 Conflict by the handler
+//	    }
+//	    return err
+//	}
+func UpdateWithVersion(ctx context.Context, db bun.IDB, model VersionedModel) error {
+	current := model.CurrentVersion()
+	model.SetVersion(current + 1)
+
+	res, err := db.NewUpdate().
+		Model(model).
+		WherePK().
+		Where("?TableAlias.version = ?", current).
+		Exec(ctx)
+	if err != nil {
+		model.SetVersion(current)
+		return TranslateError(err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		model.SetVersion(current)
+		if err != nil {
+			return err
+		}
+		return ErrStaleObject
+	}
+	return nil
+}
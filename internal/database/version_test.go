@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"replace-me/internal/database/dbtest"
+	"replace-me/internal/models"
+
+	"github.com/uptrace/bun"
+)
+
+type versionedBook struct {
+	bun.BaseModel `bun:"table:books,alias:b"`
+	models.Versioned
+
+	ID    int64  `bun:"id,pk,autoincrement"`
+	Title string `bun:"title"`
+}
+
+func TestUpdateWithVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		wantErr      error
+		wantVersion  int64
+	}{
+		{name: "row unchanged since load", rowsAffected: 1, wantVersion: 4},
+		{name: "row updated by someone else", rowsAffected: 0, wantErr: ErrStaleObject, wantVersion: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+				return dbtest.Result{RowsAffected: tt.rowsAffected}, nil
+			}}
+			db := dbtest.Open(t, srv)
+
+			book := &versionedBook{ID: 7, Title: "Dune", Versioned: models.Versioned{Version: 3}}
+			err := UpdateWithVersion(context.Background(), db, book)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if book.Version != tt.wantVersion {
+				t.Errorf("Version = %d, want %d", book.Version, tt.wantVersion)
+			}
+
+			statements := srv.Statements()
+			if len(statements) != 1 {
+				t.Fatalf("statements = %q, want one UPDATE", statements)
+			}
+			query := statements[0]
+			for _, want := range []string{`"version" = 4`, `("b".version = 3)`, `("b"."id" = 7)`} {
+				if !strings.Contains(query, want) {
+					t.Errorf("query %q does not contain %q", query, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+// Package errorreport sends server errors (5xx responses, panics) to an
+// external error tracking service.
+//
+// During an incident the same error can happen thousands of times a minute.
+// The default reporter deduplicates identical errors within a window and caps
+// the number of reports sent per window, so the tracking service sees one
+// event per distinct error (with a count of suppressed duplicates) instead of
+// a flood.
+//
+// Reporting is disabled (a no-op) until Init is called with a DSN.
+//
+// Usage:
+//
+//	// Initialize once at startup
+//	errorreport.Init(cfg.ErrorReportDSN, cfg.ErrorReportWindow)
+//	defer errorreport.Flush(ctx)
+//
+//	// Report an error with request context
+//	errorreport.Capture(err, errorreport.Fields{RequestID: id, Path: "/books"})
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"replace-me/internal/logger"
+)
+
+// Reporter sends errors to an error tracking service.
+type Reporter interface {
+	// Capture queues err for reporting. It must not block the caller.
+	Capture(err error, fields Fields)
+
+	// Flush waits until queued reports are sent or ctx is done.
+	Flush(ctx context.Context) error
+}
+
+// Fields is the request context attached to a report.
+type Fields struct {
+	RequestID string `json:"request_id,omitempty"`
+	Path      string `json:"path,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+}
+
+// Event is the JSON payload posted to the DSN.
+type Event struct {
+	Error     string    `json:"error"`
+	Fields    Fields    `json:"context"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Suppressed is the number of identical errors dropped since the last
+	// report of this error.
+	Suppressed int `json:"suppressed"`
+}
+
+// reporter is the global reporter, a no-op until Init is called.
+var reporter Reporter = nopReporter{}
+
+// Init configures the global reporter. An empty dsn disables reporting.
+//
+// Parameters:
+//   - dsn: URL that receives each event as a JSON POST
+//   - window: identical errors are reported at most once per window
+func Init(dsn string, window time.Duration) {
+	if dsn == "" {
+		reporter = nopReporter{}
+		return
+	}
+	reporter = newHTTPReporter(dsn, window)
+}
+
+// Capture reports err through the global reporter.
+func Capture(err error, fields Fields) {
+	reporter.Capture(err, fields)
+}
+
+// Flush waits for the global reporter to send queued reports.
+// Call it during shutdown so the last errors aren't lost.
+func Flush(ctx context.Context) error {
+	return reporter.Flush(ctx)
+}
+
+// nopReporter discards all reports. Used when no DSN is configured.
+type nopReporter struct{}
+
+func (nopReporter) Capture(error, Fields)       {}
+func (nopReporter) Flush(context.Context) error { return nil }
+
+// maxReportsPerWindow caps the number of distinct errors sent per window.
+const maxReportsPerWindow = 100
+
+// queueSize is the number of events that can wait to be sent.
+// Events beyond it are dropped rather than blocking request handling.
+const queueSize = 256
+
+// httpReporter posts deduplicated, rate-limited events to a DSN.
+type httpReporter struct {
+	dsn    string
+	window time.Duration
+	client *http.Client
+	queue  chan queued
+
+	mu          sync.Mutex
+	seen        map[string]*seenError
+	windowStart time.Time
+	sent        int
+}
+
+// queued is an entry of the send queue: an event to send, or a Flush
+// waiting for the events queued before it.
+type queued struct {
+	event Event
+	// flushed, when set, is closed once every earlier event was sent
+	flushed chan struct{}
+}
+
+// seenError tracks an error message within the current window.
+type seenError struct {
+	lastReported time.Time
+	suppressed   int
+}
+
+func newHTTPReporter(dsn string, window time.Duration) *httpReporter {
+	r := &httpReporter{
+		dsn:    dsn,
+		window: window,
+		client: &http.Client{Timeout: 5 * time.Second},
+		queue:  make(chan queued, queueSize),
+		seen:   make(map[string]*seenError),
+	}
+	go r.run()
+	return r
+}
+
+// Capture implements Reporter.
+func (r *httpReporter) Capture(err error, fields Fields) {
+	if err == nil {
+		return
+	}
+	now := time.Now()
+	event, ok := r.admit(err.Error(), now)
+	if !ok {
+		return
+	}
+	event.Fields = fields
+
+	select {
+	case r.queue <- queued{event: event}:
+	default:
+		logger.Warn("error report queue full, dropping report", "error", event.Error)
+	}
+}
+
+// admit decides whether an error should be sent now. Identical messages
+// within the window are counted instead of sent, and at most
+// maxReportsPerWindow events are sent per window.
+func (r *httpReporter) admit(message string, now time.Time) (Event, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.windowStart) >= r.window {
+		r.windowStart = now
+		r.sent = 0
+		for key, s := range r.seen {
+			if now.Sub(s.lastReported) >= r.window && s.suppressed == 0 {
+				delete(r.seen, key)
+			}
+		}
+	}
+
+	s, ok := r.seen[message]
+	if ok && now.Sub(s.lastReported) < r.window {
+		s.suppressed++
+		return Event{}, false
+	}
+	if r.sent >= maxReportsPerWindow {
+		if !ok {
+			s = &seenError{}
+			r.seen[message] = s
+		}
+		s.suppressed++
+		return Event{}, false
+	}
+
+	event := Event{Error: message, Timestamp: now.UTC()}
+	if ok {
+		event.Suppressed = s.suppressed
+	}
+	r.seen[message] = &seenError{lastReported: now}
+	r.sent++
+
+	return event, true
+}
+
+// run sends queued events one at a time, in order, and releases each Flush
+// once the events queued before it are sent.
+func (r *httpReporter) run() {
+	for q := range r.queue {
+		if q.flushed != nil {
+			close(q.flushed)
+			continue
+		}
+		r.send(q.event)
+	}
+}
+
+// sendStackSize is the maximum number of bytes of stack trace logged when
+// sending a report panics.
+const sendStackSize = 4 << 10 // 4 KB
+
+// send posts a single event to the DSN. Failures are logged, not retried.
+// A panic is logged rather than allowed to stop the sender, which would
+// leave every later report, and Flush, stuck in the queue.
+func (r *httpReporter) send(event Event) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			stack := make([]byte, sendStackSize)
+			stack = stack[:runtime.Stack(stack, false)]
+			logger.Error("error report panic recovered", "panic", fmt.Sprint(recovered), "error", event.Error, "stack", string(stack))
+		}
+	}()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("failed to encode error report", "error", err.Error())
+		return
+	}
+	resp, err := r.client.Post(r.dsn, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.Warn("failed to send error report", "error", err.Error())
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		logger.Warn("error report rejected", "status", resp.StatusCode)
+	}
+}
+
+// Flush implements Reporter. It queues a marker behind the pending events
+// and waits for the sender to reach it, so events captured while Flush
+// waits are sent too but not waited for.
+func (r *httpReporter) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case r.queue <- queued{flushed: flushed}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
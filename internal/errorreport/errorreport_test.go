@@ -0,0 +1,129 @@
+package errorreport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdmit(t *testing.T) {
+	const window = time.Minute
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	type capture struct {
+		message string
+		at      time.Duration // after start
+	}
+	tests := []struct {
+		name     string
+		captures []capture
+		// want is whether each capture is sent, and wantSuppressed the
+		// duplicate count carried by the sent ones
+		want           []bool
+		wantSuppressed []int
+	}{
+		{
+			name:           "duplicates within the window are suppressed",
+			captures:       []capture{{"boom", 0}, {"boom", time.Second}, {"boom", 30 * time.Second}},
+			want:           []bool{true, false, false},
+			wantSuppressed: []int{0, 0, 0},
+		},
+		{
+			name:           "reported again after the window with the count",
+			captures:       []capture{{"boom", 0}, {"boom", time.Second}, {"boom", window + time.Second}},
+			want:           []bool{true, false, true},
+			wantSuppressed: []int{0, 0, 1},
+		},
+		{
+			name:           "distinct errors are each sent",
+			captures:       []capture{{"boom", 0}, {"bang", time.Second}},
+			want:           []bool{true, true},
+			wantSuppressed: []int{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &httpReporter{window: window, seen: make(map[string]*seenError)}
+			for i, c := range tt.captures {
+				event, ok := r.admit(c.message, start.Add(c.at))
+				if ok != tt.want[i] {
+					t.Fatalf("capture %d (%s): sent = %v, want %v", i, c.message, ok, tt.want[i])
+				}
+				if ok && event.Suppressed != tt.wantSuppressed[i] {
+					t.Errorf("capture %d (%s): suppressed = %d, want %d", i, c.message, event.Suppressed, tt.wantSuppressed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAdmitCapsReportsPerWindow(t *testing.T) {
+	r := &httpReporter{window: time.Minute, seen: make(map[string]*seenError)}
+	now := time.Now()
+	sent := 0
+	for i := range maxReportsPerWindow + 10 {
+		if _, ok := r.admit(fmt.Sprintf("error %d", i), now); ok {
+			sent++
+		}
+	}
+	if sent != maxReportsPerWindow {
+		t.Errorf("sent %d reports in one window, want %d", sent, maxReportsPerWindow)
+	}
+}
+
+func TestFlushWaitsForQueuedReports(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []Event
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var event Event
+		if err := json.NewDecoder(req.Body).Decode(&event); err != nil {
+			t.Errorf("decode event: %v", err)
+		}
+		// A slow tracker: Flush must still wait for every event
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	r := newHTTPReporter(srv.URL, time.Minute)
+	errs := []string{"first", "second", "third"}
+	for _, msg := range errs {
+		r.Capture(errors.New(msg), Fields{RequestID: "req-1"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Concurrent Flushes and Captures must not race
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Capture(errors.New("concurrent"), Fields{})
+			if err := r.Flush(ctx); err != nil {
+				t.Errorf("Flush: %v", err)
+			}
+		}()
+	}
+	if err := r.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	mu.Lock()
+	got := len(received)
+	mu.Unlock()
+	if got < len(errs) {
+		t.Errorf("Flush returned after %d of %d reports were sent", got, len(errs))
+	}
+	wg.Wait()
+}
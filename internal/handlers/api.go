@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"time"
+
+	"replace-me/internal/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+// APIVersion describes a version of the JSON API mounted at /api/{Name}.
+type APIVersion struct {
+	// Name is the version path segment, e.g. "v1".
+	Name string
+
+	// Deprecated marks every response of this version with deprecation headers.
+	Deprecated bool
+
+	// DeprecatedAt is when the version was deprecated (Deprecation header).
+	// Optional; the header is "true" when unset.
+	DeprecatedAt time.Time
+
+	// Sunset is when the version will be removed (Sunset header). Optional.
+	Sunset time.Time
+
+	// Link points clients at migration documentation. Optional.
+	Link string
+}
+
+// MountAPIVersion creates the route group for an API version.
+//
+// Every version group is JSON-only (see middleware.APIOnly). Deprecated
+// versions also get Deprecation/Sunset headers on every response. Extra
+// middleware (auth, rate limiting) applies to this version only.
+//
+// Usage:
+//
+//	v1 := handlers.MountAPIVersion(e, handlers.APIVersion{
+//	    Name:       "v1",
+//	    Deprecated: true,
+//	    Sunset:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+//	})
+//	v1.GET("/books", h.ListBooksV1)
+//
+//	v2 := handlers.MountAPIVersion(e, handlers.APIVersion{Name: "v2"}, authMiddleware)
+//	v2.GET("/books", h.ListBooks)
+func MountAPIVersion(e *echo.Echo, version APIVersion, m ...echo.MiddlewareFunc) *echo.Group {
+	chain := []echo.MiddlewareFunc{middleware.APIOnly()}
+	if version.Deprecated {
+		chain = append(chain, middleware.Deprecated(version.DeprecatedAt, version.Sunset, version.Link))
+	}
+	chain = append(chain, m...)
+
+	return e.Group("/api/"+version.Name, chain...)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMountAPIVersion(t *testing.T) {
+	e := echo.New()
+	ok := func(c echo.Context) error {
+		return negotiate(c, http.StatusOK, map[string]string{"status": "ok"}, nil)
+	}
+
+	var v2Calls int
+	countV2 := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			v2Calls++
+			return next(c)
+		}
+	}
+
+	v1 := MountAPIVersion(e, APIVersion{
+		Name:         "v1",
+		Deprecated:   true,
+		DeprecatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:       time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		Link:         "https://example.com/docs/v2-migration",
+	})
+	v1.GET("/status", ok)
+	v2 := MountAPIVersion(e, APIVersion{Name: "v2"}, countV2)
+	v2.GET("/status", ok)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		// API routes answer JSON even to browsers
+		req.Header.Set(echo.HeaderAccept, "text/html")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("deprecated version", func(t *testing.T) {
+		rec := serve("/api/v1/status")
+
+		want := map[string]string{
+			"Deprecation": "@1767225600",
+			"Sunset":      "Wed, 01 Jul 2026 00:00:00 GMT",
+			"Link":        `<https://example.com/docs/v2-migration>; rel="deprecation"`,
+		}
+		for header, value := range want {
+			if got := rec.Header().Get(header); got != value {
+				t.Errorf("%s = %q, want %q", header, got, value)
+			}
+		}
+		if got := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, echo.MIMEApplicationJSON) {
+			t.Errorf("Content-Type = %q, want JSON", got)
+		}
+	})
+
+	t.Run("current version", func(t *testing.T) {
+		rec := serve("/api/v2/status")
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if got := rec.Header().Get("Deprecation"); got != "" {
+			t.Errorf("Deprecation = %q, want none", got)
+		}
+		if v2Calls != 1 {
+			t.Errorf("version middleware ran %d times, want 1", v2Calls)
+		}
+	})
+}
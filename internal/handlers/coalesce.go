@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"replace-me/internal/logger"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// inflight tracks computations in progress for coalesce.
+var inflight singleflight.Group
+
+// coalesceTimeout bounds a coalesced computation. It no longer has its
+// callers' deadlines, so without one a hung query would hold the key, and
+// every later caller for it, forever.
+const coalesceTimeout = 30 * time.Second
+
+// coalesce runs fn once for all concurrent callers with the same key and
+// gives each of them its result. Use it around expensive work whose result
+// is cached, so that when the cache entry expires under load only one
+// request recomputes it while the others wait, instead of all of them
+// hitting the database at once (a cache stampede).
+//
+// fn runs with the first caller's context minus its cancellation and
+// deadline: one client disconnecting must not fail the computation for
+// everyone waiting on it. It gets coalesceTimeout instead. Each caller
+// still stops waiting when its own context ends. The result is shared
+// between callers, so treat it as read-only.
+//
+// A panic in fn is returned to every caller as an error and logged with
+// its stack trace. singleflight would otherwise re-panic it in its own
+// goroutine, where nothing can recover it and the process crashes.
+//
+// Usage:
+//
+//	stats, err := coalesce(ctx, "dashboard-stats", func(ctx context.Context) (*Stats, error) {
+//	    if stats, ok := h.statsCache.Get(); ok {
+//	        return stats, nil
+//	    }
+//	    stats, err := h.stats.Compute(ctx) // the expensive part
+//	    if err == nil {
+//	        h.statsCache.Set(stats, time.Minute)
+//	    }
+//	    return stats, err
+//	})
+func coalesce[T any](ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	detached := context.WithoutCancel(ctx)
+	ch := inflight.DoChan(key, func() (v any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				stack := make([]byte, renderStackSize)
+				stack = stack[:runtime.Stack(stack, false)]
+
+				err = fmt.Errorf("coalesce %q: panic: %v", key, r)
+				logger.Error("coalesced function panic recovered", "error", err.Error(), "stack", string(stack))
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(detached, coalesceTimeout)
+		defer cancel()
+		return fn(ctx)
+	})
+
+	select {
+	case res := <-ch:
+		// Val is a nil interface when T is an interface and fn returned nil
+		v, _ := res.Val.(T)
+		return v, res.Err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalesce(t *testing.T) {
+	errCompute := errors.New("compute failed")
+
+	tests := []struct {
+		name    string
+		fn      func(ctx context.Context) (int, error)
+		want    int
+		wantErr string
+	}{
+		{
+			name: "value",
+			fn:   func(context.Context) (int, error) { return 42, nil },
+			want: 42,
+		},
+		{
+			name:    "error",
+			fn:      func(context.Context) (int, error) { return 0, errCompute },
+			wantErr: errCompute.Error(),
+		},
+		{
+			name:    "panic becomes an error",
+			fn:      func(context.Context) (int, error) { panic("boom") },
+			wantErr: "panic: boom",
+		},
+		{
+			name: "work has a deadline",
+			fn: func(ctx context.Context) (int, error) {
+				if _, ok := ctx.Deadline(); !ok {
+					return 0, errors.New("no deadline")
+				}
+				return 1, nil
+			},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := coalesce(context.Background(), "test-"+tt.name, tt.fn)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoalesceSharesOneRun(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 7, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make(chan int, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := coalesce(context.Background(), "shared", fn)
+			if err != nil {
+				t.Errorf("coalesce: %v", err)
+			}
+			results <- v
+		}()
+	}
+	// Let every caller join the in-flight call before it finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn ran %d times, want 1", n)
+	}
+	for v := range results {
+		if v != 7 {
+			t.Errorf("caller got %d, want 7", v)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NotModified sets the ETag and Last-Modified validators for the current
+// response and, if the request's conditional headers show the client already
+// has this version, writes 304 Not Modified and returns true. The handler
+// should then return without rendering.
+//
+// Pass "" or the zero time to skip a validator. The ETag may be given with
+// or without quotes; derive it from something that changes whenever the
+// rendered page would, such as a record's ID and updated_at.
+//
+// If-None-Match takes precedence over If-Modified-Since, as RFC 9110 requires.
+// Only GET and HEAD requests are answered with 304.
+//
+// Usage:
+//
+//	etag := fmt.Sprintf("book-%d-%d", book.ID, book.UpdatedAt.Unix())
+//	if NotModified(c, etag, book.UpdatedAt) {
+//	    return nil
+//	}
+//	return render(c, http.StatusOK, pages.Book(book))
+func NotModified(c echo.Context, etag string, lastModified time.Time) bool {
+	header := c.Response().Header()
+	if etag != "" {
+		if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+			etag = `"` + etag + `"`
+		}
+		header.Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		header.Set(echo.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	req := c.Request()
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	var notModified bool
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		notModified = etag != "" && etagMatches(inm, etag)
+	} else if ims := req.Header.Get(echo.HeaderIfModifiedSince); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		// HTTP dates have one-second precision
+		notModified = err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+
+	if notModified {
+		c.Response().WriteHeader(http.StatusNotModified)
+	}
+	return notModified
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using weak comparison: W/"x" and "x" are considered equal.
+func etagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestNotModified(t *testing.T) {
+	updated := time.Date(2026, 10, 17, 12, 0, 0, 500, time.UTC)
+	lastModified := updated.Format(http.TimeFormat)
+	earlier := updated.Add(-time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		etag    string
+		want    bool
+	}{
+		{name: "no conditional headers", etag: "book-1"},
+		{name: "matching ETag", etag: "book-1", headers: map[string]string{"If-None-Match": `"book-1"`}, want: true},
+		{name: "weak match", etag: "book-1", headers: map[string]string{"If-None-Match": `W/"book-1"`}, want: true},
+		{name: "one of several", etag: "book-1", headers: map[string]string{"If-None-Match": `"book-0", "book-1"`}, want: true},
+		{name: "wildcard", etag: "book-1", headers: map[string]string{"If-None-Match": `*`}, want: true},
+		{name: "changed ETag", etag: "book-2", headers: map[string]string{"If-None-Match": `"book-1"`}},
+		{name: "not modified since", headers: map[string]string{"If-Modified-Since": lastModified}, want: true},
+		{name: "modified since", headers: map[string]string{"If-Modified-Since": earlier}},
+		{name: "If-None-Match wins over If-Modified-Since", etag: "book-2", headers: map[string]string{"If-None-Match": `"book-1"`, "If-Modified-Since": lastModified}},
+		{name: "HEAD", method: http.MethodHead, etag: "book-1", headers: map[string]string{"If-None-Match": `"book-1"`}, want: true},
+		{name: "POST is never 304", method: http.MethodPost, etag: "book-1", headers: map[string]string{"If-None-Match": `"book-1"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/books/1", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			got := NotModified(c, tt.etag, updated)
+
+			if got != tt.want {
+				t.Errorf("NotModified() = %v, want %v", got, tt.want)
+			}
+			if tt.want && rec.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want 304", rec.Code)
+			}
+			if tt.etag != "" && rec.Header().Get("ETag") != `"`+tt.etag+`"` {
+				t.Errorf("ETag = %q, want %q", rec.Header().Get("ETag"), `"`+tt.etag+`"`)
+			}
+			if got := rec.Header().Get(echo.HeaderLastModified); got != lastModified {
+				t.Errorf("Last-Modified = %q, want %q", got, lastModified)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// csvFlushRows is how many CSV rows are written between flushes, so the
+// client sees the download progress without a flush per row.
+const csvFlushRows = 100
+
+// StreamCSV sends a CSV file download, writing rows as they are produced
+// instead of buffering the whole export in memory.
+//
+// headers is written as the first row (skipped if empty). rows is called
+// once and passes each data row to yield; it should return yield's error
+// as soon as it gets one. yield fails once the request context is cancelled
+// (the client went away), which stops the export and releases any database
+// rows it was reading.
+//
+// Once the first byte is sent the status is committed, so an error midway
+// truncates the file rather than producing an error page; it is still
+// returned and logged.
+//
+// Like any streaming response, export routes must skip the global Timeout
+// middleware, whose writer can't flush: declare them with
+// middleware.SetStreamingRoutes. Gzip compression is fine: text/csv is
+// compressed and flushes pass through.
+//
+// Usage, paired with database.StreamRows:
+//
+//	func (h *Handlers) ExportBooks(c echo.Context) error {
+//	    ctx := c.Request().Context()
+//	    q := h.db.NewSelect().Model((*models.Book)(nil)).Order("id")
+//	    return h.StreamCSV(c, "books.csv", []string{"Title", "Author"}, func(yield func([]string) error) error {
+//	        return database.StreamRows(ctx, q, func(b models.Book) error {
+//	            return yield([]string{b.Title, b.Author})
+//	        })
+//	    })
+//	}
+//
+//	middleware.SetStreamingRoutes("GET /books/export.csv")
+//	e.GET("/books/export.csv", h.ExportBooks)
+func (h *Handlers) StreamCSV(c echo.Context, filename string, headers []string, rows func(yield func([]string) error) error) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{
+		"filename": filename,
+	}))
+	res.WriteHeader(http.StatusOK)
+
+	s := h.newStream(c)
+	defer s.Close()
+	w := csv.NewWriter(s)
+	flush := func() error {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		return s.Flush()
+	}
+
+	if len(headers) > 0 {
+		if err := w.Write(headers); err != nil {
+			return err
+		}
+	}
+
+	ctx := c.Request().Context()
+	written := 0
+	err := rows(func(record []string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		written++
+		if written%csvFlushRows == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestStreamCSV(t *testing.T) {
+	errExport := errors.New("export failed")
+
+	tests := []struct {
+		name     string
+		headers  []string
+		rows     [][]string
+		rowsErr  error
+		wantBody string
+		wantErr  error
+	}{
+		{
+			name:     "headers and rows",
+			headers:  []string{"Title", "Author"},
+			rows:     [][]string{{"Dune", "Herbert"}, {"Emma", "Austen"}},
+			wantBody: "Title,Author\nDune,Herbert\nEmma,Austen\n",
+		},
+		{
+			name:     "no headers",
+			rows:     [][]string{{"a", "b"}},
+			wantBody: "a,b\n",
+		},
+		{
+			name:     "fields are quoted",
+			rows:     [][]string{{"Hello, world", `say "hi"`}},
+			wantBody: "\"Hello, world\",\"say \"\"hi\"\"\"\n",
+		},
+		{
+			name:    "error midway",
+			headers: []string{"n"},
+			rows:    [][]string{{"1"}},
+			rowsErr: errExport,
+			wantErr: errExport,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handlers{
+				cfg:     &config.Config{StreamWriteTimeout: time.Second},
+				streams: newStreamTracker(),
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/export.csv", nil), rec)
+
+			err := h.StreamCSV(c, "books.csv", tt.headers, func(yield func([]string) error) error {
+				for _, row := range tt.rows {
+					if err := yield(row); err != nil {
+						return err
+					}
+				}
+				return tt.rowsErr
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("StreamCSV error = %v, want %v", err, tt.wantErr)
+			}
+			if got := rec.Header().Get(echo.HeaderContentDisposition); got != `attachment; filename=books.csv` {
+				t.Errorf("Content-Disposition = %q", got)
+			}
+			if tt.wantErr == nil && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"replace-me/templates/components"
+
+	"github.com/labstack/echo/v4"
+)
+
+// renderFieldErrors answers a failed HTMX form submission by updating the
+// error message of every field in place (see components.FieldErrorsOOB),
+// leaving the form and what the user typed untouched.
+//
+// errs maps field names to messages and targets maps every field of the
+// form to the ID of its components.FieldError element, so fields that are
+// now valid get their old error cleared.
+//
+// The response is a 200 with "HX-Reswap: none": HTMX doesn't process
+// responses with an error status by default, and "none" stops it from
+// swapping the response into the form's own target, so only the
+// out-of-band fragments are applied.
+//
+// Usage:
+//
+//	if len(errs) > 0 {
+//	    return renderFieldErrors(c, errs, map[string]string{
+//	        "name":  "name-error",
+//	        "email": "email-error",
+//	    })
+//	}
+func renderFieldErrors(c echo.Context, errs map[string]string, targets map[string]string) error {
+	c.Response().Header().Set("HX-Reswap", "none")
+	return render(c, http.StatusOK, components.FieldErrorsOOB(errs, targets))
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRenderFieldErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/signup", nil), rec)
+
+	err := renderFieldErrors(c,
+		map[string]string{"email": "Email is <required>", "unknown": "not shown"},
+		map[string]string{"name": "name-error", "email": "email-error"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 so HTMX processes the swaps", rec.Code)
+	}
+	if got := rec.Header().Get("HX-Reswap"); got != "none" {
+		t.Errorf("HX-Reswap = %q, want none", got)
+	}
+
+	body := rec.Body.String()
+	email := `<p id="email-error" hx-swap-oob="true" class="mt-1 text-sm text-red-400" role="alert">Email is &lt;required&gt;</p>`
+	name := `<p id="name-error" hx-swap-oob="true" class="mt-1 text-sm text-red-400" role="alert"></p>`
+	if body != email+name {
+		t.Errorf("body = %q, want the email error followed by the cleared name error", body)
+	}
+	if strings.Contains(body, "not shown") {
+		t.Error("error for a field without a target was rendered")
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestGreetHTMX(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantCode int
+		wantBody string
+	}{
+		{name: "name", input: "Ada", wantCode: http.StatusOK, wantBody: "Hello, Ada! 👋"},
+		{name: "surrounding whitespace is trimmed", input: "  Ada \t", wantCode: http.StatusOK, wantBody: "Hello, Ada! 👋"},
+		{name: "empty", input: "", wantCode: http.StatusOK, wantBody: "Hello, World! 👋"},
+		{name: "whitespace only", input: " \t\n ", wantCode: http.StatusOK, wantBody: "Hello, World! 👋"},
+		{name: "markup is escaped", input: "<script>", wantCode: http.StatusOK, wantBody: "Hello, &lt;script&gt;! 👋"},
+		{name: "too long", input: strings.Repeat("é", maxNameLength+1), wantCode: http.StatusUnprocessableEntity},
+		{name: "longest allowed", input: strings.Repeat("é", maxNameLength), wantCode: http.StatusOK, wantBody: "Hello, " + strings.Repeat("é", maxNameLength) + "! 👋"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"name": {tt.input}}
+			req := httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			req.Header.Set("HX-Request", "true")
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			err := (&Handlers{}).Greet(c)
+
+			if tt.wantCode != http.StatusOK {
+				he, ok := err.(*echo.HTTPError)
+				if !ok || he.Code != tt.wantCode {
+					t.Fatalf("Greet error = %v, want HTTP %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Greet: %v", err)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
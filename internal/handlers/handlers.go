@@ -16,13 +16,19 @@
 //
 // Usage:
 //
-//	h := handlers.New(db)
+//	h := handlers.New(db, cfg, monitor)
 //	e.GET("/", h.Home)
 //	e.GET("/health", h.Health)
+//	e.GET("/readyz", h.Readyz)
 package handlers
 
 import (
-	"github.com/uptrace/bun"
+	"replace-me/internal/config"
+	"replace-me/internal/database"
+	"replace-me/internal/health"
+	"replace-me/migrations"
+
+	"github.com/uptrace/bun/migrate"
 )
 
 // Handlers holds dependencies needed by HTTP handlers.
@@ -30,18 +36,58 @@ import (
 type Handlers struct {
 	// db is available for handlers that need database access.
 	// For complex applications, inject services instead of using db directly.
-	db *bun.DB
+	// Its selects go to the read replicas (DATABASE_REPLICA_URLS); writes,
+	// transactions and db.Primary() use the primary.
+	db *database.ReplicatedDB
+
+	// cfg holds application configuration for handlers whose behavior
+	// depends on it (e.g. streaming write timeouts).
+	cfg *config.Config
+
+	// checkers are the readiness checks run by Readyz.
+	checkers []health.Checker
+
+	// migrationStatus is the cached pending-migrations check among
+	// checkers, kept so InvalidateMigrationStatus can reach it.
+	migrationStatus *health.MigrationsChecker
+
+	// streams tracks open streaming responses so DrainStreams can end
+	// them at shutdown.
+	streams *streamTracker
 }
 
-// New creates a new Handlers instance with the given database connection.
+// New creates a new Handlers instance with the given database connections,
+// configuration, and database monitor. The monitor's cached ping result is
+// used as the readiness database check.
+//
+// Application code can contribute its own readiness checks (e.g. a service
+// that depends on an external API) by passing them as extra checkers. Readyz
+// runs them alongside the built-in database and migrations checks.
 //
 // Example:
 //
-//	db, _ := database.New(cfg.DatabaseURL, true)
-//	h := handlers.New(db)
+//	db, _ := database.NewWithReplicas(cfg.DatabaseURL, cfg.DatabaseReplicaURLs, database.QueryLogFull, database.PoolOptions{}, database.RetryOptions{})
+//	monitor := database.NewMonitor(db.Primary(), cfg.DBMonitorInterval)
+//	payments := services.NewUpstreamChecker("payments", "https://payments.internal/healthz")
+//	h := handlers.New(db, cfg, monitor, payments)
 //	e.GET("/", h.Home)
-func New(db *bun.DB) *Handlers {
+func New(db *database.ReplicatedDB, cfg *config.Config, monitor *database.Monitor, checkers ...health.Checker) *Handlers {
+	migrationStatus := health.NewMigrationsChecker(migrate.NewMigrator(db.Primary(), migrations.Migrations), cfg.MigrationCheckTTL)
 	return &Handlers{
-		db: db,
+		db:              db,
+		cfg:             cfg,
+		checkers:        append([]health.Checker{monitor, migrationStatus}, checkers...),
+		migrationStatus: migrationStatus,
+		streams:         newStreamTracker(),
 	}
 }
+
+// InvalidateMigrationStatus drops the cached migration status, so the next
+// readiness probe reports the schema as it is now rather than up to
+// MIGRATION_CHECK_TTL ago. Code that applies or rolls back migrations
+// in-process (an admin endpoint, migrating at startup) must call it
+// afterwards; the migrate command runs in its own process and is picked up
+// when the cache expires.
+func (h *Handlers) InvalidateMigrationStatus() {
+	h.migrationStatus.Invalidate()
+}
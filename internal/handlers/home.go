@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"replace-me/internal/database"
+	"replace-me/internal/health"
 	"replace-me/internal/middleware"
+	"replace-me/templates/components"
 	"replace-me/templates/pages"
 
 	"github.com/labstack/echo/v4"
@@ -15,39 +20,60 @@ import (
 // Home renders the home page.
 // This demonstrates the basic request flow: handler → template rendering.
 //
+// With HOME_REDIRECT_AUTHENTICATED set, logged-in users (see
+// middleware.IsAuthenticated) are redirected there, e.g. to their
+// dashboard, and only anonymous visitors see the landing page.
+//
 // Route: GET /
 func (h *Handlers) Home(c echo.Context) error {
+	if target := h.cfg.AuthenticatedHome; target != "" && middleware.IsAuthenticated(c) {
+		return c.Redirect(http.StatusFound, target)
+	}
+
 	// Get any flash messages to display
 	flashes := middleware.GetFlashes(c)
 
 	// Render the home page template
-	return pages.Home(flashes).Render(c.Request().Context(), c.Response().Writer)
+	return render(c, http.StatusOK, pages.Home(flashes))
 }
 
+// maxNameLength is the maximum number of characters accepted by Greet.
+const maxNameLength = 50
+
 // Greet handles the greeting form submission.
 // This demonstrates:
-//   - Form data parsing
+//   - Form data parsing and validation
 //   - Flash messages
-//   - HTMX partial responses
+//   - HTMX partial responses rendered (and escaped) by templ
 //
 // Route: POST /greet
 func (h *Handlers) Greet(c echo.Context) error {
-	name := c.FormValue("name")
+	isHTMX := c.Request().Header.Get("HX-Request") == "true"
+
+	// Whitespace-only input counts as no name at all
+	name := strings.TrimSpace(c.FormValue("name"))
 	if name == "" {
 		name = "World"
 	}
 
-	// For HTMX requests, return just the greeting HTML fragment
-	if c.Request().Header.Get("HX-Request") == "true" {
-		return c.HTML(http.StatusOK, fmt.Sprintf(
-			"Hello, %s! 👋",
-			name,
-		))
+	if utf8.RuneCountInString(name) > maxNameLength {
+		message := fmt.Sprintf("Name must be at most %d characters", maxNameLength)
+		if isHTMX {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, message)
+		}
+		middleware.AddFlash(c, middleware.FlashError, message)
+		return redirect(c, "/")
+	}
+
+	// For HTMX requests, return just the greeting HTML fragment.
+	// Rendering through templ escapes the name, so input like <script> is harmless.
+	if isHTMX {
+		return render(c, http.StatusOK, components.Greeting(name))
 	}
 
 	// For regular form submissions, use flash message and redirect
 	middleware.AddFlash(c, middleware.FlashSuccess, fmt.Sprintf("Hello, %s!", name))
-	return c.Redirect(http.StatusSeeOther, "/")
+	return redirect(c, "/")
 }
 
 // Health handles health check requests.
@@ -55,10 +81,17 @@ func (h *Handlers) Greet(c echo.Context) error {
 //
 // Route: GET /health
 //
-// Returns JSON:
+// The response is content-negotiated: browsers (Accept: text/html) get a
+// small status page, everything else gets JSON:
 //
 //	{"status": "healthy", "database": "connected", "timestamp": "..."}
 //
+// Unless HEALTH_DETAILED is on (the default in development), only the
+// status is returned, so the error message of a failing database check
+// isn't disclosed to whoever calls the endpoint:
+//
+//	{"status": "unhealthy"}
+//
 // Status codes:
 //   - 200: Server is healthy
 //   - 503: Server is unhealthy (database connection failed)
@@ -68,22 +101,63 @@ func (h *Handlers) Greet(c echo.Context) error {
 //   - Load balancer health checks
 //   - Monitoring systems
 func (h *Handlers) Health(c echo.Context) error {
-	ctx := c.Request().Context()
+	code, status := h.healthStatus(c.Request().Context())
+	return negotiate(c, code, status, pages.Health(status))
+}
 
+// healthStatus computes the health status shared by the JSON and HTML
+// representations of the health endpoint.
+func (h *Handlers) healthStatus(ctx context.Context) (int, map[string]string) {
 	// Check database connectivity
-	dbStatus := "connected"
-	if err := database.HealthCheck(ctx, h.db); err != nil {
-		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+	if err := database.HealthCheck(ctx, h.db.Primary()); err != nil {
+		if !h.cfg.HealthDetailed {
+			return http.StatusServiceUnavailable, map[string]string{"status": "unhealthy"}
+		}
+		return http.StatusServiceUnavailable, map[string]string{
 			"status":    "unhealthy",
 			"database":  "disconnected",
 			"error":     err.Error(),
 			"timestamp": time.Now().UTC().Format(time.RFC3339),
-		})
+		}
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
+	if !h.cfg.HealthDetailed {
+		return http.StatusOK, map[string]string{"status": "healthy"}
+	}
+	return http.StatusOK, map[string]string{
 		"status":    "healthy",
-		"database":  dbStatus,
+		"database":  "connected",
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
-	})
+	}
+}
+
+// Readyz handles readiness probe requests.
+// It runs every registered health.Checker (database connectivity, pending
+// migrations) and reports whether this instance should receive traffic.
+//
+// Route: GET /readyz
+//
+// Returns JSON:
+//
+//	{"status": "healthy", "checks": {"database": {"status": "healthy", "checked_at": "..."}, ...}}
+//
+// As with Health, the per-check results (and their error messages) are only
+// included when HEALTH_DETAILED is on; otherwise just the overall status is:
+//
+//	{"status": "unhealthy"}
+//
+// Status codes:
+//   - 200: All checks passed
+//   - 503: At least one check failed
+func (h *Handlers) Readyz(c echo.Context) error {
+	report := health.Run(c.Request().Context(), h.checkers...)
+
+	code := http.StatusOK
+	if !report.Healthy() {
+		code = http.StatusServiceUnavailable
+	}
+	if !h.cfg.HealthDetailed {
+		return c.JSON(code, map[string]string{"status": report.Status})
+	}
+	return c.JSON(code, report)
 }
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"replace-me/internal/config"
+	"replace-me/internal/middleware"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+)
+
+func TestHomeAuthenticatedRedirect(t *testing.T) {
+	tests := []struct {
+		name          string
+		target        string
+		authenticated bool
+		wantCode      int
+		wantLocation  string
+	}{
+		{name: "logged in", target: "/dashboard", authenticated: true, wantCode: http.StatusFound, wantLocation: "/dashboard"},
+		{name: "anonymous", target: "/dashboard", wantCode: http.StatusOK},
+		{name: "redirect disabled", authenticated: true, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			session := sessions.NewSession(sessions.NewCookieStore([]byte("test-secret")), middleware.SessionName)
+			if tt.authenticated {
+				session.Values[middleware.SessionUserIDKey] = int64(42)
+			}
+			c.Set("session", session)
+
+			h := &Handlers{cfg: &config.Config{AuthenticatedHome: tt.target}}
+			if err := h.Home(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get(echo.HeaderLocation); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"browser", "text/html,application/xhtml+xml,*/*;q=0.8", echo.MIMETextHTMLCharsetUTF8, "<p>ok</p>"},
+		{"json client", "application/json", echo.MIMEApplicationJSON, `{"status":"ok"}`},
+		{"no accept header", "", echo.MIMEApplicationJSON, `{"status":"ok"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			data := map[string]string{"status": "ok"}
+			if err := negotiate(c, http.StatusTeapot, data, templ.Raw("<p>ok</p>")); err != nil {
+				t.Fatalf("negotiate: %v", err)
+			}
+
+			if rec.Code != http.StatusTeapot {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusTeapot)
+			}
+			if got := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"replace-me/internal/database"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Pagination defaults and limits for list endpoints.
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// pagination reads the "page" and "per_page" query parameters.
+// Missing or invalid values fall back to page 1 and defaultPerPage;
+// per_page is capped at maxPerPage.
+//
+// Usage:
+//
+//	page, perPage := pagination(c)
+//	result, err := database.Paginate[models.Book](ctx, q, page, perPage)
+func pagination(c echo.Context) (page, perPage int) {
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err = strconv.Atoi(c.QueryParam("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+	return page, perPage
+}
+
+// setPaginationHeaders sets an RFC 5988 Link header (first, prev, next, last)
+// and an X-Total-Count header for a paginated response. Link URLs keep all
+// other query parameters of the current request (filters, sorting).
+//
+// Usage:
+//
+//	setPaginationHeaders(c, result.PageInfo)
+//	return c.JSON(http.StatusOK, result.Items)
+//
+// Example header for page 2 of 5:
+//
+//	Link: <https://example.com/api/books?page=1&per_page=20>; rel="first", <...page=1...>; rel="prev", ...
+func setPaginationHeaders(c echo.Context, info database.PageInfo) {
+	links := []string{pageLink(c, 1, info.PerPage, "first")}
+	if info.HasPrev() {
+		links = append(links, pageLink(c, info.Page-1, info.PerPage, "prev"))
+	}
+	if info.HasNext() {
+		links = append(links, pageLink(c, info.Page+1, info.PerPage, "next"))
+	}
+	links = append(links, pageLink(c, info.TotalPages(), info.PerPage, "last"))
+
+	header := c.Response().Header()
+	header.Set("Link", strings.Join(links, ", "))
+	header.Set("X-Total-Count", strconv.Itoa(info.Total))
+}
+
+// pageLink builds a single Link header entry pointing at the given page.
+func pageLink(c echo.Context, page, perPage int, rel string) string {
+	u := *c.Request().URL
+	u.Scheme = c.Scheme()
+	u.Host = c.Request().Host
+
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(perPage))
+	u.RawQuery = query.Encode()
+
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel)
+}
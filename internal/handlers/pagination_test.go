@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"replace-me/internal/database"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantPage    int
+		wantPerPage int
+	}{
+		{"", 1, defaultPerPage},
+		{"?page=3&per_page=50", 3, 50},
+		{"?page=0&per_page=-5", 1, defaultPerPage},
+		{"?page=abc&per_page=xyz", 1, defaultPerPage},
+		{"?per_page=1000", 1, maxPerPage},
+	}
+
+	for _, tt := range tests {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/books"+tt.query, nil), httptest.NewRecorder())
+		page, perPage := pagination(c)
+		if page != tt.wantPage || perPage != tt.wantPerPage {
+			t.Errorf("pagination(%q) = %d, %d, want %d, %d", tt.query, page, perPage, tt.wantPage, tt.wantPerPage)
+		}
+	}
+}
+
+func TestSetPaginationHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     database.PageInfo
+		wantLink string
+	}{
+		{
+			name: "middle page",
+			info: database.PageInfo{Page: 2, PerPage: 10, Total: 45},
+			wantLink: `<http://example.com/books?page=1&per_page=10&sort=title>; rel="first", ` +
+				`<http://example.com/books?page=1&per_page=10&sort=title>; rel="prev", ` +
+				`<http://example.com/books?page=3&per_page=10&sort=title>; rel="next", ` +
+				`<http://example.com/books?page=5&per_page=10&sort=title>; rel="last"`,
+		},
+		{
+			name: "only page",
+			info: database.PageInfo{Page: 1, PerPage: 10, Total: 0},
+			wantLink: `<http://example.com/books?page=1&per_page=10&sort=title>; rel="first", ` +
+				`<http://example.com/books?page=1&per_page=10&sort=title>; rel="last"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "http://example.com/books?sort=title&page=9", nil), rec)
+
+			setPaginationHeaders(c, tt.info)
+
+			if got := rec.Header().Get("Link"); got != tt.wantLink {
+				t.Errorf("Link =\n%s\nwant\n%s", got, tt.wantLink)
+			}
+			if got, want := rec.Header().Get("X-Total-Count"), "45"; tt.info.Total == 45 && got != want {
+				t.Errorf("X-Total-Count = %q, want %q", got, want)
+			}
+		})
+	}
+}
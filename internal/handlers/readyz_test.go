@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"replace-me/internal/config"
+	"replace-me/internal/health"
+
+	"github.com/labstack/echo/v4"
+)
+
+// stubChecker is a health.Checker returning a fixed error.
+type stubChecker struct {
+	name string
+	err  error
+}
+
+func (s stubChecker) Name() string                    { return s.name }
+func (s stubChecker) Check(ctx context.Context) error { return s.err }
+
+func TestReadyz(t *testing.T) {
+	checkers := []health.Checker{
+		stubChecker{name: "database"},
+		stubChecker{name: "payments", err: errors.New("dial tcp 10.0.0.7:443: connection refused")},
+	}
+
+	tests := []struct {
+		name     string
+		detailed bool
+		wantBody string
+		// notInBody must not appear in the response
+		notInBody string
+	}{
+		{name: "minimal", wantBody: `{"status":"unhealthy"}`, notInBody: "10.0.0.7"},
+		{name: "detailed", detailed: true, wantBody: `"error":"dial tcp 10.0.0.7:443: connection refused"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/readyz", nil), rec)
+
+			h := &Handlers{cfg: &config.Config{HealthDetailed: tt.detailed}, checkers: checkers}
+			if err := h.Readyz(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", body, tt.wantBody)
+			}
+			if tt.notInBody != "" && strings.Contains(body, tt.notInBody) {
+				t.Errorf("body = %s, must not contain %q", body, tt.notInBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRedirect(t *testing.T) {
+	tests := []struct {
+		name         string
+		htmx         bool
+		wantStatus   int
+		wantLocation string
+		wantRedirect string
+	}{
+		{name: "full page", wantStatus: http.StatusSeeOther, wantLocation: "/books"},
+		{name: "HTMX", htmx: true, wantStatus: http.StatusOK, wantRedirect: "/books"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/books", nil)
+			if tt.htmx {
+				req.Header.Set("HX-Request", "true")
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			if err := redirect(c, "/books"); err != nil {
+				t.Fatalf("redirect: %v", err)
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get(echo.HeaderLocation); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			if got := rec.Header().Get("HX-Redirect"); got != tt.wantRedirect {
+				t.Errorf("HX-Redirect = %q, want %q", got, tt.wantRedirect)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+
+	"replace-me/internal/logger"
+	"replace-me/internal/middleware"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+// render writes a templ component as an HTML response with the given status code.
+//
+// The component is rendered into a buffer before anything is sent. If it
+// fails or panics halfway (say, a nil dereference on unexpected data), no
+// bytes have been committed yet, so the client gets a clean 500 error page
+// instead of a truncated page with a 200 status. Responses that must stream
+// should use the streaming helpers instead.
+//
+// Usage:
+//
+//	return render(c, http.StatusOK, pages.Home(flashes))
+func render(c echo.Context, code int, component templ.Component) error {
+	var buf bytes.Buffer
+	if err := renderComponent(c.Request().Context(), &buf, component); err != nil {
+		return err
+	}
+
+	// Written through c.Response() so its Size is set for the access log
+	return c.HTMLBlob(code, buf.Bytes())
+}
+
+// renderStackSize is the maximum number of bytes of stack trace logged when
+// a template panics.
+const renderStackSize = 4 << 10 // 4 KB
+
+// renderComponent renders component to w, turning a panic during rendering
+// into an error. The panic is logged with its stack trace, which the error
+// returned to the error handler doesn't carry.
+func renderComponent(ctx context.Context, w io.Writer, component templ.Component) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if r == http.ErrAbortHandler {
+			panic(r)
+		}
+
+		stack := make([]byte, renderStackSize)
+		stack = stack[:runtime.Stack(stack, false)]
+
+		err = fmt.Errorf("template panic: %v", r)
+		logger.Error("template panic recovered", "error", err.Error(), "stack", string(stack))
+	}()
+	return component.Render(ctx, w)
+}
+
+// negotiate responds with data as JSON or with component as HTML, depending on
+// what the client asked for. This lets a single handler serve both API clients
+// and browsers from the same underlying computation.
+//
+// Browsers rank "text/html" first in their Accept header and get the HTML
+// page. API routes (see middleware.APIOnly) always get JSON. Everything else
+// (curl, load balancers, Kubernetes probes, fetch() calls asking for
+// application/json) gets JSON.
+//
+// Usage:
+//
+//	status := computeStatus()
+//	return negotiate(c, http.StatusOK, status, pages.Status(status))
+func negotiate(c echo.Context, code int, data any, component templ.Component) error {
+	if middleware.IsAPI(c) || !prefersHTML(c.Request()) {
+		return c.JSON(code, data)
+	}
+	return render(c, code, component)
+}
+
+// prefersHTML reports whether the request's Accept header ranks HTML above
+// JSON, taking q-values into account. Without a preference (no Accept
+// header, or */*) JSON wins.
+func prefersHTML(r *http.Request) bool {
+	return middleware.PreferredMediaType(r, echo.MIMEApplicationJSON, echo.MIMETextHTML) == echo.MIMETextHTML
+}
+
+// redirect sends the client to url after a form submission.
+//
+// A plain 303 redirect doesn't work for HTMX requests: HTMX follows it
+// transparently and swaps the target page's body into the triggering element.
+// For HTMX requests this sets the HX-Redirect header instead, which makes
+// HTMX perform a full client-side navigation.
+//
+// Usage:
+//
+//	middleware.AddFlash(c, middleware.FlashSuccess, "Saved!")
+//	return redirect(c, "/books")
+func redirect(c echo.Context, url string) error {
+	if c.Request().Header.Get("HX-Request") == "true" {
+		c.Response().Header().Set("HX-Redirect", url)
+		return c.NoContent(http.StatusOK)
+	}
+	return c.Redirect(http.StatusSeeOther, url)
+}
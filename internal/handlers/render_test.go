@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRender(t *testing.T) {
+	errTemplate := errors.New("template failed")
+	text := func(s string) templ.Component {
+		return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+			_, err := io.WriteString(w, s)
+			return err
+		})
+	}
+
+	tests := []struct {
+		name string
+		// render renders into c; the cached cases use a key of their own
+		render     func(c echo.Context) error
+		wantStatus int
+		wantBody   string
+		wantErr    string
+	}{
+		{
+			name:       "render",
+			render:     func(c echo.Context) error { return render(c, http.StatusCreated, text("<p>hi</p>")) },
+			wantStatus: http.StatusCreated,
+			wantBody:   "<p>hi</p>",
+		},
+		{
+			name: "render error leaves the response untouched",
+			render: func(c echo.Context) error {
+				return render(c, http.StatusOK, templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+					io.WriteString(w, "<p>half")
+					return errTemplate
+				}))
+			},
+			wantErr: errTemplate.Error(),
+		},
+		{
+			name: "render panic becomes an error",
+			render: func(c echo.Context) error {
+				return render(c, http.StatusOK, templ.ComponentFunc(func(context.Context, io.Writer) error {
+					panic("nil map")
+				}))
+			},
+			wantErr: "template panic: nil map",
+		},
+		{
+			name:       "cached",
+			render:     func(c echo.Context) error { return RenderCached(c, "test-cached", time.Minute, text("<nav></nav>")) },
+			wantStatus: http.StatusOK,
+			wantBody:   "<nav></nav>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+			err := tt.render(c)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				if c.Response().Committed {
+					t.Error("response committed on error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			if got := rec.Header().Get(echo.HeaderContentType); got != echo.MIMETextHTMLCharsetUTF8 {
+				t.Errorf("Content-Type = %q", got)
+			}
+			// The access log reads the size from the response
+			if got := c.Response().Size; got != int64(len(tt.wantBody)) {
+				t.Errorf("Response().Size = %d, want %d", got, len(tt.wantBody))
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+// renderCache holds rendered fragments by key (see RenderCached). Entries
+// are replaced when they expire rather than swept.
+var renderCache = struct {
+	sync.Mutex
+	entries map[string]renderCacheEntry
+}{entries: make(map[string]renderCacheEntry)}
+
+// renderCacheEntry is a rendered fragment and when it stops being served.
+type renderCacheEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+// RenderCached is render for fragments that are identical for every request,
+// such as navigation or a footer loaded by HTMX. The component is rendered
+// once and the bytes are served for ttl under key.
+//
+// The key alone identifies the fragment: it is shared by every request,
+// user and handler in the process, and the component and its arguments are
+// not part of it. Use one key per fragment, from a small fixed set (e.g.
+// "nav", "footer"), never from user input, since entries are only replaced
+// and never removed. A fragment with variants needs a key per variant,
+// e.g. "nav:" + locale.
+//
+// Only cache components whose output doesn't depend on the request: no
+// user data, flashes, CSRF tokens or CSP nonces. Whatever the first request
+// rendered is what every request gets until the entry expires or
+// InvalidateRenderCache drops it.
+//
+// Usage:
+//
+//	func (h *Handlers) Nav(c echo.Context) error {
+//	    return RenderCached(c, "nav", time.Minute, partials.Nav(h.categories()))
+//	}
+func RenderCached(c echo.Context, key string, ttl time.Duration, component templ.Component) error {
+	body, ok := cachedFragment(key)
+	if !ok {
+		var buf bytes.Buffer
+		if err := renderComponent(c.Request().Context(), &buf, component); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+
+		renderCache.Lock()
+		renderCache.entries[key] = renderCacheEntry{body: body, expires: time.Now().Add(ttl)}
+		renderCache.Unlock()
+	}
+
+	return c.HTMLBlob(http.StatusOK, body)
+}
+
+// cachedFragment returns the cached bytes for key, if they haven't expired.
+func cachedFragment(key string) ([]byte, bool) {
+	renderCache.Lock()
+	defer renderCache.Unlock()
+
+	entry, ok := renderCache.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.body, true
+}
+
+// InvalidateRenderCache drops the cached fragment for key, so the next
+// request renders it again. Call it after changing the data it shows.
+//
+// Usage:
+//
+//	if err := h.categories.Create(ctx, name); err != nil {
+//	    return err
+//	}
+//	InvalidateRenderCache("nav")
+func InvalidateRenderCache(key string) {
+	renderCache.Lock()
+	defer renderCache.Unlock()
+	delete(renderCache.entries, key)
+}
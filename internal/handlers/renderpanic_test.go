@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRenderBuffersOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		component templ.Component
+		wantErr   string
+		wantBody  string
+	}{
+		{
+			name: "complete page",
+			component: templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+				_, err := io.WriteString(w, "<h1>Books</h1>")
+				return err
+			}),
+			wantBody: "<h1>Books</h1>",
+		},
+		{
+			name: "panic halfway",
+			component: templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+				io.WriteString(w, "<h1>Books</h1>")
+				var book *struct{ Title string }
+				_, err := io.WriteString(w, book.Title)
+				return err
+			}),
+			wantErr: "template panic",
+		},
+		{
+			name: "error halfway",
+			component: templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+				io.WriteString(w, "<h1>Books</h1>")
+				return io.ErrUnexpectedEOF
+			}),
+			wantErr: io.ErrUnexpectedEOF.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+			err := render(c, http.StatusOK, tt.component)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("render() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				// Nothing may be committed, so the error handler can still
+				// send a clean 500.
+				if c.Response().Committed || rec.Body.Len() > 0 {
+					t.Errorf("response committed with %q", rec.Body.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusOK || rec.Body.String() != tt.wantBody {
+				t.Errorf("got %d %q, want 200 %q", rec.Code, rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRenderRepanicsAbortHandler(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", r)
+		}
+	}()
+
+	render(c, http.StatusOK, templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		panic(http.ErrAbortHandler)
+	}))
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// precompressedEncodings lists the precompressed siblings Static looks for,
+// in order of preference.
+var precompressedEncodings = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Static serves the files under root, preferring precompressed variants.
+//
+// When the client accepts br or gzip and a sibling file with a ".br" or ".gz"
+// extension exists (e.g. css/output.css.gz next to css/output.css), that file
+// is sent with the matching Content-Encoding and the original file's
+// Content-Type. Otherwise the plain file is served exactly as e.Static would.
+// Responses always carry "Vary: Accept-Encoding" so caches keep the variants
+// apart.
+//
+// Precompressed responses are marked with Content-Encoding, so the production
+// Gzip middleware leaves them alone instead of compressing them twice.
+//
+// Usage:
+//
+//	e.GET("/static/*", handlers.Static("static"))
+func Static(root string) echo.HandlerFunc {
+	fsys := os.DirFS(root)
+	plain := echo.StaticDirectoryHandler(fsys, false)
+
+	return func(c echo.Context) error {
+		addVary(c.Response().Header(), echo.HeaderAcceptEncoding)
+
+		p, err := url.PathUnescape(c.Param("*"))
+		if err != nil {
+			return plain(c)
+		}
+		name := strings.TrimPrefix(path.Clean("/"+p), "/")
+
+		// Content-Type must come from the original name; sniffing the
+		// compressed bytes would be wrong. Unknown types are served plain.
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if name == "" || contentType == "" {
+			return plain(c)
+		}
+
+		accept := c.Request().Header.Get(echo.HeaderAcceptEncoding)
+		for _, pc := range precompressedEncodings {
+			if !acceptsEncoding(accept, pc.encoding) {
+				continue
+			}
+			if served := servePrecompressed(c, fsys, name, contentType, pc.encoding, pc.extension); served {
+				return nil
+			}
+		}
+
+		return plain(c)
+	}
+}
+
+// servePrecompressed serves name+extension with the given encoding and
+// content type, and reports false without writing anything if that file
+// doesn't exist or can't be served.
+func servePrecompressed(c echo.Context, fsys fs.FS, name, contentType, encoding, extension string) bool {
+	file, err := fsys.Open(name + extension)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	content, seekable := file.(io.ReadSeeker)
+	if err != nil || info.IsDir() || !seekable {
+		return false
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentEncoding, encoding)
+	http.ServeContent(c.Response(), c.Request(), name, info.ModTime(), content)
+	return true
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header value allows
+// encoding. Entries with q=0 are treated as refusals.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		token, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(token), encoding) {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// addVary adds value to the Vary header unless it is already listed.
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values(echo.HeaderVary) {
+		for _, item := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), value) {
+				return
+			}
+		}
+	}
+	header.Add(echo.HeaderVary, value)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestStatic(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"css/app.css":    "plain css",
+		"css/app.css.gz": "gzip css",
+		"css/app.css.br": "brotli css",
+		"js/app.js":      "plain js",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := echo.New()
+	e.GET("/static/*", Static(root))
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantBody       string
+		wantEncoding   string
+	}{
+		{name: "brotli preferred", path: "/static/css/app.css", acceptEncoding: "gzip, deflate, br", wantBody: "brotli css", wantEncoding: "br"},
+		{name: "gzip", path: "/static/css/app.css", acceptEncoding: "gzip", wantBody: "gzip css", wantEncoding: "gzip"},
+		{name: "brotli refused", path: "/static/css/app.css", acceptEncoding: "br;q=0, gzip", wantBody: "gzip css", wantEncoding: "gzip"},
+		{name: "no encoding accepted", path: "/static/css/app.css", wantBody: "plain css"},
+		{name: "no precompressed file", path: "/static/js/app.js", acceptEncoding: "gzip, br", wantBody: "plain js"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set(echo.HeaderAcceptEncoding, tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK || rec.Body.String() != tt.wantBody {
+				t.Errorf("got %d %q, want 200 %q", rec.Code, rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get(echo.HeaderContentEncoding); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if ext := filepath.Ext(tt.path); !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), map[string]string{".css": "text/css", ".js": "text/javascript"}[ext]) {
+				t.Errorf("Content-Type = %q for %s", rec.Header().Get(echo.HeaderContentType), tt.path)
+			}
+			if got := rec.Header().Get(echo.HeaderVary); got != echo.HeaderAcceptEncoding {
+				t.Errorf("Vary = %q, want %q", got, echo.HeaderAcceptEncoding)
+			}
+		})
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		header   string
+		encoding string
+		want     bool
+	}{
+		{"gzip, br", "br", true},
+		{"GZIP", "gzip", true},
+		{"gzip;q=0.5", "gzip", true},
+		{"gzip; q=0", "gzip", false},
+		{"br;q=0.000", "br", false},
+		{"deflate", "gzip", false},
+		{"", "gzip", false},
+	}
+
+	for _, tt := range tests {
+		if got := acceptsEncoding(tt.header, tt.encoding); got != tt.want {
+			t.Errorf("acceptsEncoding(%q, %q) = %v, want %v", tt.header, tt.encoding, got, tt.want)
+		}
+	}
+}
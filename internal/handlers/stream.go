@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// stream writes a streaming response (Server-Sent Events, chunked exports)
+// with a per-write deadline.
+//
+// Without a deadline, a client that stops reading (closed laptop lid, dead
+// connection) makes writes block forever, pinning the handler goroutine and
+// any database connection it holds. With a deadline the blocked write fails,
+// the handler returns, and its resources are released.
+//
+// Streaming routes must skip the global Timeout middleware, which buffers
+// the whole response and can't flush: declare them with
+// middleware.SetStreamingRoutes.
+//
+// Long-lived streams must also end when the server shuts down, or shutdown
+// waits for them until it times out: loops select on Done and return, and
+// Close tells SSE clients to reconnect (to another instance, or to this one
+// once it's back).
+type stream struct {
+	w       *echo.Response
+	rc      *http.ResponseController
+	timeout time.Duration
+	streams *streamTracker
+	sse     bool // an Event was written
+	closed  bool
+}
+
+// newStream prepares c's response for streaming with the configured
+// STREAM_WRITE_TIMEOUT. Set any headers (Content-Type etc.) before writing,
+// and Close the stream when the handler is done with it.
+//
+// Usage:
+//
+//	s := h.newStream(c)
+//	defer s.Close()
+//	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+//	for {
+//	    select {
+//	    case msg := <-updates:
+//	        if err := s.Event("update", msg); err != nil {
+//	            return err // client is gone or too slow
+//	        }
+//	    case <-s.Done():
+//	        return nil // server is shutting down
+//	    case <-c.Request().Context().Done():
+//	        return nil
+//	    }
+//	}
+func (h *Handlers) newStream(c echo.Context) *stream {
+	h.streams.add()
+	return &stream{
+		w:       c.Response(),
+		rc:      http.NewResponseController(c.Response()),
+		timeout: h.cfg.StreamWriteTimeout,
+		streams: h.streams,
+	}
+}
+
+// Done returns a channel that is closed when the server starts shutting
+// down. Long-lived streams should return once it is closed; short ones
+// (a CSV export) can ignore it and finish within the shutdown budget.
+func (s *stream) Done() <-chan struct{} {
+	return s.streams.shutdown
+}
+
+// Close ends the stream. During shutdown, SSE clients are first sent a
+// "reconnect" event so they know the close is deliberate. Close is safe to
+// call more than once.
+func (s *stream) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	defer s.streams.done()
+
+	select {
+	case <-s.streams.shutdown:
+		if s.sse {
+			return s.Event("reconnect", "server shutting down")
+		}
+	default:
+	}
+	return nil
+}
+
+// Write writes p to the client, failing if the write blocks past the deadline.
+func (s *stream) Write(p []byte) (int, error) {
+	if s.timeout > 0 {
+		err := s.rc.SetWriteDeadline(time.Now().Add(s.timeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return 0, err
+		}
+	}
+	return s.w.Write(p)
+}
+
+// Flush sends any buffered data to the client immediately.
+func (s *stream) Flush() error {
+	return s.rc.Flush()
+}
+
+// Event writes a single Server-Sent Event and flushes it.
+// Multi-line data is split into multiple "data:" lines as the SSE format requires.
+func (s *stream) Event(event, data string) error {
+	var b strings.Builder
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	s.sse = true
+	if _, err := s.Write([]byte(b.String())); err != nil {
+		return err
+	}
+	return s.Flush()
+}
+
+// streamTracker counts open streams and signals them to end at shutdown.
+type streamTracker struct {
+	mu       sync.Mutex
+	active   int
+	drained  chan struct{} // closed when active drops to 0 during a drain
+	shutdown chan struct{}
+	once     sync.Once
+}
+
+func newStreamTracker() *streamTracker {
+	return &streamTracker{shutdown: make(chan struct{})}
+}
+
+func (t *streamTracker) add() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active++
+}
+
+func (t *streamTracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active--
+	if t.active == 0 && t.drained != nil {
+		close(t.drained)
+		t.drained = nil
+	}
+}
+
+// DrainStreams tells open streams that the server is shutting down (see
+// stream.Done) and waits until they have closed or ctx ends, in which case
+// it returns ctx's error. Call it before e.Shutdown, which otherwise waits
+// on long-lived SSE connections until its own deadline.
+func (h *Handlers) DrainStreams(ctx context.Context) error {
+	t := h.streams
+	t.once.Do(func() { close(t.shutdown) })
+
+	t.mu.Lock()
+	if t.active == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	drained := make(chan struct{})
+	t.drained = drained
+	t.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return fmt.Errorf("%d stream(s) still open: %w", t.active, ctx.Err())
+	}
+}
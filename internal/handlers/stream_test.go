@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestDrainStreams(t *testing.T) {
+	h := &Handlers{cfg: &config.Config{}, streams: newStreamTracker()}
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/events", nil), rec)
+
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		s := h.newStream(c)
+		defer s.Close()
+		if err := s.Event("update", "1"); err != nil {
+			t.Error(err)
+		}
+		close(started)
+		<-s.Done()
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.DrainStreams(ctx); err != nil {
+		t.Fatalf("DrainStreams() = %v", err)
+	}
+	<-finished
+
+	if body := rec.Body.String(); !strings.HasSuffix(body, "event: reconnect\ndata: server shutting down\n\n") {
+		t.Errorf("body = %q, want it to end with a reconnect event", body)
+	}
+}
+
+func TestDrainStreamsTimeout(t *testing.T) {
+	h := &Handlers{cfg: &config.Config{}, streams: newStreamTracker()}
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/events", nil), httptest.NewRecorder())
+
+	// A stream that ignores Done
+	s := h.newStream(c)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := h.DrainStreams(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DrainStreams() = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestDrainStreamsNoneOpen(t *testing.T) {
+	h := &Handlers{cfg: &config.Config{}, streams: newStreamTracker()}
+	if err := h.DrainStreams(context.Background()); err != nil {
+		t.Errorf("DrainStreams() = %v, want nil", err)
+	}
+}
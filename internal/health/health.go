@@ -0,0 +1,195 @@
+// Package health provides readiness checks for the application.
+//
+// A Checker verifies one dependency (database, migrations, an external API).
+// Run executes a set of checkers and aggregates their results into a Report,
+// which the /readyz endpoint returns to load balancers and Kubernetes probes.
+//
+// Architecture:
+//
+//	GET /readyz → handlers.Readyz → health.Run(checkers...) → Report (JSON)
+//
+// Writing a checker:
+//
+//	type PaymentsChecker struct{ client *payments.Client }
+//
+//	func (c *PaymentsChecker) Name() string { return "payments" }
+//
+//	func (c *PaymentsChecker) Check(ctx context.Context) error {
+//	    return c.client.Ping(ctx)
+//	}
+//
+//	// Optional: the default is DefaultCheckTimeout
+//	func (c *PaymentsChecker) Timeout() time.Duration { return 10 * time.Second }
+//
+// Registering it (cmd/server/main.go):
+//
+//	h := handlers.New(db, cfg, dbMonitor, &PaymentsChecker{client: payments})
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// DefaultCheckTimeout is how long Run waits for a checker that doesn't set
+// its own timeout (see timeoutSetter).
+const DefaultCheckTimeout = 5 * time.Second
+
+// Status values used in results and reports.
+const (
+	StatusHealthy   = "healthy"
+	StatusUnhealthy = "unhealthy"
+)
+
+// Checker is implemented by anything that can report on its own health.
+type Checker interface {
+	// Name identifies the check in the report (e.g. "database").
+	Name() string
+
+	// Check returns nil if the dependency is healthy.
+	Check(ctx context.Context) error
+}
+
+// Result is the outcome of a single Checker.
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report aggregates the results of all checkers.
+// Status is healthy only if every check is healthy.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Healthy reports whether every check passed.
+func (r Report) Healthy() bool {
+	return r.Status == StatusHealthy
+}
+
+// checkedAtReporter is implemented by checkers that cache their result,
+// so the report shows when the dependency was actually checked.
+type checkedAtReporter interface {
+	CheckedAt() time.Time
+}
+
+// timeoutSetter is implemented by checkers that need a different timeout
+// than DefaultCheckTimeout, e.g. a slow external API.
+type timeoutSetter interface {
+	Timeout() time.Duration
+}
+
+// Run executes all checkers concurrently and aggregates their results.
+//
+// Each checker gets its own timeout. A checker still running when it
+// expires is reported unhealthy ("timed out") and abandoned, even if it
+// ignores its context, so Run always returns within the longest timeout.
+// A checker that panics is reported unhealthy too.
+func Run(ctx context.Context, checkers ...Checker) Report {
+	report := Report{
+		Status: StatusHealthy,
+		Checks: make(map[string]Result, len(checkers)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := Result{Status: StatusHealthy}
+			if err := runCheck(ctx, checker); err != nil {
+				result.Status = StatusUnhealthy
+				result.Error = err.Error()
+			}
+			result.CheckedAt = time.Now().UTC()
+			if r, ok := checker.(checkedAtReporter); ok {
+				result.CheckedAt = r.CheckedAt().UTC()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[checker.Name()] = result
+			if result.Status != StatusHealthy {
+				report.Status = StatusUnhealthy
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// runCheck runs checker with its timeout. The check runs in its own
+// goroutine so a checker that ignores ctx can't hold up the report; that
+// goroutine finishes in the background whenever the checker returns.
+func runCheck(ctx context.Context, checker Checker) error {
+	timeout := DefaultCheckTimeout
+	if t, ok := checker.(timeoutSetter); ok {
+		timeout = t.Timeout()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- checker.Check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		return ctx.Err()
+	}
+}
+
+// DatabaseChecker checks database connectivity with a ping.
+type DatabaseChecker struct {
+	db   *bun.DB
+	name string
+}
+
+// NewDatabaseChecker creates a checker that pings db.
+func NewDatabaseChecker(db *bun.DB) *DatabaseChecker {
+	return &DatabaseChecker{db: db, name: "database"}
+}
+
+// NewReplicaCheckers creates a checker per read replica (see
+// database.NewWithReplicas), named database_replica_1, database_replica_2
+// and so on, so the report shows which replica is down.
+//
+// Usage:
+//
+//	h := handlers.New(db, cfg, dbMonitor, health.NewReplicaCheckers(db.Replicas())...)
+func NewReplicaCheckers(replicas []*bun.DB) []Checker {
+	checkers := make([]Checker, len(replicas))
+	for i, replica := range replicas {
+		checkers[i] = &DatabaseChecker{db: replica, name: fmt.Sprintf("database_replica_%d", i+1)}
+	}
+	return checkers
+}
+
+// Name implements Checker.
+func (c *DatabaseChecker) Name() string { return c.name }
+
+// Check implements Checker.
+func (c *DatabaseChecker) Check(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
@@ -0,0 +1,74 @@
+package health
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testChecker is a Checker built from a function, with its own timeout.
+type testChecker struct {
+	name    string
+	timeout time.Duration
+	check   func(ctx context.Context) error
+}
+
+func (c testChecker) Name() string                    { return c.name }
+func (c testChecker) Check(ctx context.Context) error { return c.check(ctx) }
+func (c testChecker) Timeout() time.Duration          { return c.timeout }
+
+func TestRunIsolatesFailingCheckers(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+
+	release := make(chan struct{})
+	defer close(release)
+
+	checkers := []Checker{
+		// Ignores its context and hangs well past its timeout
+		testChecker{name: "hung", timeout: timeout, check: func(ctx context.Context) error {
+			<-release
+			return nil
+		}},
+		testChecker{name: "panics", timeout: timeout, check: func(ctx context.Context) error {
+			panic("nil map")
+		}},
+		testChecker{name: "ok", timeout: timeout, check: func(ctx context.Context) error {
+			return nil
+		}},
+	}
+
+	start := time.Now()
+	report := Run(context.Background(), checkers...)
+	elapsed := time.Since(start)
+
+	if elapsed > timeout+500*time.Millisecond {
+		t.Errorf("Run took %v, want it to return within the %v timeout", elapsed, timeout)
+	}
+	if report.Healthy() {
+		t.Error("report is healthy, want unhealthy")
+	}
+
+	tests := []struct {
+		name       string
+		wantStatus string
+		wantError  string
+	}{
+		{name: "hung", wantStatus: StatusUnhealthy, wantError: "timed out after 50ms"},
+		{name: "panics", wantStatus: StatusUnhealthy, wantError: "check panicked: nil map"},
+		{name: "ok", wantStatus: StatusHealthy},
+	}
+	for _, tt := range tests {
+		result, ok := report.Checks[tt.name]
+		if !ok {
+			t.Errorf("no result for %q", tt.name)
+			continue
+		}
+		if result.Status != tt.wantStatus {
+			t.Errorf("%s: status = %q, want %q", tt.name, result.Status, tt.wantStatus)
+		}
+		if !strings.Contains(result.Error, tt.wantError) || (tt.wantError == "" && result.Error != "") {
+			t.Errorf("%s: error = %q, want %q", tt.name, result.Error, tt.wantError)
+		}
+	}
+}
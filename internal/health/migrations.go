@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"replace-me/internal/logger"
+	"replace-me/migrations"
+
+	"github.com/uptrace/bun/migrate"
+	"golang.org/x/sync/singleflight"
+)
+
+// MigrationsChecker reports unhealthy while there are unapplied migrations,
+// so new instances don't receive traffic before the schema is up to date.
+//
+// Readiness probes run every few seconds on every instance, so the result is
+// cached for ttl. Once the cache is stale, the cached result is still returned
+// and a single background refresh is started; only the very first check
+// queries the database synchronously. Concurrent checks share one query.
+type MigrationsChecker struct {
+	migrator *migrate.Migrator
+	ttl      time.Duration
+	// query reports pending migrations; it is c.pending outside tests
+	query func(ctx context.Context) error
+
+	// group makes concurrent refreshes share one query. Its key is the
+	// generation, so a check after Invalidate never joins a query that
+	// started before it.
+	group singleflight.Group
+
+	mu         sync.Mutex
+	err        error
+	checkedAt  time.Time
+	refreshing bool
+	generation uint64 // incremented by Invalidate
+}
+
+// migrationsCheckTimeout bounds a migration status query. Refreshes are
+// detached from the probe that started them, which ends with its request.
+const migrationsCheckTimeout = 10 * time.Second
+
+// NewMigrationsChecker creates a checker for migrator that caches its
+// result for ttl.
+func NewMigrationsChecker(migrator *migrate.Migrator, ttl time.Duration) *MigrationsChecker {
+	c := &MigrationsChecker{migrator: migrator, ttl: ttl}
+	c.query = c.pending
+	return c
+}
+
+// Name implements Checker.
+func (c *MigrationsChecker) Name() string { return "migrations" }
+
+// Check implements Checker.
+func (c *MigrationsChecker) Check(ctx context.Context) error {
+	c.mu.Lock()
+	generation := c.generation
+	if c.checkedAt.IsZero() {
+		// Nothing cached yet: wait for the query, shared with any other
+		// check doing the same
+		c.mu.Unlock()
+		return c.refresh(ctx, generation)
+	}
+
+	err := c.err
+	if time.Since(c.checkedAt) >= c.ttl && !c.refreshing {
+		c.refreshing = true
+		go func() {
+			if err := c.refresh(context.Background(), generation); err != nil {
+				logger.Warn("migration status check failed", "error", err.Error())
+			}
+		}()
+	}
+	c.mu.Unlock()
+
+	return err
+}
+
+// CheckedAt returns when the migration status was last queried.
+func (c *MigrationsChecker) CheckedAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.checkedAt
+}
+
+// Invalidate drops the cached result so the next Check queries the database.
+// Call it after running migrations in-process. A query already in flight
+// doesn't overwrite the invalidated result.
+func (c *MigrationsChecker) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkedAt = time.Time{}
+	c.err = nil
+	c.refreshing = false
+	c.generation++
+}
+
+// refresh queries the migration status and updates the cache, unless it was
+// invalidated since generation. Concurrent refreshes of one generation share
+// a single query, which is detached from ctx's cancellation.
+func (c *MigrationsChecker) refresh(ctx context.Context, generation uint64) error {
+	result := c.group.DoChan(strconv.FormatUint(generation, 10), func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), migrationsCheckTimeout)
+		defer cancel()
+		err := c.query(ctx)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.generation == generation {
+			c.err = err
+			c.checkedAt = time.Now()
+			c.refreshing = false
+		}
+		return nil, err
+	})
+
+	select {
+	case res := <-result:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pending returns an error if any migrations are unapplied.
+func (c *MigrationsChecker) pending(ctx context.Context) error {
+	statuses, err := migrations.Status(ctx, c.migrator)
+	if err != nil {
+		return fmt.Errorf("reading migration status: %w", err)
+	}
+	if pending := migrations.Pending(statuses); pending > 0 {
+		return fmt.Errorf("%d pending migration(s)", pending)
+	}
+	return nil
+}
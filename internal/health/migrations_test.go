@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestMigrationsChecker returns a checker whose query counts its calls
+// and blocks until release is closed.
+func newTestMigrationsChecker(ttl time.Duration, release <-chan struct{}, result error) (*MigrationsChecker, *atomic.Int32) {
+	var queries atomic.Int32
+	c := &MigrationsChecker{ttl: ttl}
+	c.query = func(context.Context) error {
+		queries.Add(1)
+		<-release
+		return result
+	}
+	return c, &queries
+}
+
+func TestMigrationsCheckerCachesResult(t *testing.T) {
+	errPending := errors.New("1 pending migration(s)")
+	released := make(chan struct{})
+	close(released)
+
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		probes int
+		// invalidate calls Invalidate after the first probe
+		invalidate  bool
+		wantQueries int32
+	}{
+		{name: "rapid probes within the TTL", ttl: time.Hour, probes: 50, wantQueries: 1},
+		{name: "invalidated", ttl: time.Hour, probes: 3, invalidate: true, wantQueries: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, queries := newTestMigrationsChecker(tt.ttl, released, errPending)
+			for i := range tt.probes {
+				if err := c.Check(context.Background()); !errors.Is(err, errPending) {
+					t.Fatalf("probe %d: error = %v, want %v", i, err, errPending)
+				}
+				if i == 0 && tt.invalidate {
+					c.Invalidate()
+				}
+			}
+			if got := queries.Load(); got != tt.wantQueries {
+				t.Errorf("ran %d queries, want %d", got, tt.wantQueries)
+			}
+		})
+	}
+}
+
+func TestMigrationsCheckerSharesConcurrentQueries(t *testing.T) {
+	release := make(chan struct{})
+	c, queries := newTestMigrationsChecker(time.Hour, release, nil)
+
+	const probes = 10
+	var wg sync.WaitGroup
+	for range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Check(context.Background()); err != nil {
+				t.Errorf("Check: %v", err)
+			}
+		}()
+	}
+	// Let every probe join the query before it finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := queries.Load(); got != 1 {
+		t.Errorf("%d concurrent first probes ran %d queries, want 1", probes, got)
+	}
+	if c.CheckedAt().IsZero() {
+		t.Error("CheckedAt not set")
+	}
+}
+
+func TestMigrationsCheckerRefreshesInBackground(t *testing.T) {
+	released := make(chan struct{})
+	close(released)
+	c, queries := newTestMigrationsChecker(time.Millisecond, released, nil)
+
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first := c.CheckedAt()
+	time.Sleep(5 * time.Millisecond)
+
+	// A stale cache is served while one refresh runs in the background
+	for range 20 {
+		c.Check(context.Background())
+	}
+	deadline := time.Now().Add(time.Second)
+	for c.CheckedAt().Equal(first) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := queries.Load(); got < 2 {
+		t.Errorf("ran %d queries, want a background refresh", got)
+	}
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// attrsKey is the context key under which request-scoped log attributes
+// are stored.
+type attrsKey struct{}
+
+// WithAttrs returns a context carrying key-value attributes that are added
+// to every log entry written with it: by the *Context functions
+// (InfoContext, ...), by FromContext loggers, and by the request log.
+// Attributes accumulate across calls.
+//
+// Example:
+//
+//	ctx = logger.WithAttrs(ctx, "tenant_id", tenantID)
+//	logger.InfoContext(ctx, "invoice created") // includes tenant_id
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	existing := Attrs(ctx)
+	attrs := make([]any, 0, len(existing)+len(args))
+	attrs = append(append(attrs, existing...), args...)
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// Attrs returns the attributes stored in ctx by WithAttrs.
+func Attrs(ctx context.Context) []any {
+	attrs, _ := ctx.Value(attrsKey{}).([]any)
+	return attrs
+}
+
+// FromContext returns the global logger with the attributes stored in ctx
+// (see WithAttrs) already added.
+func FromContext(ctx context.Context) *slog.Logger {
+	l := logger.Load()
+	if attrs := Attrs(ctx); len(attrs) > 0 {
+		return l.With(attrs...)
+	}
+	return l
+}
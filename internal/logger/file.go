@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"io"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// FileConfig configures logging to a file with size-based rotation.
+//
+// When the file reaches MaxSize it is renamed with a timestamp
+// (app-2024-05-01T10-00-00.000.log) and a new one is started. Old files
+// are deleted once there are more than MaxBackups of them or they are older
+// than MaxAgeDays.
+type FileConfig struct {
+	// Path is the log file, created (with its directory) if missing.
+	Path string
+
+	// MaxSize is the size in bytes at which the file is rotated, rounded
+	// up to whole megabytes (default: 100 MB).
+	MaxSize int64
+
+	// MaxBackups is how many rotated files to keep; 0 keeps them all
+	// (subject to MaxAgeDays).
+	MaxBackups int
+
+	// MaxAgeDays is how many days to keep rotated files; 0 keeps them
+	// regardless of age (subject to MaxBackups).
+	MaxAgeDays int
+
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// writer returns the rotating writer for c.
+func (c FileConfig) writer() io.Writer {
+	const megabyte = 1 << 20
+	maxSizeMB := 0 // lumberjack's default, 100 MB
+	if c.MaxSize > 0 {
+		maxSizeMB = int((c.MaxSize + megabyte - 1) / megabyte)
+	}
+
+	return &lumberjack.Logger{
+		Filename:   c.Path,
+		MaxSize:    maxSizeMB,
+		MaxBackups: c.MaxBackups,
+		MaxAge:     c.MaxAgeDays,
+		Compress:   c.Compress,
+	}
+}
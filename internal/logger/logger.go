@@ -8,21 +8,39 @@
 //   - JSON output for production (machine-readable)
 //   - Text output for development (human-readable)
 //   - Configurable log levels (debug, info, warn, error)
-//   - Request context integration
+//   - Request context integration: request_id and WithAttrs attributes are
+//     added to entries logged with a request context (see WithRequestID)
+//   - Output fallback (stdout → stderr → discard) and SetOutput for tests
+//   - Optional log file with size-based rotation (see FileConfig)
+//   - Optional sampling of repetitive info and debug lines (see SamplingConfig)
+//   - Optional redaction of sensitive attributes such as passwords (see AddRedactKey)
+//   - SetHandler to capture structured records in tests
 //
 // Usage:
 //
 //	// Initialize once at startup
-//	logger.Init("info", true) // level, isDevelopment
+//	logger.Init("info", true, logger.TimeFormat{}) // level, isDevelopment, timestamps
+//
+//	// Or, to write to a rotated log file instead of stdout
+//	logger.InitWithConfig(logger.Config{
+//	    Level: "info",
+//	    File:  logger.FileConfig{Path: "/var/log/app/app.log", MaxBackups: 5},
+//	})
 //
 //	// Use throughout the application
 //	logger.Info("user logged in", "user_id", 123, "ip", "192.168.1.1")
 //	logger.Error("database error", "err", err, "query", "SELECT * FROM users")
 //	logger.Debug("request details", "headers", headers) // Only shown if level is debug
 //
-// With request context:
+// With request context (the request ID and attributes added with WithAttrs
+// are included):
 //
 //	logger.InfoContext(ctx, "processing request", "path", "/api/users")
+//	// request_id=9f86d081884c7d65 path=/api/users
+//
+// Use the *Context functions with c.Request().Context() in handlers, and
+// pass that context on to services, to get the request ID without
+// threading it through by hand.
 package logger
 
 import (
@@ -30,25 +48,66 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"sync/atomic"
 )
 
 // logger is the global logger instance.
-// It's initialized with defaults and can be reconfigured with Init().
-var logger *slog.Logger
+// It's initialized with defaults and can be reconfigured with Init() or
+// SetHandler(). It is an atomic pointer so swapping it while other goroutines
+// log is safe.
+var logger atomic.Pointer[slog.Logger]
 
 // init sets up a default logger that writes to stderr.
 // This ensures logging works even if Init() is not called.
 func init() {
-	logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+	logger.Store(slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
-	}))
+	})))
 }
 
 // Init configures the global logger based on environment and log level.
+// It is InitWithConfig without a log file, so logs go to stdout.
 //
 // Parameters:
 //   - level: Log level string ("debug", "info", "warn", "error")
 //   - isDevelopment: If true, uses human-readable text format; if false, uses JSON
+//   - timeFormat: Layout and time zone of timestamps; the zero value keeps
+//     slog's default (RFC 3339 with milliseconds, local time)
+func Init(level string, isDevelopment bool, timeFormat TimeFormat) {
+	InitWithConfig(Config{Level: level, Development: isDevelopment, TimeFormat: timeFormat})
+}
+
+// Config configures the global logger (see InitWithConfig).
+type Config struct {
+	// Level is "debug", "info", "warn" or "error" (default: "info").
+	Level string
+
+	// Development selects human-readable text output instead of JSON.
+	Development bool
+
+	// TimeFormat sets the layout and time zone of timestamps; the zero
+	// value keeps slog's default (RFC 3339 with milliseconds, local time).
+	TimeFormat TimeFormat
+
+	// File, when its Path is set, sends logs to a rotated file instead of
+	// stdout.
+	File FileConfig
+
+	// Sampling thins out repetitive records below warning level. It is
+	// ignored in development, where every line is wanted.
+	Sampling SamplingConfig
+
+	// Redact replaces the values of attributes with sensitive keys
+	// ("password", "token", ... see AddRedactKey) with "***".
+	Redact bool
+}
+
+// InitWithConfig configures the global logger.
+//
+// Logs are written to stdout, or to cfg.File.Path when set. If the output
+// is unusable, it falls back to stderr, and then to discarding logs, so
+// logging never crashes the app. Use SetOutput to redirect logs (e.g. to
+// capture them in tests).
 //
 // In development mode:
 //   - Uses colorized text output for easy reading in terminals
@@ -57,9 +116,11 @@ func init() {
 // In production mode:
 //   - Uses JSON format for easy parsing by log aggregators (e.g., ELK, Datadog)
 //   - Omits debug-level source information to reduce log size
-func Init(level string, isDevelopment bool) {
+//
+// The format follows Development whether logs go to stdout or a file.
+func InitWithConfig(cfg Config) {
 	var logLevel slog.Level
-	switch strings.ToLower(level) {
+	switch strings.ToLower(cfg.Level) {
 	case "debug":
 		logLevel = slog.LevelDebug
 	case "info":
@@ -76,22 +137,68 @@ func Init(level string, isDevelopment bool) {
 		Level: logLevel,
 		// AddSource adds file:line to log entries - useful for debugging
 		// but adds overhead, so only enable for debug level in development
-		AddSource: isDevelopment && logLevel == slog.LevelDebug,
+		AddSource:   cfg.Development && logLevel == slog.LevelDebug,
+		ReplaceAttr: cfg.TimeFormat.replaceAttr(),
+	}
+
+	// Write to the log file or stdout, falling back to stderr and finally
+	// discarding logs if the output is closed or broken (e.g. stdout in
+	// some sandboxed containers, or a full disk).
+	if cfg.File.Path != "" {
+		output.set(newFallbackWriter(cfg.File.writer(), os.Stderr))
+	} else {
+		output.set(newFallbackWriter(os.Stdout, os.Stderr))
 	}
 
 	var handler slog.Handler
-	if isDevelopment {
+	if cfg.Development {
 		// Text handler is easier to read in development terminals
-		handler = slog.NewTextHandler(os.Stdout, opts)
+		handler = slog.NewTextHandler(output, opts)
 	} else {
 		// JSON handler is better for production log aggregation systems
-		handler = slog.NewJSONHandler(os.Stdout, opts)
+		handler = slog.NewJSONHandler(output, opts)
+
+		// Sample high-volume lines (e.g. "request completed") so they
+		// don't drown out the rest or the log aggregator's budget
+		handler = newSamplingHandler(handler, cfg.Sampling)
 	}
 
-	logger = slog.New(handler)
+	// Keep passwords, tokens and the like out of the logs
+	if cfg.Redact {
+		handler = redactingHandler{handler}
+	}
+
+	// Add the request ID from the context to every *Context entry
+	handler = contextHandler{handler}
+
+	l := slog.New(handler)
+	logger.Store(l)
 
 	// Also set as the default logger for any code using slog directly
-	slog.SetDefault(logger)
+	slog.SetDefault(l)
+}
+
+// SetHandler replaces the handler behind the package-level logging functions
+// and returns a function that restores the previous one. It is safe to call
+// while other goroutines are logging.
+//
+// Tests use it to capture log records and assert on messages and attributes:
+//
+//	var buf bytes.Buffer
+//	restore := logger.SetHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+//	defer restore()
+//
+//	logger.Info("user logged in", "user_id", 123)
+//	// buf now holds {"time":"...","level":"INFO","msg":"user logged in","user_id":123}
+//
+// Only this package's functions are affected; slog's default logger is left
+// alone. Like the handler set by Init, h receives the request ID from the
+// context (see WithRequestID).
+func SetHandler(h slog.Handler) (restore func()) {
+	previous := logger.Swap(slog.New(contextHandler{h}))
+	return func() {
+		logger.Store(previous)
+	}
 }
 
 // Debug logs a message at debug level.
@@ -102,7 +209,7 @@ func Init(level string, isDevelopment bool) {
 //
 //	logger.Debug("processing item", "item_id", 42, "status", "pending")
 func Debug(msg string, args ...any) {
-	logger.Debug(msg, args...)
+	logger.Load().Debug(msg, args...)
 }
 
 // Info logs a message at info level.
@@ -113,7 +220,7 @@ func Debug(msg string, args ...any) {
 //
 //	logger.Info("server started", "port", 8080, "env", "production")
 func Info(msg string, args ...any) {
-	logger.Info(msg, args...)
+	logger.Load().Info(msg, args...)
 }
 
 // Warn logs a message at warning level.
@@ -124,7 +231,7 @@ func Info(msg string, args ...any) {
 //
 //	logger.Warn("rate limit approaching", "current", 950, "limit", 1000)
 func Warn(msg string, args ...any) {
-	logger.Warn(msg, args...)
+	logger.Load().Warn(msg, args...)
 }
 
 // Error logs a message at error level.
@@ -135,28 +242,30 @@ func Warn(msg string, args ...any) {
 //
 //	logger.Error("database connection failed", "err", err, "host", "db.example.com")
 func Error(msg string, args ...any) {
-	logger.Error(msg, args...)
+	logger.Load().Error(msg, args...)
 }
 
 // DebugContext logs a debug message with request context.
 // The context can carry request-specific values like request ID, user ID, etc.
+// The request ID stored with WithRequestID and attributes stored with
+// WithAttrs are added to the entry.
 func DebugContext(ctx context.Context, msg string, args ...any) {
-	logger.DebugContext(ctx, msg, args...)
+	FromContext(ctx).DebugContext(ctx, msg, args...)
 }
 
 // InfoContext logs an info message with request context.
 func InfoContext(ctx context.Context, msg string, args ...any) {
-	logger.InfoContext(ctx, msg, args...)
+	FromContext(ctx).InfoContext(ctx, msg, args...)
 }
 
 // WarnContext logs a warning message with request context.
 func WarnContext(ctx context.Context, msg string, args ...any) {
-	logger.WarnContext(ctx, msg, args...)
+	FromContext(ctx).WarnContext(ctx, msg, args...)
 }
 
 // ErrorContext logs an error message with request context.
 func ErrorContext(ctx context.Context, msg string, args ...any) {
-	logger.ErrorContext(ctx, msg, args...)
+	FromContext(ctx).ErrorContext(ctx, msg, args...)
 }
 
 // With returns a new logger with the given attributes added to every log entry.
@@ -168,11 +277,11 @@ func ErrorContext(ctx context.Context, msg string, args ...any) {
 //	reqLogger.Info("processing started")
 //	reqLogger.Info("processing completed") // Both logs have request_id and user_id
 func With(args ...any) *slog.Logger {
-	return logger.With(args...)
+	return logger.Load().With(args...)
 }
 
 // GetLogger returns the underlying slog.Logger for advanced use cases.
 // Prefer using the package-level functions (Info, Error, etc.) when possible.
 func GetLogger() *slog.Logger {
-	return logger
+	return logger.Load()
 }
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+func TestSetHandler(t *testing.T) {
+	var previous bytes.Buffer
+	defer SetHandler(slog.NewTextHandler(&previous, nil))()
+
+	var buf bytes.Buffer
+	restore := SetHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	Debug("cache miss", "key", "user:1")
+	Info("user logged in", "user_id", 123)
+
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("captured %d records, want 2:\n%s", len(records), buf.String())
+	}
+	if records[0]["level"] != "DEBUG" || records[0]["key"] != "user:1" {
+		t.Errorf("first record = %v", records[0])
+	}
+	if records[1]["msg"] != "user logged in" || records[1]["user_id"] != float64(123) {
+		t.Errorf("second record = %v", records[1])
+	}
+
+	restore()
+	buf.Reset()
+	Info("after restore")
+	if buf.Len() != 0 {
+		t.Errorf("record captured after restore: %s", buf.String())
+	}
+	if !bytes.Contains(previous.Bytes(), []byte("after restore")) {
+		t.Errorf("previous handler got %q, want the record logged after restore", previous.String())
+	}
+}
+
+func TestSetHandlerWhileLogging(t *testing.T) {
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					Debug("background")
+				}
+			}
+		}()
+	}
+
+	for range 100 {
+		SetHandler(slog.NewTextHandler(io.Discard, nil))()
+	}
+	close(stop)
+	wg.Wait()
+}
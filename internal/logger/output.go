@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// output is the writer all log handlers write to.
+// SetOutput swaps its destination without rebuilding the logger.
+var output = &swappableWriter{w: os.Stderr}
+
+// SetOutput redirects all log output to w. It is safe to call concurrently
+// with logging, which makes it convenient for capturing logs in tests:
+//
+//	var buf bytes.Buffer
+//	logger.SetOutput(&buf)
+//	defer logger.SetOutput(os.Stderr)
+func SetOutput(w io.Writer) {
+	output.set(w)
+}
+
+// swappableWriter is an io.Writer whose destination can be replaced at any time.
+type swappableWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *swappableWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+func (s *swappableWriter) set(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w = w
+}
+
+// fallbackWriter writes to the first usable writer in a chain. When a write
+// fails (closed stdout, broken pipe), it announces the switch on the next
+// writer and moves to it. Once every writer has failed, output is discarded
+// so logging never crashes or blocks the application.
+type fallbackWriter struct {
+	mu      sync.Mutex
+	writers []io.Writer
+	current int
+}
+
+// newFallbackWriter returns a writer that tries writers in order, skipping
+// any that are unusable from the start.
+func newFallbackWriter(writers ...io.Writer) *fallbackWriter {
+	f := &fallbackWriter{writers: writers}
+	for f.current < len(f.writers) && !usable(f.writers[f.current]) {
+		f.current++
+	}
+	return f
+}
+
+func (f *fallbackWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for f.current < len(f.writers) {
+		_, err := f.writers[f.current].Write(p)
+		if err == nil {
+			return len(p), nil
+		}
+
+		f.current++
+		if f.current < len(f.writers) {
+			fmt.Fprintf(f.writers[f.current], "logger: output failed (%v), falling back to next writer\n", err)
+		} else {
+			// Best effort: stderr may be the writer that just failed.
+			fmt.Fprintf(os.Stderr, "logger: all outputs failed (%v), discarding logs\n", err)
+		}
+	}
+
+	// Every writer failed: drop the message rather than return an error
+	return len(p), nil
+}
+
+// usable reports whether w looks writable. Files are checked with Stat,
+// which fails for closed descriptors; other writers are assumed usable.
+func usable(w io.Writer) bool {
+	if f, ok := w.(*os.File); ok {
+		if f == nil {
+			return false
+		}
+		_, err := f.Stat()
+		return err == nil
+	}
+	return true
+}
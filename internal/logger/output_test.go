@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// failingWriter fails every write, like a closed stdout or broken pipe.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestFallbackWriter(t *testing.T) {
+	t.Run("first writer works", func(t *testing.T) {
+		var first, second bytes.Buffer
+		w := newFallbackWriter(&first, &second)
+
+		if _, err := w.Write([]byte("hello\n")); err != nil {
+			t.Fatal(err)
+		}
+		if first.String() != "hello\n" || second.Len() != 0 {
+			t.Errorf("first = %q, second = %q", first.String(), second.String())
+		}
+	})
+
+	t.Run("falls back when a write fails", func(t *testing.T) {
+		var second bytes.Buffer
+		w := newFallbackWriter(failingWriter{}, &second)
+
+		for _, line := range []string{"one\n", "two\n"} {
+			if n, err := w.Write([]byte(line)); err != nil || n != len(line) {
+				t.Fatalf("Write = %d, %v", n, err)
+			}
+		}
+		want := "logger: output failed (broken pipe), falling back to next writer\none\ntwo\n"
+		if second.String() != want {
+			t.Errorf("second = %q, want %q", second.String(), want)
+		}
+	})
+
+	t.Run("skips closed files", func(t *testing.T) {
+		f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		var second bytes.Buffer
+		w := newFallbackWriter(f, &second)
+		if _, err := w.Write([]byte("hello\n")); err != nil {
+			t.Fatal(err)
+		}
+		if second.String() != "hello\n" {
+			t.Errorf("second = %q, want %q", second.String(), "hello\n")
+		}
+	})
+
+	t.Run("discards when every writer fails", func(t *testing.T) {
+		w := newFallbackWriter(failingWriter{})
+		for range 2 {
+			if n, err := w.Write([]byte("lost\n")); err != nil || n != 5 {
+				t.Errorf("Write = %d, %v, want 5, nil", n, err)
+			}
+		}
+	})
+}
+
+func TestSetOutput(t *testing.T) {
+	Init("info", false, TimeFormat{})
+
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(os.Stderr)
+	Info("captured", "key", "value")
+
+	if !bytes.Contains(buf.Bytes(), []byte(`"msg":"captured"`)) || !bytes.Contains(buf.Bytes(), []byte(`"key":"value"`)) {
+		t.Errorf("log output = %q, want the captured entry", buf.String())
+	}
+}
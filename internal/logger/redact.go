@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces the value of attributes with sensitive keys.
+const redactedValue = "***"
+
+// redactKeys holds the denylist checked by the redacting handler. A key
+// containing any entry (case-insensitively) is redacted, so "password"
+// also covers "db_password" and "PasswordHash".
+var redactKeys = struct {
+	sync.RWMutex
+	keys []string
+}{keys: []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"authorization",
+	"cookie",
+	"api_key",
+	"apikey",
+	"private_key",
+	"email",
+}}
+
+// AddRedactKey adds key to the list of attribute keys whose values are
+// replaced with "***" when redaction is enabled (see Config.Redact).
+// Matching is case-insensitive and also applies to keys containing key.
+// It is safe to call at any time, typically during startup:
+//
+//	logger.AddRedactKey("ssn")
+//	logger.AddRedactKey("iban")
+func AddRedactKey(key string) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		return
+	}
+	redactKeys.Lock()
+	defer redactKeys.Unlock()
+	redactKeys.keys = append(redactKeys.keys, key)
+}
+
+// isSensitive reports whether values logged under key must be redacted.
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	redactKeys.RLock()
+	defer redactKeys.RUnlock()
+	for _, k := range redactKeys.keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactingHandler is a slog.Handler that replaces the values of sensitive
+// attributes with "***" before they reach the wrapped handler. Groups are
+// checked recursively, including the groups returned by slog.LogValuer
+// types, so a struct that logs itself as a group has its fields checked
+// too.
+type redactingHandler struct {
+	slog.Handler
+}
+
+// Handle implements slog.Handler.
+func (h redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+// WithAttrs implements slog.Handler.
+func (h redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return redactingHandler{h.Handler.WithAttrs(redactAttrs(attrs))}
+}
+
+// WithGroup implements slog.Handler.
+func (h redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{h.Handler.WithGroup(name)}
+}
+
+// redactAttr returns a with its value replaced if its key is sensitive,
+// or with its group members redacted.
+func redactAttr(a slog.Attr) slog.Attr {
+	if isSensitive(a.Key) {
+		return slog.String(a.Key, redactedValue)
+	}
+	if a.Value.Kind() == slog.KindLogValuer {
+		a.Value = a.Value.Resolve()
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		a.Value = slog.GroupValue(redactAttrs(a.Value.Group())...)
+	}
+	return a
+}
+
+// redactAttrs applies redactAttr to each attribute.
+func redactAttrs(attrs []slog.Attr) []slog.Attr {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return redacted
+}
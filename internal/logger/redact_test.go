@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+// account logs itself as a group, like a model implementing slog.LogValuer.
+type account struct {
+	name     string
+	apiToken string
+}
+
+func (a account) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("name", a.name), slog.String("api_token", a.apiToken))
+}
+
+func TestRedactingHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		// log writes one record with the redacting logger
+		log  func(l *slog.Logger)
+		want map[string]any
+	}{
+		{
+			name: "sensitive key",
+			log:  func(l *slog.Logger) { l.Info("login", "user", "ada", "password", "hunter2") },
+			want: map[string]any{"user": "ada", "password": "***"},
+		},
+		{
+			name: "key containing a sensitive word, any case",
+			log:  func(l *slog.Logger) { l.Info("connect", "DB_Password", "hunter2", "SessionToken", 42) },
+			want: map[string]any{"DB_Password": "***", "SessionToken": "***"},
+		},
+		{
+			name: "group members",
+			log: func(l *slog.Logger) {
+				l.Info("request", slog.Group("headers", "Authorization", "Bearer abc", "Accept", "text/html"))
+			},
+			want: map[string]any{"headers": map[string]any{"Authorization": "***", "Accept": "text/html"}},
+		},
+		{
+			name: "whole group under a sensitive key",
+			log:  func(l *slog.Logger) { l.Info("config", slog.Group("secrets", "a", "1")) },
+			want: map[string]any{"secrets": "***"},
+		},
+		{
+			name: "LogValuer resolved to a group",
+			log:  func(l *slog.Logger) { l.Info("signup", "account", account{name: "ada", apiToken: "t0k"}) },
+			want: map[string]any{"account": map[string]any{"name": "ada", "api_token": "***"}},
+		},
+		{
+			name: "attributes added with With",
+			log:  func(l *slog.Logger) { l.With("email", "ada@example.com").Info("sent", "to", "ada") },
+			want: map[string]any{"email": "***", "to": "ada"},
+		},
+		{
+			name: "attributes inside WithGroup",
+			log:  func(l *slog.Logger) { l.WithGroup("user").Info("updated", "id", 7, "password", "x") },
+			want: map[string]any{"user": map[string]any{"id": float64(7), "password": "***"}},
+		},
+		{
+			name: "nothing sensitive",
+			log:  func(l *slog.Logger) { l.Info("hello", "path", "/books", "status", 200) },
+			want: map[string]any{"path": "/books", "status": float64(200)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(redactingHandler{slog.NewJSONHandler(&buf, nil)}))
+
+			if got := loggedAttrs(t, buf.Bytes()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logged %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddRedactKey(t *testing.T) {
+	redactKeys.RLock()
+	saved := append([]string(nil), redactKeys.keys...)
+	redactKeys.RUnlock()
+	t.Cleanup(func() {
+		redactKeys.Lock()
+		redactKeys.keys = saved
+		redactKeys.Unlock()
+	})
+
+	AddRedactKey("  SSN ")
+	AddRedactKey("")
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "ssn", want: true},
+		{key: "customer_SSN", want: true},
+		{key: "password", want: true},
+		{key: "name", want: false},
+	}
+	for _, tt := range tests {
+		if got := isSensitive(tt.key); got != tt.want {
+			t.Errorf("isSensitive(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if len(redactKeys.keys) != len(saved)+1 {
+		t.Errorf("AddRedactKey(\"\") added a key: %q", redactKeys.keys)
+	}
+}
+
+// loggedAttrs decodes a JSON log line, dropping the time, level and
+// message.
+func loggedAttrs(t *testing.T, line []byte) map[string]any {
+	t.Helper()
+	var record map[string]any
+	if err := json.Unmarshal(line, &record); err != nil {
+		t.Fatalf("decode %q: %v", line, err)
+	}
+	for _, key := range []string{slog.TimeKey, slog.LevelKey, slog.MessageKey} {
+		delete(record, key)
+	}
+	return record
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// requestIDKey is the context key under which the request ID is stored.
+type requestIDKey struct{}
+
+// requestIDAttr is the attribute key the request ID is logged under.
+const requestIDAttr = "request_id"
+
+// WithRequestID returns a context carrying the request ID id. Every entry
+// logged with it through the *Context functions (InfoContext, ...) gets a
+// request_id attribute, so the callee doesn't need to know the ID:
+//
+//	ctx = logger.WithRequestID(ctx, id) // done by the request ID middleware
+//	...
+//	logger.InfoContext(ctx, "invoice created") // includes request_id
+//
+// The request ID middleware calls it for every request, so handlers only
+// have to pass c.Request().Context() on to services.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx by WithRequestID, or ""
+// if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler is a slog.Handler that adds the request ID stored in the
+// record's context (see WithRequestID) as a request_id attribute. Records
+// that already carry a request_id are left alone, so callers that still
+// pass it explicitly don't log it twice.
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle implements slog.Handler.
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" && !hasAttr(r, requestIDAttr) {
+			r = r.Clone()
+			r.AddAttrs(slog.String(requestIDAttr, id))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// hasAttr reports whether r has a top-level attribute with the given key.
+func hasAttr(r slog.Record, key string) bool {
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = a.Key == key
+		return !found
+	})
+	return found
+}
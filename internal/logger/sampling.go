@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SamplingConfig limits how often the same log line is written under load.
+//
+// Within each Interval, the first Initial records with a given level and
+// message are logged, then only every Thereafter-th one. Warnings and
+// errors are never sampled.
+//
+// With Initial 100 and Thereafter 100, a "request completed" line logged
+// 5,000 times a second is written 149 times a second: the first 100, then
+// one in every 100 of the remaining 4,900.
+type SamplingConfig struct {
+	// Initial is how many records per message are logged in each interval
+	// before sampling starts. 0 disables sampling.
+	Initial int
+
+	// Thereafter logs one in every Thereafter records once Initial is
+	// reached. 0 drops them all until the next interval.
+	Thereafter int
+
+	// Interval is the window the counts are kept for (default: 1s).
+	Interval time.Duration
+}
+
+// samplingHandler is a slog.Handler that drops repetitive records below
+// warning level before they reach the wrapped handler.
+type samplingHandler struct {
+	slog.Handler
+	sampler *sampler
+}
+
+// newSamplingHandler wraps h so records are sampled as described by cfg.
+func newSamplingHandler(h slog.Handler, cfg SamplingConfig) slog.Handler {
+	if cfg.Initial <= 0 {
+		return h
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	return &samplingHandler{
+		Handler: h,
+		sampler: &sampler{cfg: cfg, counts: make(map[samplingKey]int)},
+	}
+}
+
+// Handle implements slog.Handler.
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && !h.sampler.keep(r.Level, r.Message, r.Time) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler. The derived handler shares the
+// counts, so a message is sampled the same whatever attributes it carries.
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+// WithGroup implements slog.Handler.
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), sampler: h.sampler}
+}
+
+// samplingKey identifies the records counted together.
+type samplingKey struct {
+	level   slog.Level
+	message string
+}
+
+// sampler counts records per level and message within the current interval.
+type sampler struct {
+	cfg SamplingConfig
+
+	mu          sync.Mutex
+	counts      map[samplingKey]int
+	windowStart time.Time
+}
+
+// keep counts a record logged at t and reports whether it should be
+// written. All counts reset when a new interval starts, which also keeps
+// the map from growing with messages that are no longer logged.
+func (s *sampler) keep(level slog.Level, message string, t time.Time) bool {
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t.Sub(s.windowStart) >= s.cfg.Interval {
+		clear(s.counts)
+		s.windowStart = t
+	}
+
+	key := samplingKey{level: level, message: message}
+	s.counts[key]++
+	n := s.counts[key]
+
+	if n <= s.cfg.Initial {
+		return true
+	}
+	return s.cfg.Thereafter > 0 && (n-s.cfg.Initial)%s.cfg.Thereafter == 0
+}
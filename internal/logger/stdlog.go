@@ -0,0 +1,29 @@
+package logger
+
+import (
+	"log"
+	"strings"
+)
+
+// NewStdLogger returns a standard library *log.Logger whose output goes
+// through the structured logger at warn level, tagged with "component".
+//
+// Use it for libraries that only accept a *log.Logger, such as
+// http.Server.ErrorLog, so their messages (TLS handshake failures, malformed
+// requests, handler panics) land in the same structured stream:
+//
+//	e.StdLogger = logger.NewStdLogger("http.server") // Echo copies it to e.Server.ErrorLog
+func NewStdLogger(component string) *log.Logger {
+	return log.New(stdLogWriter{component: component}, "", 0)
+}
+
+// stdLogWriter turns each line written by a *log.Logger into a warning.
+type stdLogWriter struct {
+	component string
+}
+
+func (w stdLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	logger.Load().Warn(msg, "component", w.component)
+	return len(p), nil
+}
@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defer SetHandler(slog.NewJSONHandler(&buf, nil))()
+
+	NewStdLogger("http.server").Printf("http: TLS handshake error from %s: EOF", "10.0.0.1:5555")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("decode %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"level":     "WARN",
+		"msg":       "http: TLS handshake error from 10.0.0.1:5555: EOF",
+		"component": "http.server",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// TimeFormat controls how log timestamps are written.
+type TimeFormat struct {
+	// Layout is "rfc3339", "rfc3339nano", "unix" (seconds since the epoch,
+	// as a number), "human" ("2006-01-02 15:04:05.000"), any Go time layout,
+	// or empty for slog's default.
+	Layout string
+
+	// UTC converts timestamps to UTC instead of the local time zone.
+	UTC bool
+}
+
+// humanTimeLayout is the "human" layout: readable in a terminal, with
+// milliseconds to tell apart lines logged close together.
+const humanTimeLayout = "2006-01-02 15:04:05.000"
+
+// replaceAttr returns a slog ReplaceAttr function that rewrites the record
+// time according to f, or nil if f keeps slog's defaults.
+func (f TimeFormat) replaceAttr() func(groups []string, a slog.Attr) slog.Attr {
+	if f.Layout == "" && !f.UTC {
+		return nil
+	}
+
+	layout := f.Layout
+	switch strings.ToLower(layout) {
+	case "rfc3339":
+		layout = time.RFC3339
+	case "rfc3339nano", "":
+		layout = time.RFC3339Nano
+	case "human":
+		layout = humanTimeLayout
+	}
+
+	return func(groups []string, a slog.Attr) slog.Attr {
+		// Only the record's own time, not time values passed as attributes
+		if len(groups) > 0 || a.Key != slog.TimeKey || a.Value.Kind() != slog.KindTime {
+			return a
+		}
+		t := a.Value.Time()
+		if f.UTC {
+			t = t.UTC()
+		}
+		if strings.EqualFold(layout, "unix") {
+			return slog.Int64(a.Key, t.Unix())
+		}
+		return slog.String(a.Key, t.Format(layout))
+	}
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestTimeFormat(t *testing.T) {
+	// 12:34:56.789 in UTC+2
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	recorded := time.Date(2026, 3, 4, 14, 34, 56, 789_000_000, zone)
+
+	tests := []struct {
+		name   string
+		format TimeFormat
+		want   any
+	}{
+		{name: "default", format: TimeFormat{}, want: "2026-03-04T14:34:56.789+02:00"},
+		{name: "utc only", format: TimeFormat{UTC: true}, want: "2026-03-04T12:34:56.789Z"},
+		{name: "rfc3339", format: TimeFormat{Layout: "rfc3339"}, want: "2026-03-04T14:34:56+02:00"},
+		{name: "rfc3339 utc", format: TimeFormat{Layout: "RFC3339", UTC: true}, want: "2026-03-04T12:34:56Z"},
+		{name: "human", format: TimeFormat{Layout: "human"}, want: "2026-03-04 14:34:56.789"},
+		{name: "human utc", format: TimeFormat{Layout: "human", UTC: true}, want: "2026-03-04 12:34:56.789"},
+		{name: "unix", format: TimeFormat{Layout: "unix"}, want: float64(recorded.Unix())},
+		{name: "go layout", format: TimeFormat{Layout: "15:04 MST", UTC: true}, want: "12:34 UTC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: tt.format.replaceAttr()})
+
+			record := slog.NewRecord(recorded, slog.LevelInfo, "hello", 0)
+			// Time values passed as attributes are left alone
+			record.AddAttrs(slog.Time("created_at", recorded))
+			if err := handler.Handle(context.Background(), record); err != nil {
+				t.Fatal(err)
+			}
+
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			if entry["time"] != tt.want {
+				t.Errorf("time = %#v, want %#v", entry["time"], tt.want)
+			}
+			if entry["created_at"] != "2026-03-04T14:34:56.789+02:00" {
+				t.Errorf("created_at = %#v, want it unchanged", entry["created_at"])
+			}
+		})
+	}
+}
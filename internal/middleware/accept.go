@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// PreferredMediaType returns the offer the request's Accept header ranks
+// highest, or "" if it accepts none of them.
+//
+// Each offer gets the q-value of the most specific media range matching it
+// ("application/json" beats "application/*" beats "*/*"), so
+// "application/json, text/plain, */*; q=0.9" prefers JSON over HTML. When
+// two offers have the same q-value, the one matched more specifically wins,
+// and after that the one listed first. A missing Accept header accepts
+// anything, so the first offer wins.
+//
+// Usage:
+//
+//	if middleware.PreferredMediaType(c.Request(), echo.MIMETextHTML, echo.MIMEApplicationJSON) == echo.MIMEApplicationJSON {
+//	    return c.JSON(http.StatusOK, data)
+//	}
+func PreferredMediaType(r *http.Request, offers ...string) string {
+	ranges := parseAccept(r.Header.Get(echo.HeaderAccept))
+
+	best := ""
+	bestQ, bestSpecificity := 0.0, -1
+	for _, offer := range offers {
+		q, specificity := 1.0, 0
+		if ranges != nil {
+			q, specificity = matchAccept(ranges, offer)
+		}
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+	return best
+}
+
+// acceptRange is one media range from an Accept header.
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept splits an Accept header into its media ranges. Returns nil
+// for an empty header. Malformed entries are skipped.
+func parseAccept(header string) []acceptRange {
+	if strings.TrimSpace(header) == "" {
+		return nil
+	}
+
+	ranges := []acceptRange{}
+	for _, part := range strings.Split(header, ",") {
+		mediaRange, params, _ := strings.Cut(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mediaRange)), "/")
+		if !ok || typ == "" || subtype == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(name) != "q" {
+				continue
+			}
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = min(max(v, 0), 1)
+			}
+		}
+		ranges = append(ranges, acceptRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// matchAccept returns the q-value the most specific range matching offer
+// gives it, and how specific that range is: 2 for type/subtype, 1 for
+// type/*, 0 for */*. An offer no range matches gets q=0.
+func matchAccept(ranges []acceptRange, offer string) (q float64, specificity int) {
+	typ, subtype, _ := strings.Cut(strings.ToLower(offer), "/")
+
+	specificity = -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q, specificity
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestPreferredMediaType(t *testing.T) {
+	const (
+		html = echo.MIMETextHTML
+		json = echo.MIMEApplicationJSON
+	)
+	browser := "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+	tests := []struct {
+		name   string
+		accept string
+		offers []string
+		want   string
+	}{
+		{name: "no header takes the first offer", offers: []string{html, json}, want: html},
+		{name: "no header, JSON first", offers: []string{json, html}, want: json},
+		{name: "exact match", accept: "application/json", offers: []string{html, json}, want: json},
+		{name: "browser", accept: browser, offers: []string{json, html}, want: html},
+		{name: "axios-style", accept: "application/json, text/plain, */*; q=0.9", offers: []string{html, json}, want: json},
+		{name: "higher q wins", accept: "text/html;q=0.5, application/json;q=0.8", offers: []string{html, json}, want: json},
+		{name: "equal q, more specific wins", accept: "application/*, */*", offers: []string{html, json}, want: json},
+		{name: "equal q and specificity, first offer wins", accept: "text/html, application/json", offers: []string{json, html}, want: json},
+		{name: "wildcard only", accept: "*/*", offers: []string{html, json}, want: html},
+		{name: "q=0 excludes an offer", accept: "*/*, text/html;q=0", offers: []string{html, json}, want: json},
+		{name: "nothing acceptable", accept: "image/png", offers: []string{html, json}, want: ""},
+		{name: "case and spaces", accept: " Application/JSON ; Q=1 ", offers: []string{html, json}, want: json},
+		{name: "malformed entries skipped", accept: "garbage, /json, application/json", offers: []string{html, json}, want: json},
+		{name: "invalid q treated as 1", accept: "text/html;q=abc, application/json;q=0.9", offers: []string{json, html}, want: html},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			if got := PreferredMediaType(req, tt.offers...); got != tt.want {
+				t.Errorf("PreferredMediaType(%q, %q) = %q, want %q", tt.accept, tt.offers, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"replace-me/internal/logger"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// combinedTimeFormat is the timestamp layout of the Combined Log Format,
+// e.g. "10/Oct/2000:13:55:36 -0700".
+const combinedTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// combinedLogMiddleware writes one access log line per request to w in the
+// Combined Log Format used by Apache and nginx, which tools such as GoAccess
+// and AWStats read directly:
+//
+//	203.0.113.7 - - [10/Oct/2026:13:55:36 +0000] "GET /books?page=2 HTTP/1.1" 200 5120 "https://example.com/" "Mozilla/5.0 ..."
+//
+// It replaces the structured request log (see ACCESS_LOG_FORMAT); errors
+// are still logged by the error handler.
+func combinedLogMiddleware(w io.Writer) echo.MiddlewareFunc {
+	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		LogRemoteIP:     true,
+		LogMethod:       true,
+		LogURI:          true,
+		LogProtocol:     true,
+		LogStatus:       true,
+		LogResponseSize: true,
+		LogReferer:      true,
+		LogUserAgent:    true,
+		LogError:        true,
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			// The error handler runs after this middleware returns, so a
+			// handler error hasn't set the response status yet.
+			if v.Error != nil && !c.Response().Committed {
+				v.Status, _ = errorStatus(v.Error, false)
+			}
+			if clientGone(c) {
+				v.Status = StatusClientClosedRequest
+			}
+			_, err := io.WriteString(w, formatCombined(v))
+			return err
+		},
+	})
+}
+
+// formatCombined formats a request as a Combined Log Format line.
+func formatCombined(v middleware.RequestLoggerValues) string {
+	size := "-"
+	if v.ResponseSize > 0 {
+		size = strconv.FormatInt(v.ResponseSize, 10)
+	}
+
+	var b strings.Builder
+	b.WriteString(combinedField(v.RemoteIP))
+	b.WriteString(" - - [")
+	b.WriteString(v.StartTime.Format(combinedTimeFormat))
+	b.WriteString(`] "`)
+	b.WriteString(combinedQuoted(v.Method + " " + v.URI + " " + v.Protocol))
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(v.Status))
+	b.WriteString(" ")
+	b.WriteString(size)
+	b.WriteString(` "`)
+	b.WriteString(combinedQuoted(combinedField(v.Referer)))
+	b.WriteString(`" "`)
+	b.WriteString(combinedQuoted(combinedField(v.UserAgent)))
+	b.WriteString("\"\n")
+	return b.String()
+}
+
+// combinedField returns "-" for empty values, as the format requires.
+func combinedField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// combinedQuoted escapes a value written inside double quotes, so a
+// crafted User-Agent or URI can't break the line apart.
+func combinedQuoted(value string) string {
+	return combinedEscaper.Replace(value)
+}
+
+var combinedEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// openAccessLog returns the writer for access log lines: the file at path
+// (appended to, created if missing), or stdout when path is empty or the
+// file can't be opened.
+func openAccessLog(path string) io.Writer {
+	if path == "" {
+		return os.Stdout
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		logger.Error("failed to open access log, writing to stdout", "path", path, "error", err.Error())
+		return os.Stdout
+	}
+	return f
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// apiContextKey marks a request as belonging to a JSON API route.
+const apiContextKey = "api"
+
+// APIOnly returns a middleware for JSON API route groups.
+//
+// Routes behind it:
+//   - always get JSON error responses from the error handler, whatever the
+//     Accept header says
+//   - reject request bodies that aren't application/json (415)
+//   - default the response Content-Type to application/json
+//
+// Usage:
+//
+//	api := e.Group("/api", middleware.APIOnly())
+//	api.GET("/books", h.ListBooks)
+func APIOnly() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(apiContextKey, true)
+
+			// Requests that carry a body must send JSON
+			req := c.Request()
+			if req.ContentLength != 0 && req.Method != http.MethodGet && req.Method != http.MethodHead {
+				mediaType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+				if mediaType != echo.MIMEApplicationJSON {
+					return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+				}
+			}
+
+			// Ensure every response is labeled as JSON, even ones written
+			// without c.JSON (e.g. c.Blob or a streamed encoder).
+			res := c.Response()
+			res.Before(func() {
+				if res.Header().Get(echo.HeaderContentType) == "" {
+					res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+				}
+			})
+
+			return next(c)
+		}
+	}
+}
+
+// IsAPI reports whether the request is being served by an APIOnly route group.
+func IsAPI(c echo.Context) bool {
+	api, _ := c.Get(apiContextKey).(bool)
+	return api
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestAPIOnly(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = customErrorHandler(&config.Config{})
+	api := e.Group("/api", APIOnly())
+	api.GET("/books", func(c echo.Context) error {
+		// Written without c.JSON, so no Content-Type is set
+		c.Response().WriteHeader(http.StatusOK)
+		_, err := c.Response().Write([]byte(`[]`))
+		return err
+	})
+	api.POST("/books", func(c echo.Context) error {
+		return c.NoContent(http.StatusCreated)
+	})
+	api.GET("/missing", func(c echo.Context) error {
+		return echo.ErrNotFound
+	})
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		contentType     string
+		body            string
+		accept          string
+		wantStatus      int
+		wantContentType string
+	}{
+		{name: "JSON body", method: http.MethodPost, path: "/api/books", contentType: "application/json; charset=utf-8", body: `{}`, wantStatus: http.StatusCreated},
+		{name: "form body", method: http.MethodPost, path: "/api/books", contentType: echo.MIMEApplicationForm, body: "title=x", wantStatus: http.StatusUnsupportedMediaType, wantContentType: echo.MIMEApplicationJSON},
+		{name: "no body", method: http.MethodPost, path: "/api/books", wantStatus: http.StatusCreated},
+		{name: "response defaults to JSON", method: http.MethodGet, path: "/api/books", wantStatus: http.StatusOK, wantContentType: echo.MIMEApplicationJSON},
+		{name: "errors are JSON for browsers too", method: http.MethodGet, path: "/api/missing", accept: "text/html", wantStatus: http.StatusNotFound, wantContentType: echo.MIMEApplicationJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set(echo.HeaderContentType, tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, tt.wantContentType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+		})
+	}
+}
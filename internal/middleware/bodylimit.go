@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"cmp"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// bodyLimitKey is the context key under which the request's *limitedBody
+// is stored, so WithBodyLimit can adjust the limit for a single route.
+const bodyLimitKey = "_body_limit"
+
+// BodyLimits maps routes to their maximum request body size in bytes.
+// Keys are Echo route patterns as registered (c.Path()), optionally prefixed
+// with a method, and may end in "*" to cover every route under a prefix:
+//
+//	middleware.SetBodyLimits(middleware.BodyLimits{
+//	    "POST /uploads":  50 << 20, // 50 MB for file uploads
+//	    "/api/*":         64 << 10, // 64 KB for JSON API bodies
+//	    "PUT /books/:id": 1 << 20,
+//	})
+//
+// The most specific key wins: "METHOD /path", then "/path", then the longest
+// matching prefix, with "METHOD /prefix*" ahead of "/prefix*" of the same
+// length. Routes without an entry use MAX_REQUEST_BODY_SIZE.
+type BodyLimits map[string]int64
+
+// routeBodyLimits holds the limits registered with SetBodyLimits.
+var routeBodyLimits bodyLimitRules
+
+// SetBodyLimits declares per-route body limits (see BodyLimits). Call it
+// once during startup, before the server starts accepting requests.
+func SetBodyLimits(limits BodyLimits) {
+	routeBodyLimits = compileBodyLimits(limits)
+}
+
+// bodyLimitRules is BodyLimits prepared for lookup: exact keys in a map,
+// prefix keys sorted so the first match is the most specific.
+type bodyLimitRules struct {
+	exact    map[string]int64
+	prefixes []bodyLimitPrefix
+}
+
+// bodyLimitPrefix is a BodyLimits key ending in "*". An empty method
+// matches every method.
+type bodyLimitPrefix struct {
+	method string
+	prefix string
+	limit  int64
+}
+
+// compileBodyLimits splits limits into exact and prefix rules. Prefixes are
+// ordered longest first, method-qualified before bare among equal lengths,
+// so lookup never depends on map iteration order.
+func compileBodyLimits(limits BodyLimits) bodyLimitRules {
+	rules := bodyLimitRules{exact: make(map[string]int64, len(limits))}
+	for key, limit := range limits {
+		pattern, ok := strings.CutSuffix(key, "*")
+		if !ok {
+			rules.exact[key] = limit
+			continue
+		}
+		rule := bodyLimitPrefix{prefix: pattern, limit: limit}
+		if method, path, ok := strings.Cut(pattern, " "); ok {
+			rule.method, rule.prefix = method, path
+		}
+		rules.prefixes = append(rules.prefixes, rule)
+	}
+	slices.SortFunc(rules.prefixes, func(a, b bodyLimitPrefix) int {
+		if n := cmp.Compare(len(b.prefix), len(a.prefix)); n != 0 {
+			return n
+		}
+		// Descending, so a method-qualified rule comes before the bare one
+		return cmp.Compare(b.method, a.method)
+	})
+	return rules
+}
+
+// lookup returns the limit for the matched route, if one is declared.
+func (r bodyLimitRules) lookup(method, path string) (int64, bool) {
+	if limit, ok := r.exact[method+" "+path]; ok {
+		return limit, true
+	}
+	if limit, ok := r.exact[path]; ok {
+		return limit, true
+	}
+	for _, rule := range r.prefixes {
+		if (rule.method == "" || rule.method == method) && strings.HasPrefix(path, rule.prefix) {
+			return rule.limit, true
+		}
+	}
+	return 0, false
+}
+
+// bodyLimitMiddleware caps the size of request bodies. The limit depends on
+// the matched route (see BodyLimits), falling back to defaultLimit.
+//
+// Nothing is rejected up front: the body is wrapped so that reading past the
+// limit fails with 413 Request Entity Too Large. A declared Content-Length
+// over the limit fails on the first read, without reading the body. Waiting
+// for the handler to read lets WithBodyLimit raise the limit for its route.
+func bodyLimitMiddleware(defaultLimit int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Body == nil || req.Body == http.NoBody {
+				return next(c)
+			}
+
+			limit := defaultLimit
+			if n, ok := routeBodyLimits.lookup(req.Method, c.Path()); ok {
+				limit = n
+			}
+
+			body := &limitedBody{
+				ReadCloser:    req.Body,
+				contentLength: req.ContentLength,
+				limit:         limit,
+			}
+			req.Body = body
+			c.Set(bodyLimitKey, body)
+			return next(c)
+		}
+	}
+}
+
+// WithBodyLimit overrides the request body limit for a single route or group.
+//
+// Usage:
+//
+//	e.POST("/uploads", h.Upload, middleware.WithBodyLimit(50<<20))
+func WithBodyLimit(limit int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if body, ok := c.Get(bodyLimitKey).(*limitedBody); ok {
+				body.limit = limit
+			}
+			return next(c)
+		}
+	}
+}
+
+// limitedBody is a request body that fails with 413 once more than limit
+// bytes have been read.
+type limitedBody struct {
+	io.ReadCloser
+	contentLength int64
+	limit         int64
+	read          int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.contentLength > b.limit || b.read > b.limit {
+		return 0, echo.ErrStatusRequestEntityTooLarge
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return n, echo.ErrStatusRequestEntityTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestBodyLimitsLookup(t *testing.T) {
+	limits := compileBodyLimits(BodyLimits{
+		"POST /uploads":  50,
+		"/uploads":       40,
+		"PUT /books/:id": 30,
+		"/api/*":         20,
+		"POST /api/*":    21,
+		"/api/admin/*":   10,
+		"GET /static/*":  5,
+	})
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantLimit int64
+		wantOK    bool
+	}{
+		{name: "method and path", method: http.MethodPost, path: "/uploads", wantLimit: 50, wantOK: true},
+		{name: "path", method: http.MethodPut, path: "/uploads", wantLimit: 40, wantOK: true},
+		{name: "exact path beats prefix", method: http.MethodPut, path: "/books/:id", wantLimit: 30, wantOK: true},
+		{name: "other method on a method-qualified path", method: http.MethodPost, path: "/books/:id"},
+		{name: "prefix", method: http.MethodGet, path: "/api/books", wantLimit: 20, wantOK: true},
+		{name: "method-qualified prefix wins a tie", method: http.MethodPost, path: "/api/books", wantLimit: 21, wantOK: true},
+		{name: "longest prefix", method: http.MethodPost, path: "/api/admin/users", wantLimit: 10, wantOK: true},
+		{name: "method-qualified prefix, other method", method: http.MethodPost, path: "/static/app.css"},
+		{name: "no rule", method: http.MethodPost, path: "/books"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeat to catch answers that depend on map iteration order
+			for range 20 {
+				limit, ok := limits.lookup(tt.method, tt.path)
+				if limit != tt.wantLimit || ok != tt.wantOK {
+					t.Fatalf("lookup(%s, %s) = %d, %v, want %d, %v", tt.method, tt.path, limit, ok, tt.wantLimit, tt.wantOK)
+				}
+			}
+		})
+	}
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	t.Cleanup(func() { SetBodyLimits(nil) })
+	SetBodyLimits(BodyLimits{"POST /uploads": 8})
+
+	tests := []struct {
+		name  string
+		path  string
+		body  string
+		route []echo.MiddlewareFunc
+		// unknownLength sends the body without a Content-Length
+		unknownLength bool
+		wantErr       error
+	}{
+		{name: "within the default limit", path: "/books", body: "1234"},
+		{name: "over the default limit", path: "/books", body: "123456", wantErr: echo.ErrStatusRequestEntityTooLarge},
+		{name: "over, without Content-Length", path: "/books", body: "123456", unknownLength: true, wantErr: echo.ErrStatusRequestEntityTooLarge},
+		{name: "route limit", path: "/uploads", body: "12345678"},
+		{name: "WithBodyLimit raises the limit", path: "/books", body: "123456", route: []echo.MiddlewareFunc{WithBodyLimit(16)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(bodyLimitMiddleware(4))
+			var readErr error
+			handler := func(c echo.Context) error {
+				_, readErr = io.ReadAll(c.Request().Body)
+				return nil
+			}
+			e.POST("/books", handler, tt.route...)
+			e.POST("/uploads", handler, tt.route...)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.unknownLength {
+				req.ContentLength = -1
+			}
+			e.ServeHTTP(httptest.NewRecorder(), req)
+			if !errors.Is(readErr, tt.wantErr) {
+				t.Errorf("read error = %v, want %v", readErr, tt.wantErr)
+			}
+		})
+	}
+}
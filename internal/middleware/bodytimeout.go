@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"replace-me/internal/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// bodyReadTimeoutKey is the context key under which the request's
+// *timedBody is stored, so WithBodyReadTimeout can adjust the deadline for a
+// single route.
+const bodyReadTimeoutKey = "_body_read_timeout"
+
+// bodyReadTimeoutMiddleware limits how long reading a request body may take,
+// counted from the start of the request.
+//
+// The server's READ_TIMEOUT covers the whole request, and a client that
+// trickles its body a few bytes at a time keeps a handler (and whatever it
+// holds, like a database transaction) busy for all of it. With this
+// middleware a body that isn't fully received within timeout fails to read
+// with 408 Request Timeout, which the error handler sends back.
+//
+// The deadline is set on the connection, so a read blocked on a silent
+// client fails when it passes instead of waiting for the next byte. It is
+// cleared once the body has been read; a body the handler leaves unread
+// keeps it, so the server gives up on discarding it at the deadline too.
+// A timeout of 0 disables the check.
+//
+// The deadline is set through the server's own ResponseWriter, taken here
+// before the Timeout middleware replaces it with a writer that can't set
+// deadlines. A writer that can't set them fails the request with an error
+// rather than leaving a deadline that can't be cleared: the server's
+// background read would then cancel the request context at the deadline.
+func bodyReadTimeoutMiddleware(timeout time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if timeout <= 0 || req.Body == nil || req.Body == http.NoBody {
+				return next(c)
+			}
+
+			body := &timedBody{
+				ReadCloser: req.Body,
+				rc:         http.NewResponseController(c.Response().Writer),
+			}
+			if err := body.setTimeout(timeout); err != nil {
+				return err
+			}
+
+			req.Body = body
+			c.Set(bodyReadTimeoutKey, body)
+			return next(c)
+		}
+	}
+}
+
+// WithBodyReadTimeout overrides BODY_READ_TIMEOUT for a single route or
+// group, e.g. to give large uploads more time. The new timeout counts from
+// when the route is reached.
+//
+// Usage:
+//
+//	e.POST("/uploads", h.Upload,
+//	    middleware.WithBodyLimit(50<<20),
+//	    middleware.WithBodyReadTimeout(2*time.Minute),
+//	)
+func WithBodyReadTimeout(timeout time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if body, ok := c.Get(bodyReadTimeoutKey).(*timedBody); ok {
+				if err := body.setTimeout(timeout); err != nil {
+					return err
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// timedBody is a request body that fails with 408 once its deadline passes.
+type timedBody struct {
+	io.ReadCloser
+	rc       *http.ResponseController // of the server's writer, not the Timeout middleware's
+	deadline time.Time
+	done     bool
+}
+
+// setTimeout moves the deadline to timeout from now.
+func (b *timedBody) setTimeout(timeout time.Duration) error {
+	b.deadline = time.Now().Add(timeout)
+	if err := b.rc.SetReadDeadline(b.deadline); err != nil {
+		return fmt.Errorf("body read timeout: set read deadline: %w", err)
+	}
+	return nil
+}
+
+// clearDeadline removes the connection read deadline. Left in place, it
+// would fail the server's own background read on the connection and cancel
+// the request context.
+func (b *timedBody) clearDeadline() {
+	if b.done {
+		return
+	}
+	b.done = true
+	if err := b.rc.SetReadDeadline(time.Time{}); err != nil {
+		logger.Error("failed to clear body read deadline", "error", err.Error())
+	}
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	if b.done {
+		return b.ReadCloser.Read(p)
+	}
+	if time.Now().After(b.deadline) {
+		return 0, echo.ErrRequestTimeout
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if errors.Is(err, io.EOF) {
+		b.clearDeadline()
+	} else if err != nil && (errors.Is(err, os.ErrDeadlineExceeded) || time.Now().After(b.deadline)) {
+		return n, echo.ErrRequestTimeout
+	}
+	return n, err
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+func TestBodyReadTimeout(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+
+	tests := []struct {
+		name string
+		// body returns the request body; stalled bodies send a byte and
+		// then nothing
+		body       func() io.Reader
+		handlerRun time.Duration // how long the handler keeps working after reading
+		// routeTimeout, if set, is applied with WithBodyReadTimeout
+		routeTimeout time.Duration
+		wantStatus   int
+	}{
+		{
+			name:       "fast body",
+			body:       func() io.Reader { return strings.NewReader("hello") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "fast body, handler outlives the read timeout",
+			body:       func() io.Reader { return strings.NewReader("hello") },
+			handlerRun: 2 * timeout,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "stalled body",
+			body: func() io.Reader {
+				r, w := io.Pipe()
+				go func() {
+					w.Write([]byte("h"))
+					time.Sleep(4 * timeout)
+					w.Close()
+				}()
+				return r
+			},
+			wantStatus: http.StatusRequestTimeout,
+		},
+		{
+			name: "slow body within a longer route timeout",
+			body: func() io.Reader {
+				r, w := io.Pipe()
+				go func() {
+					w.Write([]byte("h"))
+					time.Sleep(2 * timeout)
+					w.Close()
+				}()
+				return r
+			},
+			routeTimeout: 5 * timeout,
+			wantStatus:   http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(bodyReadTimeoutMiddleware(timeout))
+			// The Timeout middleware swaps in a writer that can't set
+			// deadlines; WithBodyReadTimeout and clearing the deadline
+			// must still reach the connection
+			e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{Timeout: 5 * time.Second}))
+			var routeMiddleware []echo.MiddlewareFunc
+			if tt.routeTimeout > 0 {
+				routeMiddleware = append(routeMiddleware, WithBodyReadTimeout(tt.routeTimeout))
+			}
+			e.POST("/", func(c echo.Context) error {
+				if _, err := io.ReadAll(c.Request().Body); err != nil {
+					return err
+				}
+				time.Sleep(tt.handlerRun)
+				if err := c.Request().Context().Err(); err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "request context: "+err.Error())
+				}
+				return c.NoContent(http.StatusOK)
+			}, routeMiddleware...)
+			srv := httptest.NewServer(e)
+			defer srv.Close()
+
+			res, err := http.Post(srv.URL, "text/plain", tt.body())
+			if err != nil {
+				t.Fatalf("POST: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.wantStatus {
+				body, _ := io.ReadAll(res.Body)
+				t.Errorf("status = %d (%s), want %d", res.StatusCode, body, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"replace-me/internal/database"
+
+	"github.com/labstack/echo/v4"
+)
+
+// requestContextMiddleware marks each request's context (see
+// database.WithRequestContext) so database.CheckQueryContexts can tell
+// queries that carry the request context from ones that were accidentally
+// given context.Background().
+func requestContextMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			c.SetRequest(req.WithContext(database.WithRequestContext(req.Context())))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"replace-me/internal/logger"
+	"replace-me/internal/reqctx"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxContextHeaderLength is the longest header value copied into the context.
+const maxContextHeaderLength = 128
+
+// contextHeadersMiddleware copies the named request headers into the request
+// context (see reqctx) and adds them to the request's log attributes (see
+// logger.WithAttrs), e.g. X-Tenant-ID is logged as "tenant_id".
+//
+// Values end up in logs, so they are validated first: only printable ASCII
+// without spaces, at most maxContextHeaderLength bytes. Invalid values are
+// dropped rather than cleaned up, so a forged value with a newline can't
+// inject fake log lines.
+func contextHeadersMiddleware(names []string) echo.MiddlewareFunc {
+	canonical := make([]string, len(names))
+	for i, name := range names {
+		canonical[i] = http.CanonicalHeaderKey(name)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			values := make(map[string]string, len(canonical))
+			var attrs []any
+			for _, name := range canonical {
+				value := req.Header.Get(name)
+				if value == "" {
+					continue
+				}
+				if !validContextHeader(value) {
+					logger.Debug("ignoring invalid context header",
+						"header", name,
+						"request_id", c.Response().Header().Get(echo.HeaderXRequestID),
+					)
+					continue
+				}
+				values[name] = value
+				attrs = append(attrs, contextHeaderLogKey(name), value)
+			}
+
+			if len(values) > 0 {
+				ctx := reqctx.WithHeaders(req.Context(), values)
+				ctx = logger.WithAttrs(ctx, attrs...)
+				c.SetRequest(req.WithContext(ctx))
+			}
+			return next(c)
+		}
+	}
+}
+
+// validContextHeader reports whether value is safe to store and log.
+func validContextHeader(value string) bool {
+	if len(value) > maxContextHeaderLength {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < 0x21 || value[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// contextHeaderLogKey turns a header name into a log attribute key:
+// "X-Tenant-ID" → "tenant_id".
+func contextHeaderLogKey(name string) string {
+	name = strings.TrimPrefix(http.CanonicalHeaderKey(name), "X-")
+	return strings.ReplaceAll(strings.ToLower(name), "-", "_")
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"replace-me/internal/logger"
+	"replace-me/internal/reqctx"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestContextHeadersMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantTenant string
+		wantRole   string
+		wantAttrs  []any
+	}{
+		{
+			name:       "listed headers",
+			headers:    map[string]string{"X-Tenant-ID": "acme", "X-User-Role": "admin"},
+			wantTenant: "acme",
+			wantRole:   "admin",
+			wantAttrs:  []any{"tenant_id", "acme", "user_role", "admin"},
+		},
+		{
+			name:      "unlisted header",
+			headers:   map[string]string{"X-Region": "eu"},
+			wantAttrs: nil,
+		},
+		{
+			name:      "newline is dropped",
+			headers:   map[string]string{"X-Tenant-ID": "acme\nlevel=ERROR msg=forged"},
+			wantAttrs: nil,
+		},
+		{
+			name:      "too long is dropped",
+			headers:   map[string]string{"X-Tenant-ID": strings.Repeat("a", maxContextHeaderLength+1)},
+			wantAttrs: nil,
+		},
+		{
+			name:      "invalid value does not drop the valid one",
+			headers:   map[string]string{"X-Tenant-ID": "has space", "X-User-Role": "viewer"},
+			wantRole:  "viewer",
+			wantAttrs: []any{"user_role", "viewer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			var tenant, role string
+			var attrs []any
+			handler := contextHeadersMiddleware([]string{"x-tenant-id", "X-User-Role"})(func(c echo.Context) error {
+				ctx := c.Request().Context()
+				tenant, role, attrs = reqctx.TenantID(ctx), reqctx.UserRole(ctx), logger.Attrs(ctx)
+				return nil
+			})
+			if err := handler(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if tenant != tt.wantTenant || role != tt.wantRole {
+				t.Errorf("tenant, role = %q, %q, want %q, %q", tenant, role, tt.wantTenant, tt.wantRole)
+			}
+			if !reflect.DeepEqual(attrs, tt.wantAttrs) {
+				t.Errorf("log attrs = %v, want %v", attrs, tt.wantAttrs)
+			}
+		})
+	}
+}
+
+func TestContextHeaderLogKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"X-Tenant-ID", "tenant_id"},
+		{"x-user-role", "user_role"},
+		{"Tenant", "tenant"},
+	}
+	for _, tt := range tests {
+		if got := contextHeaderLogKey(tt.name); got != tt.want {
+			t.Errorf("contextHeaderLogKey(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
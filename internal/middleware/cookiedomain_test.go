@@ -0,0 +1,27 @@
+package middleware
+
+import "testing"
+
+func TestCookieDomainMatches(t *testing.T) {
+	tests := []struct {
+		host   string
+		domain string
+		want   bool
+	}{
+		{"example.com", "example.com", true},
+		{"app.example.com", ".example.com", true},
+		{"app.example.com", "example.com", true},
+		{"APP.Example.com", "example.COM", true},
+		{"app.example.com:8080", ".example.com", true},
+		{"localhost:8080", "localhost", true},
+		{"example.org", "example.com", false},
+		{"badexample.com", "example.com", false},
+		{"example.com", "app.example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := cookieDomainMatches(tt.host, tt.domain); got != tt.want {
+			t.Errorf("cookieDomainMatches(%q, %q) = %v, want %v", tt.host, tt.domain, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMatchedCORSOrigin(t *testing.T) {
+	allowed := []string{"https://app.example.com", "https://*.preview.example.com", "http://localhost:300?"}
+
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{"https://app.example.com", "https://app.example.com"},
+		{"https://pr-12.preview.example.com", "https://*.preview.example.com"},
+		{"http://localhost:3001", "http://localhost:300?"},
+		{"http://localhost:30011", ""},
+		{"https://evil.com", ""},
+		{"https://app.example.com.evil.com", ""},
+	}
+
+	for _, tt := range tests {
+		if got := matchedCORSOrigin(tt.origin, allowed); got != tt.want {
+			t.Errorf("matchedCORSOrigin(%q) = %q, want %q", tt.origin, got, tt.want)
+		}
+	}
+
+	if got := matchedCORSOrigin("https://any.example", []string{"*"}); got != "*" {
+		t.Errorf("matchedCORSOrigin with * = %q, want *", got)
+	}
+}
+
+func TestCORSDebugKeepsDecision(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		cfg := &config.Config{
+			CORSAllowedOrigins: []string{"https://app.example.com"},
+			CORSDebug:          debug,
+		}
+		e := echo.New()
+		e.Use(corsMiddleware(cfg))
+		e.GET("/", func(c echo.Context) error {
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		for origin, want := range map[string]string{
+			"https://app.example.com": "https://app.example.com",
+			"https://evil.com":        "",
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderOrigin, origin)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != want {
+				t.Errorf("debug=%v origin %q: Access-Control-Allow-Origin = %q, want %q", debug, origin, got, want)
+			}
+		}
+	}
+}
+
+func TestNormalizeOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{"HTTP://LocalHost:80", "http://localhost"},
+		{"https://example.com:443", "https://example.com"},
+		{"http://localhost:3000", "http://localhost:3000"},
+		{"https://example.com:80", "https://example.com:80"},
+		{" https://Example.com ", "https://example.com"},
+		{"null", "null"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeOrigin(tt.origin); got != tt.want {
+			t.Errorf("normalizeOrigin(%q) = %q, want %q", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCORSNormalizedOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    string
+	}{
+		{name: "default port in config", allowed: []string{"http://localhost:80"}, origin: "http://localhost", want: "http://localhost"},
+		{name: "mixed case in config", allowed: []string{"https://App.Example.com"}, origin: "https://app.example.com", want: "https://app.example.com"},
+		{name: "different port", allowed: []string{"http://localhost:3000"}, origin: "http://localhost:3001", want: ""},
+		{name: "wildcard", allowed: []string{"*"}, origin: "https://any.example", want: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(corsMiddleware(&config.Config{CORSAllowedOrigins: tt.allowed}))
+			e.GET("/", func(c echo.Context) error {
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderOrigin, tt.origin)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != tt.want {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"fmt"
+
+	"replace-me/internal/csp"
+
+	"github.com/labstack/echo/v4"
+)
+
+// cspMiddleware sends policy as the Content-Security-Policy header, with a
+// fresh nonce substituted for "{nonce}" on every request. The nonce is also
+// stored in the request context so templates can add it to inline scripts
+// and styles (see csp.Nonce).
+func cspMiddleware(policy string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			nonce, err := csp.NewNonce()
+			if err != nil {
+				return fmt.Errorf("generate csp nonce: %w", err)
+			}
+
+			c.Response().Header().Set(echo.HeaderContentSecurityPolicy, csp.Policy(policy, nonce))
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(csp.WithNonce(req.Context(), nonce)))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"replace-me/internal/csp"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCSPMiddleware(t *testing.T) {
+	const policy = "script-src 'self' 'nonce-{nonce}'; style-src 'nonce-{nonce}'"
+	handler := cspMiddleware(policy)(func(c echo.Context) error {
+		return c.String(http.StatusOK, csp.Nonce(c.Request().Context()))
+	})
+
+	serve := func() (header, nonce string) {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		if err := handler(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Header().Get(echo.HeaderContentSecurityPolicy), rec.Body.String()
+	}
+
+	header, nonce := serve()
+	if nonce == "" {
+		t.Fatal("handler saw no nonce in the request context")
+	}
+	if want := "script-src 'self' 'nonce-" + nonce + "'; style-src 'nonce-" + nonce + "'"; header != want {
+		t.Errorf("Content-Security-Policy = %q, want %q", header, want)
+	}
+	if strings.Contains(header, csp.NoncePlaceholder) {
+		t.Errorf("Content-Security-Policy %q still contains the placeholder", header)
+	}
+
+	if _, second := serve(); second == nonce {
+		t.Errorf("two requests got the same nonce %q", nonce)
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"time"
+
+	"replace-me/internal/database"
+
+	"github.com/labstack/echo/v4"
+	"github.com/uptrace/bun"
+)
+
+// requestDB is the database registered with SetRequestDB, or nil.
+var requestDB *bun.DB
+
+// SetRequestDB makes every request take a connection from db's pool before
+// it reaches the session middleware and the handler, waiting at most
+// DB_ACQUIRE_TIMEOUT (see database.Acquire). When the pool stays exhausted
+// that long the request is answered with 503 and Retry-After instead of
+// queueing until it times out. The connection is stored in the request
+// context, where database.DB and database.RunInTx use it, and returned to
+// the pool when the request ends.
+//
+// Static files, health probes and metrics don't take one (see
+// skipsRequestConn). Call it once during startup, before the server starts
+// accepting requests:
+//
+//	middleware.SetRequestDB(db)
+func SetRequestDB(db *bun.DB) {
+	requestDB = db
+}
+
+// requestConnMiddleware takes the request's connection from the database
+// registered with SetRequestDB, if any. It is looked up per request, so
+// SetRequestDB may run after Setup.
+func requestConnMiddleware(timeout time.Duration, metricsPath string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			db := requestDB
+			if db == nil || skipsRequestConn(c.Path(), metricsPath) {
+				return next(c)
+			}
+
+			req := c.Request()
+			conn, err := database.Acquire(req.Context(), db, timeout)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			c.SetRequest(req.WithContext(database.WithConn(req.Context(), db, conn)))
+			return next(c)
+		}
+	}
+}
+
+// skipsRequestConn reports whether requests to the route path go without a
+// connection: static files never query, and probes and metrics must keep
+// answering while the pool is exhausted.
+func skipsRequestConn(path, metricsPath string) bool {
+	switch path {
+	case "/static/*", "/health", "/readyz":
+		return true
+	default:
+		return path != "" && path == metricsPath
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"replace-me/internal/config"
+	"replace-me/internal/database"
+	"replace-me/internal/database/dbtest"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequestConn(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		saturated bool
+		wantCode  int
+	}{
+		{name: "free pool", path: "/books", wantCode: http.StatusOK},
+		{name: "exhausted pool", path: "/books", saturated: true, wantCode: http.StatusServiceUnavailable},
+		{name: "health probe on an exhausted pool", path: "/health", saturated: true, wantCode: http.StatusOK},
+		{name: "metrics on an exhausted pool", path: "/metrics", saturated: true, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &dbtest.Server{}
+			db := dbtest.Open(t, srv)
+			db.SetMaxOpenConns(1)
+			SetRequestDB(db)
+			t.Cleanup(func() {
+				requestDB = nil
+				defaultSession = nil
+			})
+
+			if tt.saturated {
+				held, err := db.Conn(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer held.Close()
+			}
+
+			e := echo.New()
+			Setup(e, &config.Config{
+				Environment:       "development",
+				SessionSecret:     "test-secret-0123456789abcdef0123456789",
+				SessionCookiePath: "/",
+				RequestTimeout:    time.Second,
+				DBAcquireTimeout:  20 * time.Millisecond,
+				MetricsPath:       "/metrics",
+			})
+			e.GET("/books", func(c echo.Context) error {
+				ctx := c.Request().Context()
+				// Runs on the request's connection: with one connection in
+				// the pool, taking another would wait out the timeout
+				if _, err := database.DB(ctx, db).NewSelect().TableExpr("books").Column("id").Exec(ctx); err != nil {
+					return err
+				}
+				return c.NoContent(http.StatusOK)
+			})
+			for _, path := range []string{"/health", "/metrics"} {
+				e.GET(path, func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			wantRetryAfter := ""
+			if tt.wantCode == http.StatusServiceUnavailable {
+				wantRetryAfter = poolExhaustedRetryAfter
+			}
+			if got := rec.Header().Get("Retry-After"); got != wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, wantRetryAfter)
+			}
+		})
+	}
+}
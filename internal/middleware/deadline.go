@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Header names used to coordinate request timeouts with callers.
+const (
+	// HeaderRequestTimeout lets a caller ask for a shorter deadline, in
+	// milliseconds, e.g. "X-Request-Timeout: 2000".
+	HeaderRequestTimeout = "X-Request-Timeout"
+
+	// HeaderTimeoutMs tells the caller the deadline the server applied to
+	// the request, in milliseconds.
+	HeaderTimeoutMs = "X-Timeout-Ms"
+)
+
+// minRequestTimeout is the shortest deadline a caller can ask for. Anything
+// lower is raised to it, so a bogus header can't make every request fail.
+const minRequestTimeout = 100 * time.Millisecond
+
+// deadlineMiddleware advertises the request timeout in the X-Timeout-Ms
+// response header, so callers can size their own timeouts to match.
+//
+// A caller that will give up sooner can send X-Request-Timeout (milliseconds)
+// to bound the work done on its behalf: the request context's deadline is
+// shortened accordingly, and handlers passing that context to the database
+// or other services stop when the caller has stopped waiting. The header can
+// only shorten the deadline, never extend it past timeout. Invalid values are
+// ignored.
+//
+// It must run before the Timeout middleware, which then responds with 503
+// when the shortened deadline passes, just as it does for the default one.
+func deadlineMiddleware(timeout time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			effective := timeout
+			if requested, ok := requestedTimeout(c.Request().Header.Get(HeaderRequestTimeout)); ok {
+				effective = min(max(requested, minRequestTimeout), timeout)
+			}
+
+			c.Response().Header().Set(HeaderTimeoutMs, strconv.FormatInt(effective.Milliseconds(), 10))
+
+			if effective < timeout {
+				req := c.Request()
+				ctx, cancel := context.WithTimeout(req.Context(), effective)
+				defer cancel()
+				c.SetRequest(req.WithContext(ctx))
+			}
+			return next(c)
+		}
+	}
+}
+
+// requestedTimeout parses an X-Request-Timeout value in milliseconds.
+func requestedTimeout(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
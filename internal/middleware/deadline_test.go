@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestDeadlineMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		wantHeader   string
+		wantDeadline time.Duration // 0 means the context has no deadline of its own
+	}{
+		{name: "no header", wantHeader: "30000"},
+		{name: "shorter", header: "2000", wantHeader: "2000", wantDeadline: 2 * time.Second},
+		{name: "longer is capped", header: "60000", wantHeader: "30000"},
+		{name: "below minimum is raised", header: "1", wantHeader: "100", wantDeadline: minRequestTimeout},
+		{name: "not a number", header: "soon", wantHeader: "30000"},
+		{name: "negative", header: "-5", wantHeader: "30000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderRequestTimeout, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			var deadline time.Time
+			var hasDeadline bool
+			handler := deadlineMiddleware(30 * time.Second)(func(c echo.Context) error {
+				deadline, hasDeadline = c.Request().Context().Deadline()
+				return nil
+			})
+			start := time.Now()
+			if err := handler(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := rec.Header().Get(HeaderTimeoutMs); got != tt.wantHeader {
+				t.Errorf("%s = %q, want %q", HeaderTimeoutMs, got, tt.wantHeader)
+			}
+			if tt.wantDeadline == 0 {
+				if hasDeadline {
+					t.Errorf("context has a deadline, want the Timeout middleware's alone")
+				}
+				return
+			}
+			if !hasDeadline {
+				t.Fatal("context has no deadline")
+			}
+			if remaining := deadline.Sub(start); (remaining - tt.wantDeadline).Abs() > 50*time.Millisecond {
+				t.Errorf("deadline in %v, want about %v", remaining, tt.wantDeadline)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Deprecated returns a middleware that marks every response as deprecated
+// using the standard headers, so API clients and tooling can warn about it:
+//   - Deprecation: when the routes were deprecated (RFC 9745)
+//   - Sunset: when the routes will stop working, if set (RFC 8594)
+//   - Link: documentation for migrating away, if set (rel="deprecation")
+//
+// Usage:
+//
+//	v1 := e.Group("/api/v1", middleware.Deprecated(
+//	    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), // deprecated since
+//	    time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), // removed on
+//	    "https://example.com/docs/api/v2-migration",
+//	))
+func Deprecated(deprecatedAt, sunset time.Time, link string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			if deprecatedAt.IsZero() {
+				header.Set("Deprecation", "true")
+			} else {
+				header.Set("Deprecation", fmt.Sprintf("@%d", deprecatedAt.Unix()))
+			}
+			if !sunset.IsZero() {
+				header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			if link != "" {
+				header.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, link))
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestErrorFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		api     bool
+		want    string
+	}{
+		{name: "browser", headers: map[string]string{"Accept": "text/html"}, want: errorFormatPage},
+		{name: "JSON client", headers: map[string]string{"Accept": "application/json"}, want: errorFormatJSON},
+		{name: "JSON body", headers: map[string]string{"Content-Type": "application/json"}, want: errorFormatJSON},
+		{name: "HTMX", headers: map[string]string{"HX-Request": "true"}, want: errorFormatHTMX},
+		{name: "API route", headers: map[string]string{"Accept": "text/html"}, api: true, want: errorFormatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			if tt.api {
+				c.Set(apiContextKey, true)
+			}
+
+			if got := errorFormat(c); got != tt.want {
+				t.Errorf("errorFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomErrorHandlerOutput(t *testing.T) {
+	const message = `<img src=x onerror=alert(1)>`
+	handle := customErrorHandler(&config.Config{})
+
+	serve := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Response().Header().Set(echo.HeaderXRequestID, "req-1")
+		handle(echo.NewHTTPError(http.StatusConflict, message), c)
+		return rec
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		rec := serve(map[string]string{"Accept": "application/json"})
+
+		var body struct {
+			Error     string `json:"error"`
+			Code      int    `json:"code"`
+			RequestID string `json:"request_id"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+		}
+		if rec.Code != http.StatusConflict || body.Code != http.StatusConflict || body.Error != message || body.RequestID != "req-1" {
+			t.Errorf("got %d %+v, want %d with the message and request ID", rec.Code, body, http.StatusConflict)
+		}
+	})
+
+	for name, headers := range map[string]map[string]string{
+		"HTMX": {"HX-Request": "true"},
+		"page": {"Accept": "text/html"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := serve(headers)
+
+			if rec.Code != http.StatusConflict {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+			}
+			if body := rec.Body.String(); strings.Contains(body, "<img") || !strings.Contains(body, "&lt;img") {
+				t.Errorf("message not escaped in body:\n%s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"replace-me/internal/config"
+	"replace-me/internal/database"
+	"replace-me/internal/errorreport"
+	"replace-me/internal/logger"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+// Error response formats chosen by errorFormat.
+const (
+	errorFormatJSON = "json"
+	errorFormatHTMX = "htmx"
+	errorFormatPage = "page"
+)
+
+// StatusClientClosedRequest is the status recorded for requests the client
+// abandoned before the response was ready (nginx's 499). It only appears in
+// logs and metrics: the client is gone, so nothing is sent.
+const StatusClientClosedRequest = 499
+
+// poolExhaustedRetryAfter is the Retry-After value (in seconds) sent with
+// 503 responses caused by database.ErrPoolExhausted. Connections are held
+// for the length of a request, so one frees up quickly.
+const poolExhaustedRetryAfter = "1"
+
+// ServerErrorPage builds the page browsers see for 5xx responses (see
+// SetServerErrorPage). message is the user-facing message from errorStatus.
+type ServerErrorPage func(code int, message, requestID string) templ.Component
+
+// serverErrorPage is the registered 5xx page; nil means the generic page.
+var serverErrorPage ServerErrorPage
+
+// SetServerErrorPage registers the page rendered for 5xx browser responses.
+// The templates import this package, so the page is plugged in from main
+// rather than imported here. 4xx errors keep the generic error page.
+//
+// Usage:
+//
+//	middleware.SetServerErrorPage(func(code int, message, requestID string) templ.Component {
+//	    return pages.ServerError(code, message, requestID, cfg.SupportEmail)
+//	})
+func SetServerErrorPage(page ServerErrorPage) {
+	serverErrorPage = page
+}
+
+// customErrorHandler returns an error handler that renders pretty error pages.
+// In development, it shows detailed error information.
+// In production, it shows user-friendly messages without technical details.
+//
+// The work is split into small functions so each step can be exercised on
+// its own with an echo.Context built from httptest:
+//   - errorStatus: error → status code and user-facing message
+//   - errorFormat: request → "json", "htmx", or "page"
+//   - writeJSONError / writeHTMXError / writePageError: render each format
+//
+// Browsers get pages.ServerError for 5xx responses once it is registered
+// with SetServerErrorPage, and the generic error page for everything else.
+func customErrorHandler(cfg *config.Config) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		// Nothing can be written once the response is committed, e.g. when
+		// a streaming handler fails or panics midway, but a server error
+		// must still be reported
+		if c.Response().Committed {
+			reportCommittedError(c, err)
+			return
+		}
+
+		code, message := errorStatus(err, cfg.IsDevelopment())
+		if errors.Is(err, database.ErrPoolExhausted) {
+			c.Response().Header().Set("Retry-After", poolExhaustedRetryAfter)
+		}
+
+		// Log the error with context
+		requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+		switch {
+		case clientGone(c):
+			code = StatusClientClosedRequest
+			// The client disconnected: not a server error, and nobody is
+			// left to read a response body. Only the status is recorded.
+			// A context cancelled while the client is still connected is
+			// an ordinary error and gets a response below.
+			logger.DebugContext(c.Request().Context(), "client closed request",
+				"error", err.Error(),
+				"request_id", requestID,
+				"path", c.Request().URL.Path,
+			)
+			c.Response().WriteHeader(code)
+			return
+		case code == http.StatusGatewayTimeout:
+			// The server ran out of time. Worth a warning, but it is load
+			// or a slow dependency rather than a bug to report.
+			logger.WarnContext(c.Request().Context(), "request timed out",
+				"error", err.Error(),
+				"request_id", requestID,
+				"path", c.Request().URL.Path,
+			)
+		case code >= 500:
+			logger.ErrorContext(c.Request().Context(), "http error",
+				"code", code,
+				"error", err.Error(),
+				"request_id", requestID,
+				"path", c.Request().URL.Path,
+			)
+
+			// Report server errors to the error tracker. Panics recovered by
+			// recoverMiddleware arrive here as 500s too.
+			errorreport.Capture(err, errorReportFields(c, requestID))
+		}
+
+		var writeErr error
+		switch errorFormat(c) {
+		case errorFormatJSON:
+			writeErr = writeJSONError(c, code, message, requestID)
+		case errorFormatHTMX:
+			writeErr = writeHTMXError(c, code, message)
+		default:
+			writeErr = writePageError(c, code, message, requestID, cfg.IsDevelopment())
+		}
+		if writeErr != nil {
+			logger.Error("failed to write error response", "error", writeErr.Error(), "request_id", requestID)
+		}
+	}
+}
+
+// reportCommittedError logs and reports a server error that happened after
+// the response was committed. Client disconnects and timeouts are left out,
+// as they are for uncommitted responses.
+func reportCommittedError(c echo.Context, err error) {
+	code, _ := errorStatus(err, false)
+	if code < 500 || code == http.StatusGatewayTimeout || clientGone(c) {
+		return
+	}
+	requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+	logger.ErrorContext(c.Request().Context(), "http error after response was committed",
+		"code", code,
+		"error", err.Error(),
+		"request_id", requestID,
+		"path", c.Request().URL.Path,
+	)
+	errorreport.Capture(err, errorReportFields(c, requestID))
+}
+
+// clientContextKey is the Echo context key holding the request's original
+// context (see trackClientMiddleware).
+const clientContextKey = "client_context"
+
+// trackClientMiddleware remembers the request's original context for
+// clientGone. It must run before Deadline and Timeout, which replace the
+// request context with ones they cancel when they return.
+func trackClientMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(clientContextKey, c.Request().Context())
+			return next(c)
+		}
+	}
+}
+
+// clientGone reports whether the client disconnected before the response
+// was finished: net/http cancels the original request context when the
+// connection closes.
+func clientGone(c echo.Context) bool {
+	ctx, ok := c.Get(clientContextKey).(context.Context)
+	return ok && errors.Is(ctx.Err(), context.Canceled)
+}
+
+// errorReportFields collects the request context attached to error reports.
+func errorReportFields(c echo.Context, requestID string) errorreport.Fields {
+	fields := errorreport.Fields{
+		RequestID: requestID,
+		Path:      c.Request().URL.Path,
+	}
+	if session := GetSession(c); session != nil {
+		if userID, ok := session.Values[SessionUserIDKey]; ok {
+			fields.UserID = fmt.Sprintf("%v", userID)
+		}
+	}
+	return fields
+}
+
+// errorStatus extracts the HTTP status code and the message shown to the user.
+// Messages of *echo.HTTPError are always shown; other errors only reveal
+// their text in development. Timeouts (context.DeadlineExceeded, cancelled
+// database queries, see database.TranslateError) map to 504 Gateway
+// Timeout, an exhausted connection pool (see database.Acquire) to 503
+// Service Unavailable, and bodies over the route's size limit to 413 even
+// when a binder wrapped the error in a 400. Repository errors map to 404
+// (database.ErrNotFound) and 400 (database.ErrInvalidSort).
+//
+// A context.Canceled error is a 500 like any other: it only becomes
+// StatusClientClosedRequest when the client is gone (see clientGone), which
+// the caller checks.
+func errorStatus(err error, isDevelopment bool) (int, string) {
+	code := http.StatusInternalServerError
+	message := "Internal Server Error"
+
+	var he *echo.HTTPError
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, database.ErrQueryCanceled) {
+		// The request ran out of time, usually while waiting on the database
+		code = http.StatusGatewayTimeout
+		message = "The request took too long to complete"
+	} else if errors.Is(err, database.ErrPoolExhausted) {
+		// Shedding load: the client should come back shortly
+		code = http.StatusServiceUnavailable
+		message = "The server is busy, please try again shortly"
+	} else if errors.Is(err, echo.ErrStatusRequestEntityTooLarge) {
+		code = http.StatusRequestEntityTooLarge
+		message = "Request body too large"
+	} else if errors.Is(err, echo.ErrRequestTimeout) {
+		// Checked before errors.As: Bind wraps read errors in a 400
+		code = http.StatusRequestTimeout
+		message = "The request body was not received in time"
+	} else if errors.Is(err, database.ErrNotFound) {
+		code = http.StatusNotFound
+		message = "Not Found"
+	} else if errors.Is(err, database.ErrInvalidSort) {
+		code = http.StatusBadRequest
+		message = "Invalid sort parameter"
+	} else if errors.As(err, &he) {
+		code = he.Code
+		if he.Message != nil {
+			message = fmt.Sprintf("%v", he.Message)
+		}
+	} else if isDevelopment {
+		// In development, show the actual error
+		message = err.Error()
+	}
+
+	return code, message
+}
+
+// errorFormat decides how an error response should be rendered for this request.
+func errorFormat(c echo.Context) string {
+	req := c.Request()
+
+	// API routes, clients that rank JSON above HTML in their Accept header,
+	// and requests that sent JSON. Without a preference (no Accept header,
+	// or */*) the error stays HTML.
+	if IsAPI(c) ||
+		PreferredMediaType(req, echo.MIMETextHTML, echo.MIMEApplicationJSON) == echo.MIMEApplicationJSON ||
+		strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return errorFormatJSON
+	}
+
+	// HTMX requests get a partial HTML error
+	if req.Header.Get("HX-Request") == "true" {
+		return errorFormatHTMX
+	}
+
+	// Browsers get a full error page
+	return errorFormatPage
+}
+
+// writeJSONError writes the error as a JSON object.
+func writeJSONError(c echo.Context, code int, message, requestID string) error {
+	return c.JSON(code, map[string]any{
+		"error":      message,
+		"code":       code,
+		"request_id": requestID,
+	})
+}
+
+// writeHTMXError writes the error as an HTML fragment for HTMX to swap in.
+func writeHTMXError(c echo.Context, code int, message string) error {
+	return c.HTML(code, fmt.Sprintf(`
+				<div class="bg-red-100 border border-red-400 text-red-700 px-4 py-3 rounded mb-4" role="alert">
+					<strong class="font-bold">Error %d</strong>
+					<span class="block sm:inline">%s</span>
+				</div>
+			`, code, html.EscapeString(message)))
+}
+
+// writePageError writes a full HTML error page: the registered server error
+// page for 5xx responses, the generic page otherwise. If the server error
+// page itself fails to render, the generic page is used instead.
+func writePageError(c echo.Context, code int, message, requestID string, isDevelopment bool) error {
+	if code >= 500 && serverErrorPage != nil {
+		// The request context may already be cancelled by the Timeout
+		// middleware, which would make templ render nothing.
+		ctx := context.WithoutCancel(c.Request().Context())
+		page, err := renderServerErrorPage(ctx, code, message, requestID)
+		if err == nil {
+			return c.HTMLBlob(code, page)
+		}
+		logger.Error("failed to render server error page", "error", err.Error(), "request_id", requestID)
+	}
+	return c.HTML(code, renderErrorPage(code, message, requestID, isDevelopment))
+}
+
+// renderServerErrorPage renders the registered server error page into
+// memory, so a failure (or panic) leaves the response untouched.
+func renderServerErrorPage(ctx context.Context, code int, message, requestID string) (page []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("template panic: %v", r)
+		}
+	}()
+
+	var buf bytes.Buffer
+	if err := serverErrorPage(code, message, requestID).Render(ctx, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderErrorPage generates an HTML error page.
+// The page styling matches the application's design.
+// The message and request ID are HTML-escaped.
+func renderErrorPage(code int, message, requestID string, isDevelopment bool) string {
+	title := http.StatusText(code)
+	if title == "" {
+		title = "Error"
+	}
+
+	// Additional debug info for development
+	debugInfo := ""
+	if isDevelopment && requestID != "" {
+		debugInfo = fmt.Sprintf(`
+			<p class="text-sm text-gray-500 mt-4">Request ID: %s</p>
+		`, html.EscapeString(requestID))
+	}
+
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="UTF-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1.0">
+	<title>%d %s - Go Fullstack Starter</title>
+	<link href="/static/css/output.css" rel="stylesheet">
+</head>
+<body class="min-h-screen bg-gray-100 flex items-center justify-center">
+	<div class="text-center p-8">
+		<h1 class="text-6xl font-bold text-gray-800 mb-4">%d</h1>
+		<h2 class="text-2xl font-semibold text-gray-600 mb-4">%s</h2>
+		<p class="text-gray-500 mb-8">%s</p>
+		<a href="/" class="inline-block bg-gray-800 text-white px-6 py-3 rounded hover:bg-gray-700 transition-colors">
+			Go Home
+		</a>
+		%s
+	</div>
+</body>
+</html>
+`, code, title, code, title, html.EscapeString(message), debugInfo)
+}
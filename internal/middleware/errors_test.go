@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"replace-me/internal/config"
+	"replace-me/internal/database"
+	"replace-me/internal/errorreport"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestErrorStatus(t *testing.T) {
+	errInternal := errors.New("connection reset by peer")
+
+	tests := []struct {
+		name          string
+		err           error
+		isDevelopment bool
+		wantCode      int
+		wantMessage   string
+	}{
+		{name: "plain error", err: errInternal, wantCode: http.StatusInternalServerError, wantMessage: "Internal Server Error"},
+		{name: "plain error in development", err: errInternal, isDevelopment: true, wantCode: http.StatusInternalServerError, wantMessage: errInternal.Error()},
+		{name: "HTTP error", err: echo.NewHTTPError(http.StatusForbidden, "No access"), wantCode: http.StatusForbidden, wantMessage: "No access"},
+		{name: "cancelled context", err: fmt.Errorf("query: %w", context.Canceled), wantCode: http.StatusInternalServerError, wantMessage: "Internal Server Error"},
+		{name: "deadline exceeded", err: fmt.Errorf("query: %w", context.DeadlineExceeded), wantCode: http.StatusGatewayTimeout, wantMessage: "The request took too long to complete"},
+		{name: "cancelled query", err: database.ErrQueryCanceled, wantCode: http.StatusGatewayTimeout, wantMessage: "The request took too long to complete"},
+		{name: "pool exhausted", err: database.ErrPoolExhausted, wantCode: http.StatusServiceUnavailable, wantMessage: "The server is busy, please try again shortly"},
+		{name: "body too large inside a bind error", err: echo.NewHTTPError(http.StatusBadRequest).SetInternal(echo.ErrStatusRequestEntityTooLarge), wantCode: http.StatusRequestEntityTooLarge, wantMessage: "Request body too large"},
+		{name: "body read timeout", err: echo.ErrRequestTimeout, wantCode: http.StatusRequestTimeout, wantMessage: "The request body was not received in time"},
+		{name: "repository row not found", err: fmt.Errorf("load book: %w", database.ErrNotFound), wantCode: http.StatusNotFound, wantMessage: "Not Found"},
+		{name: "repository sort rejected", err: fmt.Errorf("%w: %q", database.ErrInvalidSort, "id"), wantCode: http.StatusBadRequest, wantMessage: "Invalid sort parameter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, message := errorStatus(tt.err, tt.isDevelopment)
+			if code != tt.wantCode || message != tt.wantMessage {
+				t.Errorf("errorStatus = %d %q, want %d %q", code, message, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestCancelledRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		clientGone bool
+		wantStatus int
+		wantBody   bool
+	}{
+		{name: "client disconnected", clientGone: true, wantStatus: StatusClientClosedRequest},
+		{name: "cancelled internally, client still connected", wantStatus: http.StatusInternalServerError, wantBody: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = customErrorHandler(&config.Config{})
+			e.Use(trackClientMiddleware())
+			e.GET("/", func(c echo.Context) error {
+				return fmt.Errorf("load page: %w", context.Canceled)
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.clientGone {
+				// net/http cancels the request context when the
+				// connection closes
+				cancel()
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if gotBody := rec.Body.Len() > 0; gotBody != tt.wantBody {
+				t.Errorf("wrote a body = %v, want %v", gotBody, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCommittedErrorsAreReported(t *testing.T) {
+	var reports atomic.Int32
+	tracker := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		reports.Add(1)
+	}))
+	defer tracker.Close()
+	errorreport.Init(tracker.URL, time.Minute)
+	t.Cleanup(func() { errorreport.Init("", 0) })
+
+	tests := []struct {
+		name        string
+		handler     echo.HandlerFunc
+		wantReports int32
+	}{
+		{
+			name: "panic after the response was committed",
+			handler: func(c echo.Context) error {
+				c.Response().WriteHeader(http.StatusOK)
+				panic("midway through the stream")
+			},
+			wantReports: 1,
+		},
+		{
+			name: "error after the response was committed",
+			handler: func(c echo.Context) error {
+				c.Response().WriteHeader(http.StatusOK)
+				return errors.New("export failed")
+			},
+			wantReports: 1,
+		},
+		{
+			name: "client error after the response was committed",
+			handler: func(c echo.Context) error {
+				c.Response().WriteHeader(http.StatusOK)
+				return echo.ErrBadRequest
+			},
+		},
+		{
+			name: "timeout after the response was committed",
+			handler: func(c echo.Context) error {
+				c.Response().WriteHeader(http.StatusOK)
+				return context.DeadlineExceeded
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports.Store(0)
+			e := echo.New()
+			e.HTTPErrorHandler = customErrorHandler(&config.Config{})
+			e.Use(recoverMiddleware())
+			// Each case reports a distinct error, so deduplication can't
+			// hide a report
+			e.GET("/", func(c echo.Context) error {
+				return tt.handler(c)
+			})
+
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			if err := errorreport.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := reports.Load(); got != tt.wantReports {
+				t.Errorf("sent %d reports, want %d", got, tt.wantReports)
+			}
+		})
+	}
+}
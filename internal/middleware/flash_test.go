@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+)
+
+func TestFlashExpiry(t *testing.T) {
+	tests := []struct {
+		name string
+		// staleFor is how long ago an earlier, unread flash was added;
+		// 0 means there is none
+		staleFor time.Duration
+		// readAfter is how long after AddFlash the flashes are read
+		readAfter time.Duration
+		want      int
+	}{
+		{name: "fresh flash", want: 1},
+		{name: "flash left unread too long", readAfter: flashMaxAge, want: 0},
+		{name: "new flash keeps an old one alive", staleFor: flashMaxAge - time.Minute, readAfter: 2 * time.Minute, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := sessions.NewSession(nil, SessionName)
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+			c.Set(sessionContextKey(SessionName), session)
+
+			if tt.staleFor > 0 {
+				session.AddFlash("earlier", FlashInfo)
+				session.Values[flashAddedKey] = time.Now().Add(-tt.staleFor).Unix()
+			}
+			AddFlash(c, FlashSuccess, "saved")
+
+			pruneStaleFlashes(session, time.Now().Add(tt.readAfter))
+			if got := len(GetFlashes(c)); got != tt.want {
+				t.Errorf("got %d flashes, want %d", got, tt.want)
+			}
+			if _, ok := session.Values[flashAddedKey]; ok {
+				t.Error("flash timestamp left after reading the flashes")
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// alreadyCompressedTypes are never gzipped, whatever GZIP_CONTENT_TYPES says:
+// compressing them again wastes CPU and usually makes them bigger.
+var alreadyCompressedTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"image/avif",
+	"video/*",
+	"audio/*",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+}
+
+// gzipContentTypes returns a middleware that limits Echo's Gzip middleware to
+// responses whose Content-Type matches one of types. It must be registered
+// directly after middleware.Gzip().
+//
+// Echo's Gzip compresses every response. This middleware looks at the
+// Content-Type when the response starts and, for types that shouldn't be
+// compressed, routes the response around the gzip writer.
+//
+// Types may use a "*" subtype wildcard, e.g. "text/*".
+//
+// Caching: Echo's Gzip adds "Vary: Accept-Encoding" to every response, so
+// shared caches keep compressed and uncompressed variants apart. A strong
+// ETag set by a handler identifies the exact bytes of the uncompressed body,
+// so it is turned into a weak ETag ("W/...") when the response is compressed.
+// Both variants then validate against the same If-None-Match, which uses weak
+// comparison.
+func gzipContentTypes(types []string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// Echo's Gzip only wraps the writer when the client accepts gzip
+			if !strings.Contains(c.Request().Header.Get(echo.HeaderAcceptEncoding), "gzip") {
+				return next(c)
+			}
+			gz, ok := c.Response().Writer.(interface{ Unwrap() http.ResponseWriter })
+			if !ok {
+				return next(c)
+			}
+
+			res := c.Response()
+			filter := &gzipTypeFilterWriter{
+				res:   res,
+				gzip:  res.Writer,
+				plain: gz.Unwrap(),
+				types: types,
+			}
+			res.Writer = filter
+			err := next(c)
+
+			if filter.encoding != "" {
+				c.Set(contentEncodingKey, filter.encoding)
+			}
+			return err
+		}
+	}
+}
+
+// contentEncodingKey is the context key under which gzipContentTypes records
+// the Content-Encoding of a response that was already encoded by its handler.
+const contentEncodingKey = "gzip.preservedContentEncoding"
+
+// preserveContentEncoding restores the Content-Encoding header of responses
+// that were encoded by the handler itself, such as precompressed static
+// files. It must be registered directly before middleware.Gzip().
+//
+// When Echo's Gzip didn't write the body, it removes a "gzip" Content-Encoding
+// header on its way out. Behind the Timeout middleware, which buffers headers
+// until the handler chain returns, that would strip the header from a .gz file
+// served as-is and clients would receive compressed bytes they can't decode.
+func preserveContentEncoding() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			if encoding, ok := c.Get(contentEncodingKey).(string); ok {
+				c.Response().Header().Set(echo.HeaderContentEncoding, encoding)
+			}
+			return err
+		}
+	}
+}
+
+// gzipTypeFilterWriter decides, on the first WriteHeader or Write, whether the
+// response goes through the gzip writer or straight to the client.
+type gzipTypeFilterWriter struct {
+	res    *echo.Response
+	gzip   http.ResponseWriter
+	plain  http.ResponseWriter
+	types  []string
+	target http.ResponseWriter
+
+	// encoding is the Content-Encoding the handler set itself, if any
+	encoding string
+}
+
+func (w *gzipTypeFilterWriter) Header() http.Header {
+	return w.plain.Header()
+}
+
+func (w *gzipTypeFilterWriter) WriteHeader(code int) {
+	w.decide(nil)
+	w.target.WriteHeader(code)
+}
+
+func (w *gzipTypeFilterWriter) Write(b []byte) (int, error) {
+	w.decide(b)
+	return w.target.Write(b)
+}
+
+func (w *gzipTypeFilterWriter) Flush() {
+	w.decide(nil)
+	_ = http.NewResponseController(w.target).Flush()
+}
+
+func (w *gzipTypeFilterWriter) Unwrap() http.ResponseWriter {
+	w.decide(nil)
+	return w.target
+}
+
+// decide picks the target writer once, based on the Content-Type. When no
+// Content-Type is set yet it is sniffed from the first body bytes, as net/http
+// would; with neither, the response is left uncompressed.
+func (w *gzipTypeFilterWriter) decide(body []byte) {
+	if w.target != nil {
+		return
+	}
+
+	contentType := w.Header().Get(echo.HeaderContentType)
+	if contentType == "" && len(body) > 0 {
+		contentType = http.DetectContentType(body)
+		w.Header().Set(echo.HeaderContentType, contentType)
+	}
+
+	// Responses that are already encoded (e.g. precompressed static files)
+	// must not be compressed a second time.
+	w.encoding = w.Header().Get(echo.HeaderContentEncoding)
+	alreadyEncoded := w.encoding != ""
+
+	w.target = w.plain
+	if contentType != "" && !alreadyEncoded && compressible(contentType, w.types) {
+		w.target = w.gzip
+		weakenETag(w.Header())
+	}
+	// Later writes skip this wrapper entirely
+	w.res.Writer = w.target
+}
+
+// weakenETag marks a strong ETag as weak. The compressed body is a different
+// byte sequence, so keeping the strong ETag would break byte-range requests
+// and caches that rely on strong validators.
+func weakenETag(h http.Header) {
+	etag := h.Get("ETag")
+	if etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", "W/"+etag)
+	}
+}
+
+// compressible reports whether contentType matches one of types and is not
+// an already-compressed format.
+func compressible(contentType string, types []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if matchesMediaType(mediaType, alreadyCompressedTypes) {
+		return false
+	}
+	return matchesMediaType(mediaType, types)
+}
+
+// matchesMediaType reports whether mediaType equals one of patterns or
+// matches a "type/*" wildcard pattern.
+func matchesMediaType(mediaType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+func TestCompressible(t *testing.T) {
+	types := []string{"text/*", "application/json", "image/svg+xml"}
+
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"text/html; charset=UTF-8", true},
+		{"text/css", true},
+		{"application/json", true},
+		{"image/svg+xml", true},
+		{"application/octet-stream", false},
+		{"image/png", false},
+		{"not a media type", false},
+	}
+
+	for _, tt := range tests {
+		if got := compressible(tt.contentType, types); got != tt.want {
+			t.Errorf("compressible(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+
+	// Already-compressed formats are skipped even when configured
+	if compressible("image/png", []string{"image/*"}) {
+		t.Error("image/png compressed with image/* configured")
+	}
+}
+
+func TestGzipContentTypes(t *testing.T) {
+	body := strings.Repeat("compress me ", 100)
+
+	tests := []struct {
+		name        string
+		contentType string
+		wantGzip    bool
+	}{
+		{name: "configured type", contentType: echo.MIMEApplicationJSON, wantGzip: true},
+		{name: "wildcard type", contentType: echo.MIMETextPlainCharsetUTF8, wantGzip: true},
+		{name: "unlisted type", contentType: echo.MIMEOctetStream, wantGzip: false},
+		{name: "already compressed", contentType: "image/png", wantGzip: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(middleware.Gzip())
+			e.Use(gzipContentTypes([]string{"text/*", "application/json", "image/*"}))
+			e.GET("/", func(c echo.Context) error {
+				return c.Blob(http.StatusOK, tt.contentType, []byte(body))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			gzipped := rec.Header().Get(echo.HeaderContentEncoding) == "gzip"
+			if gzipped != tt.wantGzip {
+				t.Errorf("gzipped = %v, want %v", gzipped, tt.wantGzip)
+			}
+			if !tt.wantGzip && rec.Body.String() != body {
+				t.Errorf("uncompressed body was modified")
+			}
+		})
+	}
+}
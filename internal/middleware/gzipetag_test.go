@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+func TestGzipETag(t *testing.T) {
+	body := strings.Repeat("compress me ", 100)
+
+	tests := []struct {
+		name           string
+		etag           string
+		contentType    string
+		acceptEncoding string
+		want           string
+	}{
+		{name: "compressed strong ETag is weakened", etag: `"v1"`, contentType: echo.MIMETextHTMLCharsetUTF8, acceptEncoding: "gzip", want: `W/"v1"`},
+		{name: "compressed weak ETag is kept", etag: `W/"v1"`, contentType: echo.MIMETextHTMLCharsetUTF8, acceptEncoding: "gzip", want: `W/"v1"`},
+		{name: "uncompressed type keeps a strong ETag", etag: `"v1"`, contentType: "image/png", acceptEncoding: "gzip", want: `"v1"`},
+		{name: "client without gzip keeps a strong ETag", etag: `"v1"`, contentType: echo.MIMETextHTMLCharsetUTF8, want: `"v1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(middleware.Gzip())
+			e.Use(gzipContentTypes([]string{"text/*"}))
+			e.GET("/", func(c echo.Context) error {
+				c.Response().Header().Set("ETag", tt.etag)
+				return c.Blob(http.StatusOK, tt.contentType, []byte(body))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set(echo.HeaderAcceptEncoding, tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("ETag"); got != tt.want {
+				t.Errorf("ETag = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsRegistry holds the metrics served by MetricsHandler: the HTTP
+// request metrics recorded by Metrics, plus Go runtime and process
+// metrics. Register your own collectors on it:
+//
+//	booksCreated := prometheus.NewCounter(prometheus.CounterOpts{
+//	    Name: "books_created_total",
+//	    Help: "Books created.",
+//	})
+//	middleware.MetricsRegistry.MustRegister(booksCreated)
+var MetricsRegistry = prometheus.NewRegistry()
+
+// Request metrics recorded by Metrics.
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by method, route and status code.",
+	}, []string{"method", "path", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests, by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path"})
+)
+
+func init() {
+	MetricsRegistry.MustRegister(
+		httpRequestsTotal,
+		httpRequestDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// unmatchedRoute is the path label for requests that matched no route, so
+// scanners probing random URLs don't create a series per URL.
+const unmatchedRoute = "unmatched"
+
+// methodLabel returns the method label for method. Clients can send any
+// token as the method, so methods outside the standard set are counted as
+// "OTHER" to keep the number of series bounded.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodConnect,
+		http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
+
+// Metrics returns a middleware that counts requests and records their
+// duration on MetricsRegistry.
+//
+// The path label is the route template from c.Path() ("/books/:id"), not
+// the requested URI, so the number of series stays bounded by the number
+// of routes. Nonstandard methods are labeled "OTHER" (see methodLabel).
+func Metrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := c.Response().Status
+			// The error handler runs after this middleware returns, so a
+			// handler error hasn't set the response status yet.
+			if err != nil && !c.Response().Committed {
+				status, _ = errorStatus(err, false)
+			}
+			// A client that disconnected isn't a server error, whatever the
+			// Timeout middleware wrote to the dead connection.
+			if clientGone(c) {
+				status = StatusClientClosedRequest
+			}
+
+			path := c.Path()
+			if path == "" {
+				path = unmatchedRoute
+			}
+			method := methodLabel(c.Request().Method)
+
+			httpRequestsTotal.WithLabelValues(method, path, strconv.Itoa(status)).Inc()
+			httpRequestDuration.WithLabelValues(method, path).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// MetricsHandler serves MetricsRegistry in the Prometheus text format.
+// With a non-empty token, scrapes must send "Authorization: Bearer <token>"
+// (Prometheus' authorization/bearer_token scrape settings); anything else
+// gets 401.
+//
+// Usage:
+//
+//	e.GET(cfg.MetricsPath, middleware.MetricsHandler(cfg.MetricsToken))
+func MetricsHandler(token string) echo.HandlerFunc {
+	handler := echo.WrapHandler(promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{}))
+	want := []byte("Bearer " + token)
+
+	return func(c echo.Context) error {
+		if token != "" {
+			got := []byte(c.Request().Header.Get(echo.HeaderAuthorization))
+			if subtle.ConstantTimeCompare(got, want) != 1 {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+			}
+		}
+		return handler(c)
+	}
+}
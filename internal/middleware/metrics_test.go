@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMethodLabel(t *testing.T) {
+	tests := []struct {
+		method    string
+		wantLabel string
+	}{
+		{method: http.MethodGet, wantLabel: "GET"},
+		{method: http.MethodPost, wantLabel: "POST"},
+		{method: http.MethodOptions, wantLabel: "OPTIONS"},
+		{method: "PURGE", wantLabel: "OTHER"},
+		{method: "get", wantLabel: "OTHER"},
+		{method: "X-RANDOM-1234", wantLabel: "OTHER"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			e := echo.New()
+			e.Use(Metrics())
+			e.Add(tt.method, "/metrics-method", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			counter := httpRequestsTotal.WithLabelValues(tt.wantLabel, "/metrics-method", "200")
+			before := testutil.ToFloat64(counter)
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, "/metrics-method", nil))
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("requests counted under method %q: %v, want 1", tt.wantLabel, got)
+			}
+		})
+	}
+}
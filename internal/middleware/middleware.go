@@ -5,13 +5,18 @@
 // are registered and can modify requests/responses or short-circuit the chain.
 //
 // This package includes:
-//   - Request logging with structured output
+//   - Request logging with structured output (or Combined Log Format)
 //   - Panic recovery with error logging
-//   - Request ID generation for tracing
+//   - Request ID generation for tracing (honoring valid inbound IDs)
+//   - Gateway headers (tenant, role) copied into the request context and logs
 //   - CORS handling for cross-origin requests
-//   - Request timeout to prevent hanging requests
+//   - Content-Security-Policy with per-request nonces
+//   - Request timeout to prevent hanging requests, shortened on request (X-Request-Timeout)
+//   - Per-route request body size limits
+//   - Per-request query budget warnings to catch N+1 queries (development)
 //   - Custom error handling with pretty error pages
 //   - Session/flash message support
+//   - JSON-only API route groups (see APIOnly)
 //
 // Usage:
 //
@@ -22,20 +27,36 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"replace-me/internal/config"
 	"replace-me/internal/logger"
+	"replace-me/internal/tracing"
 
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-// sessionStore is the global session store for flash messages and user sessions.
-// It uses encrypted cookies to store session data securely on the client side.
-var sessionStore *sessions.CookieStore
+// sessionStore is the default session store for flash messages and user sessions.
+// By default it uses encrypted cookies to store session data securely on the
+// client side; with SESSION_BACKEND=postgres the data is kept in the database.
+var sessionStore SessionStore
+
+// sessionOptions are the cookie options of the default session.
+var sessionOptions *sessions.Options
+
+// defaultSession is the session middleware of the default session, set by
+// Setup. Middleware that runs before it can load the session on demand
+// with withSession.
+var defaultSession echo.MiddlewareFunc
 
 // SessionName is the name of the session cookie.
 // Change this if you want a different cookie name in the browser.
@@ -44,57 +65,227 @@ const SessionName = "session"
 // Setup configures all middleware for the Echo instance.
 // Middleware are applied in order, so the sequence matters:
 //  1. RequestID - Adds unique ID to each request for tracing
-//  2. Logger - Logs request details (needs request ID to be set first)
-//  3. Recover - Catches panics and prevents server crashes
-//  4. Timeout - Cancels requests that take too long
-//  5. CORS - Handles cross-origin requests
-//  6. Session - Makes session available to handlers
-//  7. Gzip - Compresses responses (production only)
+//  2. Tracing - Starts an OpenTelemetry span per request (if OTEL_EXPORTER_OTLP_ENDPOINT is set)
+//  3. ContextHeaders - Copies gateway headers (CONTEXT_HEADERS) into the context and logs
+//  4. Logger - Logs request details (needs request ID to be set first)
+//  5. Metrics - Records Prometheus request metrics per route (unless METRICS_PATH is empty)
+//  6. Recover - Catches panics and prevents server crashes
+//  7. SecureHeaders - Sends the CSP (per-request nonce), nosniff, frame, referrer and HSTS headers
+//  8. RateLimit - Answers 429 to clients over the limit (if SetRateLimit was called)
+//  9. BodyLimit - Caps request body size, per route (see BodyLimits)
+// 10. BodyReadTimeout - Answers 408 to request bodies that arrive too slowly
+// 11. QueryBudget - Warns about requests issuing too many queries (development only)
+// 12. RetryBudget - Caps transaction retries per request (REQUEST_RETRY_BUDGET)
+// 13. RequestContext - Marks request contexts for the query context check (development only)
+// 14. Deadline - Advertises the timeout and lets callers shorten it
+// 15. Timeout - Cancels requests that take too long
+// 16. CORS - Handles cross-origin requests (if any origins are allowed)
+// 17. CrossOrigin - Rejects cross-site state-changing requests (CSRF_PROTECTION)
+// 18. RequestConn - Takes a pooled database connection per request (if SetRequestDB was called)
+// 19. Session - Makes session available to handlers
+// 20. Gzip - Compresses responses (production only)
 func Setup(e *echo.Echo, cfg *config.Config) {
 	// Initialize the session store with the secret key from config.
-	// CookieStore encrypts session data and stores it in a browser cookie.
-	// This is simpler than server-side sessions (no Redis/DB needed) but
-	// has a 4KB size limit and sends data on every request.
-	sessionStore = sessions.NewCookieStore([]byte(cfg.SessionSecret))
-	sessionStore.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   86400 * 7, // 7 days
+	// The default CookieStore encrypts session data and stores it in a
+	// browser cookie. This is simpler than server-side sessions (no Redis/DB
+	// needed) but has a 4KB size limit and sends data on every request.
+	// SESSION_BACKEND=postgres keeps the data in the sessions table instead
+	// (see UsePostgresSessions).
+	sessionStore = newSessionStore(cfg.SessionBackend, cfg.SessionSecret)
+	sessionOptions = &sessions.Options{
+		Path:     cfg.SessionCookiePath,
+		Domain:   cfg.SessionCookieDomain, // Empty means host-only cookie
 		HttpOnly: true,      // Prevents JavaScript access (XSS protection)
-		Secure:   cfg.IsProduction(), // HTTPS only in production
+		Secure:   cfg.IsProduction(), // HTTPS only in production (and per request over HTTPS)
 		SameSite: http.SameSiteLaxMode, // CSRF protection
 	}
+	setSessionStoreOptions(sessionStore, sessionOptions, cfg.SessionMaxAge)
+
+	// Determine the client IP (c.RealIP(), used by the request log and
+	// RateLimit) from the connection, or from X-Forwarded-For when the
+	// request came through one of TRUSTED_PROXIES.
+	e.IPExtractor = ipExtractor(cfg.TrustedProxies)
 
 	// Request ID middleware generates a unique ID for each request.
 	// This ID is added to logs and response headers, making it easy to
 	// trace a request through the system and correlate logs.
-	e.Use(middleware.RequestID())
+	// A valid inbound X-Request-ID from a proxy or caller is reused.
+	e.Use(requestIDMiddleware(cfg.RequestIDFormat))
+
+	// Client tracking middleware keeps the request's original context, so
+	// the tracing, logging and metrics middleware and the error handler can
+	// tell a client that disconnected (logged as 499) from a request the
+	// server cancelled. It must run before Deadline and Timeout.
+	e.Use(trackClientMiddleware())
+
+	// Tracing middleware starts an OpenTelemetry span for each request and
+	// puts it in the request context, so database queries made with that
+	// context are traced as its children. It sits outside the logger so
+	// request logs carry the trace ID.
+	if tracing.Enabled() {
+		e.Use(Tracing(cfg.OTelServiceName))
+	}
+
+	// Context headers middleware copies metadata set by the gateway (tenant,
+	// user role, ...) into the request context, where handlers read it with
+	// the reqctx getters and the request log picks it up.
+	if len(cfg.ContextHeaders) > 0 {
+		e.Use(contextHeadersMiddleware(cfg.ContextHeaders))
+	}
 
 	// Custom request logger using our structured logger.
 	// Logs method, path, status, latency, and other useful info.
-	e.Use(requestLoggerMiddleware())
+	// With ACCESS_LOG_FORMAT=combined, Apache-style access log lines are
+	// written to ACCESS_LOG_FILE instead, for tools like GoAccess.
+	if cfg.AccessLogFormat == "combined" {
+		e.Use(combinedLogMiddleware(openAccessLog(cfg.AccessLogFile)))
+	} else {
+		e.Use(requestLoggerMiddleware())
+	}
+
+	// Metrics middleware counts requests and times them per route template
+	// for Prometheus. It wraps Recover, which turns panics into errors, so
+	// panics are counted as 500s. The metrics are served at METRICS_PATH
+	// (see MetricsHandler).
+	if cfg.MetricsPath != "" {
+		e.Use(Metrics())
+	}
 
 	// Recover middleware catches panics in handlers and converts them to errors.
 	// Without this, a panic would crash the entire server. Instead, we log the
 	// panic with stack trace and return a 500 error to the client.
 	e.Use(recoverMiddleware())
 
+	// Secure headers middleware sends the Content-Security-Policy (with a
+	// per-request nonce templates can use, see the csp package) and the
+	// nosniff, frame, referrer and HSTS headers (SECURE_HEADERS). Routes
+	// are registered after Setup, so every response gets them.
+	e.Use(SecureHeaders(cfg))
+
+	// Rate limit middleware rejects clients over RATE_LIMIT_RPS with 429
+	// (see SetRateLimit). It runs before the body and session middleware,
+	// so a flood of requests doesn't get its bodies read or its sessions
+	// loaded; the session is only loaded for the flash shown to a limited
+	// form submission.
+	e.Use(rateLimitMiddleware())
+
+	// Body limit middleware rejects request bodies larger than
+	// MAX_REQUEST_BODY_SIZE with 413. Routes that need more (uploads) or
+	// less (JSON APIs) declare their own limit, see BodyLimits.
+	e.Use(bodyLimitMiddleware(cfg.MaxRequestBodyBytes))
+
+	// Body read timeout middleware answers 408 when a request body isn't
+	// fully received within BODY_READ_TIMEOUT, so slow-trickling clients
+	// can't tie up handlers. WithBodyReadTimeout overrides it per route.
+	e.Use(bodyReadTimeoutMiddleware(cfg.BodyReadTimeout))
+
+	// Query budget middleware counts database queries per request and warns
+	// when a request issues more than QUERY_BUDGET of them, which usually
+	// means an N+1 query. Development only: it's a debugging aid.
+	if cfg.IsDevelopment() && cfg.QueryBudget > 0 {
+		e.Use(queryBudgetMiddleware(cfg.QueryBudget))
+	}
+
+	// Retry budget middleware caps the transaction retries a request may
+	// make on serialization failures (REQUEST_RETRY_BUDGET), so conflicts
+	// under contention don't turn into a retry storm.
+	e.Use(retryBudgetMiddleware(cfg.RequestRetryBudget))
+
+	// Mark request contexts so queries run with context.Background() by
+	// mistake can be flagged (see database.CheckQueryContexts).
+	// Development only, like the check itself.
+	if cfg.IsDevelopment() {
+		e.Use(requestContextMiddleware())
+	}
+
+	// Deadline middleware sends the effective timeout in X-Timeout-Ms and
+	// lets callers shorten it with X-Request-Timeout (never lengthen it).
+	e.Use(deadlineMiddleware(cfg.RequestTimeout))
+
 	// Timeout middleware cancels requests that exceed the configured duration.
 	// This prevents slow handlers from consuming resources indefinitely.
 	// The handler receives a cancelled context and should check ctx.Done().
+	// Streaming routes (SetStreamingRoutes) are skipped: the timeout writer
+	// can't flush.
 	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
 		Timeout: cfg.RequestTimeout,
-		Skipper: func(c echo.Context) bool {
-			// Skip timeout for certain paths if needed (e.g., file uploads, SSE)
-			// return strings.HasPrefix(c.Path(), "/upload")
-			return false
-		},
+		Skipper: isStreamingRoute,
 	}))
 
 	// CORS middleware handles Cross-Origin Resource Sharing.
 	// This is required when your frontend is served from a different domain
 	// than your API (e.g., frontend on localhost:3000, API on localhost:8080).
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: cfg.CORSAllowedOrigins,
+	// With no allowed origins (the default outside development) it's left
+	// out, so browsers only allow same-origin requests.
+	if len(cfg.CORSAllowedOrigins) > 0 {
+		e.Use(corsMiddleware(cfg))
+	}
+
+	// Cross-origin protection rejects state-changing requests sent by other
+	// sites' pages, which would otherwise carry the user's session cookie
+	// (CSRF). On by default outside development (CSRF_PROTECTION).
+	if cfg.CSRFProtection {
+		e.Use(crossOriginMiddleware(cfg.CORSAllowedOrigins))
+	}
+
+	// Request connection middleware takes a database connection for the
+	// request, answering 503 when the pool stays exhausted for
+	// DB_ACQUIRE_TIMEOUT (see SetRequestDB). It runs before the session
+	// middleware, so the postgres session store uses the same connection.
+	e.Use(requestConnMiddleware(cfg.DBAcquireTimeout, cfg.MetricsPath))
+
+	// Session middleware makes the session store available to handlers.
+	// Handlers can then use GetSession() to read/write session data.
+	// With SESSION_SLIDING each request renews the session's expiry.
+	// Sessions added with RegisterSession (e.g. a separate admin session)
+	// get their own cookie and store alongside the default one.
+	defaultSession = sessionMiddleware(SessionName, sessionStore, sessionOptions, cfg.SessionMaxAge, cfg.SessionSliding)
+	e.Use(defaultSession)
+	for _, named := range namedSessions {
+		store, options := named.newStore(cfg)
+		e.Use(sessionMiddleware(named.name, store, options, named.maxAge(cfg), named.opts.Sliding))
+	}
+
+	// Gzip compression reduces response size by 70-90% for text content.
+	// Only enabled in production to avoid slowing down development.
+	// The browser automatically decompresses the response.
+	// Only types listed in GZIP_CONTENT_TYPES are compressed, and responses
+	// that are already encoded (precompressed static files) pass through.
+	if cfg.IsProduction() {
+		e.Use(preserveContentEncoding())
+		e.Use(middleware.Gzip())
+		e.Use(gzipContentTypes(cfg.GzipContentTypes))
+	}
+
+	// Set custom error handler for pretty error pages
+	e.HTTPErrorHandler = customErrorHandler(cfg)
+}
+
+// corsMiddleware returns the CORS middleware configured from cfg.
+// With CORS_DEBUG enabled, every request carrying an Origin header also logs
+// (at debug level) whether the origin was allowed, which configured origin
+// it matched, and the resulting Access-Control-Allow-Origin header.
+//
+// Origins are compared after normalization (see normalizeOrigin), so a
+// configured "http://localhost:80" matches a browser sending "http://localhost".
+func corsMiddleware(cfg *config.Config) echo.MiddlewareFunc {
+	allowedOrigins := make([]string, len(cfg.CORSAllowedOrigins))
+	for i, origin := range cfg.CORSAllowedOrigins {
+		allowedOrigins[i] = normalizeOrigin(origin)
+	}
+
+	// A "*" entry keeps Echo's built-in matching, which answers with a
+	// literal "*" rather than echoing the caller's origin back.
+	var allowOriginFunc func(origin string) (bool, error)
+	if !slices.Contains(allowedOrigins, "*") {
+		allowOriginFunc = func(origin string) (bool, error) {
+			return matchedCORSOrigin(normalizeOrigin(origin), allowedOrigins) != "", nil
+		}
+	}
+
+	cors := middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:    cfg.CORSAllowedOrigins,
+		AllowOriginFunc: allowOriginFunc,
 		AllowMethods: []string{
 			http.MethodGet,
 			http.MethodPost,
@@ -109,43 +300,119 @@ func Setup(e *echo.Echo, cfg *config.Config) {
 			echo.HeaderAccept,
 			echo.HeaderAuthorization,
 			"X-Request-ID",
+			HeaderRequestTimeout,
 			"HX-Request", // HTMX header
 			"HX-Current-URL",
 			"HX-Target",
 			"HX-Trigger",
 		},
+		// Let cross-origin API clients read pagination headers
+		ExposeHeaders: []string{
+			"Link",
+			"X-Total-Count",
+			HeaderTimeoutMs,
+		},
 		AllowCredentials: true, // Allow cookies in cross-origin requests
 		MaxAge:           86400, // Cache preflight response for 24 hours
-	}))
+	})
 
-	// Session middleware makes the session store available to handlers.
-	// Handlers can then use GetSession() to read/write session data.
-	e.Use(sessionMiddleware())
+	if !cfg.CORSDebug {
+		return cors
+	}
 
-	// Gzip compression reduces response size by 70-90% for text content.
-	// Only enabled in production to avoid slowing down development.
-	// The browser automatically decompresses the response.
-	if cfg.IsProduction() {
-		e.Use(middleware.Gzip())
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withCORS := cors(next)
+		return func(c echo.Context) error {
+			origin := c.Request().Header.Get(echo.HeaderOrigin)
+			if origin == "" {
+				// Same-origin or non-browser request: CORS doesn't apply
+				return withCORS(c)
+			}
+
+			err := withCORS(c)
+
+			allowOrigin := c.Response().Header().Get(echo.HeaderAccessControlAllowOrigin)
+			logger.Debug("cors decision",
+				"origin", origin,
+				"allowed", allowOrigin != "",
+				"matched", matchedCORSOrigin(normalizeOrigin(origin), allowedOrigins),
+				"allow_origin", allowOrigin,
+				"preflight", c.Request().Method == http.MethodOptions,
+				"path", c.Request().URL.Path,
+			)
+
+			return err
+		}
 	}
+}
 
-	// Set custom error handler for pretty error pages
-	e.HTTPErrorHandler = customErrorHandler(cfg)
+// matchedCORSOrigin returns the configured allowed origin that origin matches,
+// or "" if none does. Patterns may use "*" and "?" wildcards, as in Echo's
+// CORS middleware (e.g. "https://*.example.com").
+func matchedCORSOrigin(origin string, allowed []string) string {
+	for _, pattern := range allowed {
+		if pattern == "*" || pattern == origin {
+			return pattern
+		}
+		if !strings.ContainsAny(pattern, "*?") {
+			continue
+		}
+		re := regexp.QuoteMeta(pattern)
+		re = strings.ReplaceAll(re, `\*`, ".*")
+		re = strings.ReplaceAll(re, `\?`, ".")
+		if ok, _ := regexp.MatchString("^"+re+"$", origin); ok {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// normalizeOrigin returns origin in a canonical form for comparison: lowercase
+// (scheme and host are case-insensitive) and without the default port for
+// its scheme. Values that aren't scheme://host[:port] are only lowercased.
+//
+// Examples:
+//
+//	normalizeOrigin("HTTP://LocalHost:80")      // "http://localhost"
+//	normalizeOrigin("https://example.com:443")  // "https://example.com"
+//	normalizeOrigin("http://localhost:3000")    // "http://localhost:3000"
+func normalizeOrigin(origin string) string {
+	origin = strings.ToLower(strings.TrimSpace(origin))
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok {
+		return origin
+	}
+	switch scheme {
+	case "http":
+		host = strings.TrimSuffix(host, ":80")
+	case "https":
+		host = strings.TrimSuffix(host, ":443")
+	}
+	return scheme + "://" + host
 }
 
 // requestLoggerMiddleware returns a middleware that logs HTTP requests using structured logging.
 // Each log entry includes: method, path, status, latency, request_id, client_ip, user_agent.
 func requestLoggerMiddleware() echo.MiddlewareFunc {
 	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
-		LogMethod:    true,
-		LogURI:       true,
-		LogStatus:    true,
-		LogLatency:   true,
-		LogRequestID: true,
-		LogRemoteIP:  true,
-		LogUserAgent: true,
-		LogError:     true,
+		LogMethod:      true,
+		LogURI:         true,
+		LogStatus:      true,
+		LogLatency:     true,
+		LogRequestID:   true,
+		LogRemoteIP:    true,
+		LogUserAgent:   true,
+		LogError:       true,
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			// The error handler runs after this middleware returns, so a
+			// handler error hasn't set the response status yet.
+			if v.Error != nil && !c.Response().Committed {
+				v.Status, _ = errorStatus(v.Error, false)
+			}
+			if clientGone(c) {
+				v.Status = StatusClientClosedRequest
+			}
+
 			// Build log entry with request details
 			args := []any{
 				"method", v.Method,
@@ -156,13 +423,21 @@ func requestLoggerMiddleware() echo.MiddlewareFunc {
 				"ip", v.RemoteIP,
 			}
 
+			// Add request-scoped attributes (e.g. gateway context headers)
+			args = append(args, logger.Attrs(c.Request().Context())...)
+
 			// Add error if present
 			if v.Error != nil {
 				args = append(args, "error", v.Error.Error())
 			}
 
-			// Log at appropriate level based on status code
+			// Log at appropriate level based on status code. Requests the
+			// client abandoned are routine, not errors.
 			switch {
+			case v.Status == StatusClientClosedRequest:
+				logger.Info("request cancelled by client", args...)
+			case v.Status == http.StatusGatewayTimeout:
+				logger.Warn("request timed out", args...)
 			case v.Status >= 500:
 				logger.Error("request failed", args...)
 			case v.Status >= 400:
@@ -177,45 +452,162 @@ func requestLoggerMiddleware() echo.MiddlewareFunc {
 }
 
 // recoverMiddleware returns a middleware that recovers from panics.
-// When a panic occurs, it logs the error with stack trace and returns a 500 error.
+// The recovered value is converted to an error and passed to customErrorHandler:
+//   - *echo.HTTPError: passed through unchanged so its status and message are honored.
+//     This supports helpers that panic for control flow; no stack is logged.
+//   - error: wrapped as "panic: <err>" and logged with a stack trace.
+//   - anything else: formatted with %v and logged with a stack trace.
 func recoverMiddleware() echo.MiddlewareFunc {
-	return middleware.RecoverWithConfig(middleware.RecoverConfig{
-		// LogErrorFunc is called when a panic is recovered
-		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
-			logger.Error("panic recovered",
-				"error", err.Error(),
-				"stack", string(stack),
-				"request_id", c.Response().Header().Get(echo.HeaderXRequestID),
-				"path", c.Request().URL.Path,
-			)
-			return err
-		},
-	})
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (returnErr error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				// http.ErrAbortHandler is a deliberate signal to net/http to abort
+				// the response; it must keep propagating.
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+				// Hand the error to the error handler here (like Echo's Recover does)
+				// so the response status is set before the request logger runs.
+				c.Error(panicToError(c, r))
+			}()
+			return next(c)
+		}
+	}
 }
 
-// sessionMiddleware returns a middleware that initializes the session for each request.
-// The session is stored in the Echo context and can be retrieved with GetSession().
-func sessionMiddleware() echo.MiddlewareFunc {
+// panicStackSize is the maximum number of bytes of stack trace logged for a panic.
+const panicStackSize = 4 << 10 // 4 KB
+
+// panicToError converts a recovered panic value into an error and logs it.
+func panicToError(c echo.Context, recovered any) error {
+	requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var err error
+	switch v := recovered.(type) {
+	case *echo.HTTPError:
+		// Intentional control flow, not a crash: no stack, no error-level log.
+		logger.Debug("http error panic recovered",
+			"code", v.Code,
+			"request_id", requestID,
+			"path", c.Request().URL.Path,
+		)
+		return v
+	case error:
+		err = fmt.Errorf("panic: %w", v)
+	default:
+		err = fmt.Errorf("panic: %v", v)
+	}
+
+	stack := make([]byte, panicStackSize)
+	stack = stack[:runtime.Stack(stack, false)]
+
+	logger.Error("panic recovered",
+		"error", err.Error(),
+		"stack", string(stack),
+		"request_id", requestID,
+		"path", c.Request().URL.Path,
+	)
+	return err
+}
+
+// sessionMiddleware returns a middleware that initializes the named session for each request.
+// The session is stored in the Echo context and can be retrieved with GetSession()
+// (or GetSessionNamed() for sessions added with RegisterSession).
+func sessionMiddleware(name string, store SessionStore, storeOptions *sessions.Options, maxAge time.Duration, sliding bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Get or create session for this request
-			session, err := sessionStore.Get(c.Request(), SessionName)
+			session, err := store.Get(c.Request(), name)
 			if err != nil {
 				// Session decode error (e.g., invalid signature) - create new session
-				logger.Warn("session decode error, creating new session", "error", err.Error())
-				session, _ = sessionStore.New(c.Request(), SessionName)
+				logger.Warn("session decode error, creating new session", "session", name, "error", err.Error())
+				session, _ = store.New(c.Request(), name)
+			}
+
+			// The signed cookie value is only checked against the store's
+			// MaxAge since it was last saved, so enforce the session's own
+			// expiry here. Expired sessions start over empty.
+			now := time.Now()
+			expiresAt, ok := session.Values[sessionExpiresKey].(int64)
+			if ok && now.Unix() >= expiresAt {
+				clear(session.Values)
+				session.IsNew = true
+				ok = false
+			}
+			if !ok || sliding {
+				expiresAt = now.Add(maxAge).Unix()
+				session.Values[sessionExpiresKey] = expiresAt
+			}
+			pruneStaleFlashes(session, now)
+
+			// Tell the browser when the session expires rather than resetting
+			// the full max age on every save.
+			options := *storeOptions
+			options.MaxAge = max(int(expiresAt-now.Unix()), 1)
+			// Mark the cookie Secure whenever the request came over HTTPS
+			// (directly or via a proxy's X-Forwarded-Proto), not only in
+			// production, so local HTTPS setups (mkcert) behave like prod.
+			options.Secure = options.Secure || c.Scheme() == "https"
+			session.Options = &options
+
+			// Browsers silently drop cookies whose Domain doesn't cover the
+			// request host, which shows up as "sessions randomly don't work".
+			// Warn once so the misconfiguration is visible in the logs.
+			if domain := storeOptions.Domain; domain != "" && !cookieDomainMatches(c.Request().Host, domain) {
+				cookieDomainWarning.Do(func() {
+					logger.Warn("session cookie domain does not match request host, browsers will reject the cookie",
+						"cookie_domain", domain,
+						"host", c.Request().Host,
+					)
+				})
 			}
 
 			// Store session in context for handlers to access
-			c.Set("session", session)
+			c.Set(sessionContextKey(name), session)
+
+			// Without sliding expiry the cookie only needs rewriting when the
+			// session is new or a handler changed it. Sliding sessions are
+			// saved on every request to push the expiry forward.
+			//
+			// A new session is only saved once a handler puts something in
+			// it. Otherwise every request without a cookie (assets, probes,
+			// bots) would write a row or key to a server-side store that
+			// lives for SESSION_MAX_AGE.
+			before := fmt.Sprint(session.Values)
+			changed := func() bool {
+				if session.IsNew && !hasSessionData(session) {
+					return false
+				}
+				return sliding || session.IsNew || fmt.Sprint(session.Values) != before
+			}
+
+			// Save the session just before the response headers are written.
+			// session.Save sets a Set-Cookie header, which is silently dropped
+			// once the handler has started writing the body, so saving after
+			// the handler returns would lose changes for most responses.
+			saved := false
+			save := func() {
+				if saved || !changed() {
+					return
+				}
+				saved = true
+				if saveErr := session.Save(c.Request(), c.Response()); saveErr != nil {
+					logger.Error("failed to save session", "error", saveErr.Error())
+				}
+			}
+			c.Response().Before(save)
 
 			// Call the next handler
 			err = next(c)
 
-			// Save session after handler completes (even on error)
-			// This persists any changes made to the session
-			if saveErr := session.Save(c.Request(), c.Response()); saveErr != nil {
-				logger.Error("failed to save session", "error", saveErr.Error())
+			// Handlers that write nothing never trigger the Before hook;
+			// save now so their session changes still persist (even on error).
+			if !c.Response().Committed {
+				save()
 			}
 
 			return err
@@ -223,21 +615,93 @@ func sessionMiddleware() echo.MiddlewareFunc {
 	}
 }
 
-// GetSession retrieves the session from the Echo context.
+// sessionExpiresKey holds the Unix time at which a session expires.
+const sessionExpiresKey = "_expires_at"
+
+// hasSessionData reports whether session holds anything besides its expiry.
+func hasSessionData(session *sessions.Session) bool {
+	for key := range session.Values {
+		if key != sessionExpiresKey {
+			return true
+		}
+	}
+	return false
+}
+
+// flashAddedKey holds the Unix time at which the newest unread flash message
+// was added. Unread flashes are kept or dropped together: a message added
+// by the latest redirect must not be discarded because an older one on the
+// same session went stale.
+const flashAddedKey = "_flash_at"
+
+// flashMaxAge is how long an unread flash message is kept. Flashes are meant
+// for the very next page; one still unread after this long belongs to a
+// redirect the user never followed and would otherwise show up out of
+// context on some later page.
+const flashMaxAge = 10 * time.Minute
+
+// pruneStaleFlashes discards the unread flash messages once the newest of
+// them is older than flashMaxAge.
+func pruneStaleFlashes(session *sessions.Session, now time.Time) {
+	addedAt, ok := session.Values[flashAddedKey].(int64)
+	if !ok || now.Sub(time.Unix(addedAt, 0)) < flashMaxAge {
+		return
+	}
+	for _, flashType := range flashTypes {
+		delete(session.Values, flashType)
+	}
+	delete(session.Values, flashAddedKey)
+}
+
+// cookieDomainWarning ensures the cookie domain mismatch warning is logged only once.
+var cookieDomainWarning sync.Once
+
+// cookieDomainMatches reports whether a cookie with the given Domain attribute
+// would be accepted by a browser for the given request host (RFC 6265 domain-match).
+// A leading dot on the domain is ignored, as browsers do.
+//
+// Examples:
+//
+//	cookieDomainMatches("app.example.com", ".example.com") // true
+//	cookieDomainMatches("example.com", "example.com")      // true
+//	cookieDomainMatches("example.org", "example.com")      // false
+func cookieDomainMatches(host, domain string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// GetSession retrieves the default session from the Echo context.
 // Returns nil if the session middleware is not configured.
 //
 // Usage in handlers:
 //
 //	session := middleware.GetSession(c)
 //	if session != nil {
-//	    session.Values["user_id"] = 123
+//	    session.Values[middleware.SessionUserIDKey] = 123
 //	}
 func GetSession(c echo.Context) *sessions.Session {
-	session, ok := c.Get("session").(*sessions.Session)
-	if !ok {
-		return nil
-	}
-	return session
+	return GetSessionNamed(c, SessionName)
+}
+
+// SessionUserIDKey is the session value holding the logged-in user's ID.
+// Login handlers set it; IsAuthenticated and error reports read it.
+const SessionUserIDKey = "user_id"
+
+// IsAuthenticated reports whether the request's session belongs to a
+// logged-in user, i.e. has a SessionUserIDKey value.
+//
+// Usage:
+//
+//	if !middleware.IsAuthenticated(c) {
+//	    return c.Redirect(http.StatusSeeOther, "/login")
+//	}
+func IsAuthenticated(c echo.Context) bool {
+	return IsAuthenticatedNamed(c, SessionName)
 }
 
 // Flash message types for styling
@@ -248,8 +712,13 @@ const (
 	FlashInfo    = "info"    // Blue - informational
 )
 
+// flashTypes lists the flash types in the order GetFlashes returns them.
+var flashTypes = []string{FlashSuccess, FlashError, FlashWarning, FlashInfo}
+
 // AddFlash adds a flash message to the session.
-// Flash messages are shown once and then automatically removed.
+// Flash messages are shown once and then automatically removed. Messages
+// left unread for more than 10 minutes (a redirect the user never followed)
+// are discarded rather than shown on some unrelated later page.
 // They're useful for showing success/error messages after form submissions.
 //
 // Parameters:
@@ -268,6 +737,7 @@ func AddFlash(c echo.Context, flashType, message string) {
 	}
 	// Store as a structured flash with type and message
 	session.AddFlash(message, flashType)
+	session.Values[flashAddedKey] = time.Now().Unix()
 }
 
 // FlashMessage represents a flash message with its type for styling.
@@ -292,7 +762,7 @@ func GetFlashes(c echo.Context) []FlashMessage {
 	var messages []FlashMessage
 
 	// Get flashes for each type
-	for _, flashType := range []string{FlashSuccess, FlashError, FlashWarning, FlashInfo} {
+	for _, flashType := range flashTypes {
 		flashes := session.Flashes(flashType)
 		for _, flash := range flashes {
 			if msg, ok := flash.(string); ok {
@@ -303,113 +773,11 @@ func GetFlashes(c echo.Context) []FlashMessage {
 			}
 		}
 	}
+	delete(session.Values, flashAddedKey)
 
 	return messages
 }
 
-// customErrorHandler returns an error handler that renders pretty error pages.
-// In development, it shows detailed error information.
-// In production, it shows user-friendly messages without technical details.
-func customErrorHandler(cfg *config.Config) echo.HTTPErrorHandler {
-	return func(err error, c echo.Context) {
-		// Don't handle if response already committed
-		if c.Response().Committed {
-			return
-		}
-
-		// Extract HTTP error code and message
-		code := http.StatusInternalServerError
-		message := "Internal Server Error"
-
-		if he, ok := err.(*echo.HTTPError); ok {
-			code = he.Code
-			if he.Message != nil {
-				message = fmt.Sprintf("%v", he.Message)
-			}
-		} else if cfg.IsDevelopment() {
-			// In development, show the actual error
-			message = err.Error()
-		}
-
-		// Log the error with context
-		requestID := c.Response().Header().Get(echo.HeaderXRequestID)
-		if code >= 500 {
-			logger.Error("http error",
-				"code", code,
-				"error", err.Error(),
-				"request_id", requestID,
-				"path", c.Request().URL.Path,
-			)
-		}
-
-		// Check if client wants JSON (API request)
-		if c.Request().Header.Get("Accept") == "application/json" ||
-			c.Request().Header.Get("Content-Type") == "application/json" {
-			c.JSON(code, map[string]any{
-				"error":      message,
-				"code":       code,
-				"request_id": requestID,
-			})
-			return
-		}
-
-		// For HTMX requests, return a partial HTML error
-		if c.Request().Header.Get("HX-Request") == "true" {
-			c.HTML(code, fmt.Sprintf(`
-				<div class="bg-red-100 border border-red-400 text-red-700 px-4 py-3 rounded mb-4" role="alert">
-					<strong class="font-bold">Error %d</strong>
-					<span class="block sm:inline">%s</span>
-				</div>
-			`, code, message))
-			return
-		}
-
-		// Render full error page for browser requests
-		errorPage := renderErrorPage(code, message, requestID, cfg.IsDevelopment())
-		c.HTML(code, errorPage)
-	}
-}
-
-// renderErrorPage generates an HTML error page.
-// The page styling matches the application's design.
-func renderErrorPage(code int, message, requestID string, isDevelopment bool) string {
-	title := http.StatusText(code)
-	if title == "" {
-		title = "Error"
-	}
-
-	// Additional debug info for development
-	debugInfo := ""
-	if isDevelopment && requestID != "" {
-		debugInfo = fmt.Sprintf(`
-			<p class="text-sm text-gray-500 mt-4">Request ID: %s</p>
-		`, requestID)
-	}
-
-	return fmt.Sprintf(`
-<!DOCTYPE html>
-<html lang="en">
-<head>
-	<meta charset="UTF-8">
-	<meta name="viewport" content="width=device-width, initial-scale=1.0">
-	<title>%d %s - Go Fullstack Starter</title>
-	<link href="/static/css/output.css" rel="stylesheet">
-</head>
-<body class="min-h-screen bg-gray-100 flex items-center justify-center">
-	<div class="text-center p-8">
-		<h1 class="text-6xl font-bold text-gray-800 mb-4">%d</h1>
-		<h2 class="text-2xl font-semibold text-gray-600 mb-4">%s</h2>
-		<p class="text-gray-500 mb-8">%s</p>
-		<a href="/" class="inline-block bg-gray-800 text-white px-6 py-3 rounded hover:bg-gray-700 transition-colors">
-			Go Home
-		</a>
-		%s
-	</div>
-</body>
-</html>
-`, code, title, code, title, message, debugInfo)
-}
-
 // WithTimeout wraps a handler function with a custom timeout.
 // Use this for handlers that need longer or shorter timeouts than the default.
 //
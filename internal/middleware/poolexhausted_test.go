@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"replace-me/internal/config"
+	"replace-me/internal/database"
+	"replace-me/internal/database/dbtest"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestPoolExhaustedResponse(t *testing.T) {
+	db := dbtest.Open(t, &dbtest.Server{})
+	db.SetMaxOpenConns(1)
+
+	held, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Close()
+
+	e := echo.New()
+	e.HTTPErrorHandler = customErrorHandler(&config.Config{})
+	e.GET("/books", func(c echo.Context) error {
+		conn, err := database.Acquire(c.Request().Context(), db, 20*time.Millisecond)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/books", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get("Retry-After"); got != poolExhaustedRetryAfter {
+		t.Errorf("Retry-After = %q, want %q", got, poolExhaustedRetryAfter)
+	}
+}
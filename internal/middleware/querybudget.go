@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"replace-me/internal/database"
+	"replace-me/internal/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// queryBudgetMiddleware counts the database queries each request issues and
+// logs a warning when a request exceeds budget. A handler that issues one
+// query per row of a list (the classic N+1) shows up here long before it
+// shows up as a slow page in production.
+//
+// Only queries run with the request context are counted, so handlers should
+// pass c.Request().Context() to Bun.
+func queryBudgetMiddleware(budget int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := database.WithQueryCounter(req.Context())
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+
+			if count := database.QueryCount(ctx); count > budget {
+				logger.Warn("query budget exceeded",
+					"method", req.Method,
+					"path", req.URL.Path,
+					"route", c.Path(),
+					"queries", count,
+					"budget", budget,
+					"request_id", c.Response().Header().Get(echo.HeaderXRequestID),
+				)
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"time"
+
+	"replace-me/internal/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimitStore holds the token buckets of RateLimit. MemoryRateLimitStore
+// keeps them in process memory; a store shared by several instances (e.g.
+// backed by Redis) only needs to implement this method.
+type RateLimitStore interface {
+	// Allow takes a token from key's bucket. If the bucket is empty it
+	// returns false and how long the client should wait before retrying.
+	Allow(key string) (bool, time.Duration)
+}
+
+// RateLimitConfig configures RateLimit.
+type RateLimitConfig struct {
+	// Store holds the per-client token buckets. Required.
+	Store RateLimitStore
+
+	// KeyFunc identifies the client. Defaults to c.RealIP(), which only
+	// trusts X-Forwarded-For from TRUSTED_PROXIES (see Setup).
+	KeyFunc func(c echo.Context) string
+
+	// Skipper exempts requests from the limit, e.g. static assets and
+	// health probes. Defaults to limiting every request.
+	Skipper func(c echo.Context) bool
+}
+
+// rateLimitMessage is shown to clients that exceeded the limit.
+const rateLimitMessage = "Too many requests, please slow down"
+
+// RateLimit returns a middleware that limits each client to the rate and
+// burst of cfg.Store's token buckets. A client over the limit gets 429 Too
+// Many Requests with a Retry-After header, rendered like any other error
+// (JSON, HTMX partial or error page).
+//
+// A browser form submission over the limit is redirected back to the page
+// it came from with an error flash instead, so the user keeps their place.
+// Only then is the session loaded (see withSession): the global limit
+// runs before the session middleware, so rejecting the other requests
+// never touches the session store.
+//
+// The global limit is registered with SetRateLimit, which Setup places
+// ahead of the body and session middleware. RateLimit can also guard a
+// single route with a stricter store:
+//
+//	e.POST("/login", h.Login, middleware.RateLimit(middleware.RateLimitConfig{Store: loginStore}))
+func RateLimit(cfg RateLimitConfig) echo.MiddlewareFunc {
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = func(c echo.Context) string { return c.RealIP() }
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if cfg.Skipper != nil && cfg.Skipper(c) {
+				return next(c)
+			}
+
+			key := cfg.KeyFunc(c)
+			allowed, retryAfter := cfg.Store.Allow(key)
+			if allowed {
+				return next(c)
+			}
+
+			// Retry-After is in whole seconds; round up so a client that
+			// honours it finds a token waiting.
+			seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+			c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+
+			logger.DebugContext(c.Request().Context(), "rate limit exceeded",
+				"client", key,
+				"path", c.Request().URL.Path,
+				"retry_after", seconds,
+			)
+
+			if back, ok := formRedirectTarget(c); ok {
+				return withSession(c, func(c echo.Context) error {
+					AddFlash(c, FlashError, rateLimitMessage+" and try again in a moment.")
+					return c.Redirect(http.StatusSeeOther, back)
+				})
+			}
+			return echo.NewHTTPError(http.StatusTooManyRequests, rateLimitMessage)
+		}
+	}
+}
+
+// rateLimiter is the global limit registered with SetRateLimit, or nil
+// while rate limiting is off.
+var rateLimiter echo.MiddlewareFunc
+
+// SetRateLimit turns on the global rate limit (see RateLimit). Setup runs
+// it after Recover and SecureHeaders and before the body and session
+// middleware, so a limited request is logged and counted but doesn't read
+// its body or load its session. Call it once during startup, before the
+// server starts accepting requests:
+//
+//	store := middleware.NewMemoryRateLimitStore(cfg.RateLimitRPS, cfg.RateLimitBurst, cfg.RateLimitMaxKeys, cfg.RateLimitIdleTTL)
+//	store.Start()
+//	defer store.Stop()
+//	middleware.SetRateLimit(middleware.RateLimitConfig{Store: store})
+func SetRateLimit(cfg RateLimitConfig) {
+	rateLimiter = RateLimit(cfg)
+}
+
+// rateLimitMiddleware applies the limit registered with SetRateLimit, if
+// any. It is looked up per request, so SetRateLimit may run after Setup.
+func rateLimitMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if rateLimiter == nil {
+				return next(c)
+			}
+			return rateLimiter(next)(c)
+		}
+	}
+}
+
+// withSession calls fn with the default session available. Inside the
+// session middleware that is the request's session; before it (the global
+// rate limit) the session is loaded, and saved after fn, only for this call.
+func withSession(c echo.Context, fn echo.HandlerFunc) error {
+	if GetSession(c) != nil || defaultSession == nil {
+		return fn(c)
+	}
+	return defaultSession(fn)(c)
+}
+
+// formRedirectTarget returns where to send a rate-limited browser form
+// submission: the same-origin page in its Referer. GET requests, API,
+// JSON and HTMX requests, requests when sessions aren't set up and
+// cross-origin referers get the 429 response instead.
+func formRedirectTarget(c echo.Context) (string, bool) {
+	req := c.Request()
+	if req.Method == http.MethodGet || req.Method == http.MethodHead ||
+		errorFormat(c) != errorFormatPage || (GetSession(c) == nil && defaultSession == nil) {
+		return "", false
+	}
+
+	referer, err := url.Parse(req.Header.Get("Referer"))
+	if err != nil || referer.Host != req.Host || referer.Path == "" {
+		return "", false
+	}
+	if referer.RawQuery != "" {
+		return referer.Path + "?" + referer.RawQuery, true
+	}
+	return referer.Path, true
+}
+
+// ipExtractor returns how Echo determines the client IP (c.RealIP()).
+//
+// With no trusted proxies, the connection's remote address is used and
+// X-Forwarded-For is ignored, since any client could forge it. Behind a
+// load balancer or reverse proxy, list its addresses (IPs or CIDR ranges):
+// X-Forwarded-For is then read right to left, skipping the trusted hops,
+// and the first untrusted address is the client.
+//
+// Entries that don't parse are skipped; config validation rejects them.
+func ipExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		ipNet, err := parseTrustedProxy(proxy)
+		if err != nil {
+			continue
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// parseTrustedProxy parses a TRUSTED_PROXIES entry: a CIDR range
+// ("10.0.0.0/8") or a single address ("10.0.0.1").
+func parseTrustedProxy(s string) (*net.IPNet, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		s = netip.PrefixFrom(addr, addr.BitLen()).String()
+	}
+	_, ipNet, err := net.ParseCIDR(s)
+	return ipNet, err
+}
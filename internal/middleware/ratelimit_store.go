@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"replace-me/internal/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// MemoryRateLimitStore keeps one token bucket per client key (e.g. IP) in
+// memory, with bounded memory use:
+//   - At most maxKeys buckets are kept. When a new key arrives at capacity,
+//     the least recently used bucket is evicted.
+//   - A background sweeper removes buckets idle for longer than idleTTL.
+//
+// Eviction fails safe: an evicted client that comes back gets a fresh bucket
+// with a full burst, which is still rate limited, never unlimited. Keep
+// maxKeys well above the number of clients active within one burst window so
+// attackers can't reset their own bucket by flooding the store with new keys.
+//
+// The rate and burst can be changed at runtime with SetLimit, e.g. to tighten
+// limits during an attack without a redeploy.
+//
+// Usage:
+//
+//	store := middleware.NewMemoryRateLimitStore(10, 20, cfg.RateLimitMaxKeys, cfg.RateLimitIdleTTL)
+//	store.Start()
+//	defer store.Stop()
+//	allowed, retryAfter := store.Allow(c.RealIP())
+type MemoryRateLimitStore struct {
+	settings atomic.Pointer[rateLimitSettings]
+	maxKeys  int
+	idleTTL  time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List // of *rateLimitBucket, most recently used first
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// rateLimitSettings is the refill rate and burst size shared by all buckets.
+type rateLimitSettings struct {
+	limit rate.Limit
+	burst int
+}
+
+// rateLimitBucket is the token bucket of a single client key.
+type rateLimitBucket struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewMemoryRateLimitStore creates a store whose buckets refill at rps tokens
+// per second up to burst tokens, holding at most maxKeys buckets and dropping
+// buckets idle for idleTTL. idleTTL must be greater than 0, or every bucket
+// would be swept (config.Validate checks RATE_LIMIT_IDLE_TTL).
+func NewMemoryRateLimitStore(rps float64, burst, maxKeys int, idleTTL time.Duration) *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
+		maxKeys: maxKeys,
+		idleTTL: idleTTL,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	s.SetLimit(rps, burst)
+	return s
+}
+
+// SetLimit changes the refill rate and burst size of every bucket. It is
+// safe to call while requests are being limited, e.g. from a SIGHUP handler
+// or an admin endpoint. Existing buckets switch to the new settings the next
+// time their client makes a request; tokens already in a bucket above the
+// new burst are dropped.
+func (s *MemoryRateLimitStore) SetLimit(rps float64, burst int) {
+	s.settings.Store(&rateLimitSettings{limit: rate.Limit(rps), burst: burst})
+}
+
+// Allow takes a token from key's bucket. If the bucket is empty it returns
+// false and how long the client should wait before retrying.
+func (s *MemoryRateLimitStore) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+	settings := s.settings.Load()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bucket *rateLimitBucket
+	if elem, ok := s.buckets[key]; ok {
+		bucket = elem.Value.(*rateLimitBucket)
+		s.lru.MoveToFront(elem)
+	} else {
+		if s.maxKeys > 0 && s.lru.Len() >= s.maxKeys {
+			s.removeLocked(s.lru.Back())
+		}
+		bucket = &rateLimitBucket{key: key, limiter: rate.NewLimiter(settings.limit, settings.burst)}
+		s.buckets[key] = s.lru.PushFront(bucket)
+	}
+	bucket.lastSeen = now
+
+	// Adopt settings changed by SetLimit since this bucket was last used
+	if bucket.limiter.Limit() != settings.limit {
+		bucket.limiter.SetLimitAt(now, settings.limit)
+	}
+	if bucket.limiter.Burst() != settings.burst {
+		bucket.limiter.SetBurstAt(now, settings.burst)
+	}
+
+	reservation := bucket.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, time.Second
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// Don't consume the token the client isn't allowed to use yet
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// Len returns the number of buckets currently held, for metrics.
+func (s *MemoryRateLimitStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// BucketsGauge returns a gauge reporting Len as rate_limit_buckets, to
+// register on MetricsRegistry. A count pinned at maxKeys means clients are
+// being evicted and RATE_LIMIT_MAX_KEYS may be too low.
+//
+//	middleware.MetricsRegistry.MustRegister(store.BucketsGauge())
+func (s *MemoryRateLimitStore) BucketsGauge() prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "rate_limit_buckets",
+		Help: "Per-client rate limit buckets held in memory.",
+	}, func() float64 {
+		return float64(s.Len())
+	})
+}
+
+// Start runs the idle-bucket sweeper in a background goroutine until Stop
+// is called. The sweeper runs every idleTTL/2, and at most once a second, so
+// the ticker interval is never 0.
+func (s *MemoryRateLimitStore) Start() {
+	interval := max(s.idleTTL/2, time.Second)
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if removed := s.sweep(time.Now()); removed > 0 {
+					logger.Debug("rate limit buckets swept", "removed", removed, "buckets", s.Len())
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the sweeper and waits for it to exit.
+func (s *MemoryRateLimitStore) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// sweep removes buckets not used since now-idleTTL and returns how many
+// were removed. Buckets are ordered by last use, so it stops at the first
+// bucket that is still active.
+func (s *MemoryRateLimitStore) sweep(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for elem := s.lru.Back(); elem != nil; elem = s.lru.Back() {
+		if now.Sub(elem.Value.(*rateLimitBucket).lastSeen) < s.idleTTL {
+			break
+		}
+		s.removeLocked(elem)
+		removed++
+	}
+	return removed
+}
+
+// removeLocked drops a bucket. s.mu must be held.
+func (s *MemoryRateLimitStore) removeLocked(elem *list.Element) {
+	bucket := s.lru.Remove(elem).(*rateLimitBucket)
+	delete(s.buckets, bucket.key)
+}
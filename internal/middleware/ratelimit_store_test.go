@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMemoryRateLimitStoreAllow(t *testing.T) {
+	tests := []struct {
+		name    string
+		rps     float64
+		burst   int
+		maxKeys int
+		// keys are requested in order; want is whether each is allowed
+		keys []string
+		want []bool
+	}{
+		{
+			name:  "burst then limited",
+			rps:   0.001,
+			burst: 2,
+			keys:  []string{"a", "a", "a"},
+			want:  []bool{true, true, false},
+		},
+		{
+			name:  "clients have their own buckets",
+			rps:   0.001,
+			burst: 1,
+			keys:  []string{"a", "b", "a", "b"},
+			want:  []bool{true, true, false, false},
+		},
+		{
+			name:  "zero burst rejects everything",
+			rps:   0.001,
+			burst: 0,
+			keys:  []string{"a"},
+			want:  []bool{false},
+		},
+		{
+			// a is evicted by c and comes back with a full bucket
+			name:    "least recently used bucket is evicted",
+			rps:     0.001,
+			burst:   1,
+			maxKeys: 2,
+			keys:    []string{"a", "b", "c", "a", "c"},
+			want:    []bool{true, true, true, true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryRateLimitStore(tt.rps, tt.burst, tt.maxKeys, time.Minute)
+			for i, key := range tt.keys {
+				allowed, retryAfter := s.Allow(key)
+				if allowed != tt.want[i] {
+					t.Fatalf("request %d (%s): allowed = %v, want %v", i, key, allowed, tt.want[i])
+				}
+				if !allowed && retryAfter <= 0 {
+					t.Errorf("request %d (%s): retryAfter = %s, want > 0", i, key, retryAfter)
+				}
+			}
+			if tt.maxKeys > 0 && s.Len() > tt.maxKeys {
+				t.Errorf("Len = %d, want at most %d", s.Len(), tt.maxKeys)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStoreSetLimit(t *testing.T) {
+	s := NewMemoryRateLimitStore(0.001, 1, 0, time.Minute)
+	if allowed, _ := s.Allow("a"); !allowed {
+		t.Fatal("first request limited")
+	}
+	if allowed, _ := s.Allow("a"); allowed {
+		t.Fatal("second request allowed with a burst of 1")
+	}
+
+	// The bucket adopts the new rate on its next request, after which it
+	// refills at that rate
+	s.SetLimit(1000, 5)
+	s.Allow("a")
+	time.Sleep(10 * time.Millisecond)
+	if allowed, _ := s.Allow("a"); !allowed {
+		t.Error("request limited after SetLimit raised the limit")
+	}
+}
+
+func TestMemoryRateLimitStoreSweep(t *testing.T) {
+	const idleTTL = time.Minute
+
+	tests := []struct {
+		name        string
+		after       time.Duration
+		wantRemoved int
+	}{
+		{name: "active buckets are kept", after: idleTTL / 2},
+		{name: "idle buckets are removed", after: idleTTL, wantRemoved: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryRateLimitStore(1, 1, 0, idleTTL)
+			s.Allow("a")
+			s.Allow("b")
+
+			if removed := s.sweep(time.Now().Add(tt.after)); removed != tt.wantRemoved {
+				t.Errorf("sweep removed %d, want %d", removed, tt.wantRemoved)
+			}
+			if got := testutil.ToFloat64(s.BucketsGauge()); got != float64(2-tt.wantRemoved) {
+				t.Errorf("rate_limit_buckets = %v, want %d", got, 2-tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStoreStartStop(t *testing.T) {
+	// A sub-second idle TTL must not make the sweeper's ticker panic
+	s := NewMemoryRateLimitStore(1, 1, 0, time.Nanosecond)
+	s.Start()
+	s.Stop()
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"replace-me/internal/config"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+)
+
+// fixedRateLimitStore allows every request or none.
+type fixedRateLimitStore struct{ allow bool }
+
+func (s fixedRateLimitStore) Allow(key string) (bool, time.Duration) {
+	return s.allow, time.Second
+}
+
+// switchRateLimitStore allows requests while allow is set.
+type switchRateLimitStore struct{ allow bool }
+
+func (s *switchRateLimitStore) Allow(key string) (bool, time.Duration) {
+	return s.allow, time.Second
+}
+
+// countingSessionStore is a cookie store that counts session loads.
+type countingSessionStore struct {
+	*sessions.CookieStore
+	loads int
+}
+
+func (s *countingSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	s.loads++
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func TestRateLimitLoadsSessionOnDemand(t *testing.T) {
+	tests := []struct {
+		name      string
+		allow     bool
+		method    string
+		headers   map[string]string
+		wantCode  int
+		wantLoads int
+		// wantLocation is the redirect target; empty means no redirect
+		wantLocation string
+	}{
+		{name: "allowed", allow: true, method: http.MethodPost, wantCode: http.StatusOK, wantLoads: 1},
+		{name: "limited GET", method: http.MethodGet, wantCode: http.StatusTooManyRequests},
+		{
+			name:     "limited JSON request",
+			method:   http.MethodPost,
+			headers:  map[string]string{echo.HeaderAccept: echo.MIMEApplicationJSON},
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			name:         "limited form submission",
+			method:       http.MethodPost,
+			headers:      map[string]string{"Referer": "http://example.com/greet?step=2"},
+			wantCode:     http.StatusSeeOther,
+			wantLoads:    1,
+			wantLocation: "/greet?step=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &countingSessionStore{CookieStore: sessions.NewCookieStore([]byte("test-secret"))}
+			defaultSession = sessionMiddleware(SessionName, store, &sessions.Options{Path: "/"}, time.Hour, false)
+			SetRateLimit(RateLimitConfig{Store: fixedRateLimitStore{allow: tt.allow}})
+			t.Cleanup(func() {
+				defaultSession = nil
+				rateLimiter = nil
+			})
+
+			e := echo.New()
+			e.Use(rateLimitMiddleware())
+			e.Use(defaultSession)
+			e.Any("/greet", func(c echo.Context) error {
+				return c.String(http.StatusOK, "hello")
+			})
+
+			req := httptest.NewRequest(tt.method, "/greet", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if store.loads != tt.wantLoads {
+				t.Errorf("session loaded %d times, want %d", store.loads, tt.wantLoads)
+			}
+			if got := rec.Header().Get(echo.HeaderLocation); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			// The flash must reach the page the user is sent back to
+			setCookie := rec.Header().Get(echo.HeaderSetCookie)
+			if hasCookie := strings.HasPrefix(setCookie, SessionName+"="); hasCookie != (tt.wantLocation != "") {
+				t.Errorf("Set-Cookie = %q, want a session cookie only with the redirect", setCookie)
+			}
+		})
+	}
+}
+
+func TestSetupRateLimitsBeforeSession(t *testing.T) {
+	limit := &switchRateLimitStore{allow: true}
+	SetRateLimit(RateLimitConfig{Store: limit})
+	t.Cleanup(func() {
+		defaultSession = nil
+		rateLimiter = nil
+	})
+
+	// Sliding sessions are saved on every request the session middleware
+	// handles, so a Set-Cookie shows whether it ran
+	e := echo.New()
+	Setup(e, &config.Config{
+		Environment:       "development",
+		SessionSecret:     "test-secret-0123456789abcdef0123456789",
+		SessionCookiePath: "/",
+		SessionMaxAge:     time.Hour,
+		SessionSliding:    true,
+	})
+	e.POST("/login", func(c echo.Context) error {
+		GetSession(c).Values[SessionUserIDKey] = int64(42)
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/login", nil))
+	cookie := rec.Header().Get(echo.HeaderSetCookie)
+	if cookie == "" {
+		t.Fatal("no session cookie after login")
+	}
+
+	limit.allow = false
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderCookie, strings.Split(cookie, ";")[0])
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get(echo.HeaderSetCookie); got != "" {
+		t.Errorf("Set-Cookie = %q on a limited request, want the session left alone", got)
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		panicValue any
+		wantStatus int
+	}{
+		{name: "HTTP error keeps its status", panicValue: echo.NewHTTPError(http.StatusTeapot, "short and stout"), wantStatus: http.StatusTeapot},
+		{name: "wrapped HTTP error keeps its status", panicValue: errors.Join(echo.ErrForbidden), wantStatus: http.StatusForbidden},
+		{name: "error", panicValue: errors.New("boom"), wantStatus: http.StatusInternalServerError},
+		{name: "string", panicValue: "boom", wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = customErrorHandler(&config.Config{})
+			e.Use(recoverMiddleware())
+			e.GET("/", func(c echo.Context) error {
+				panic(tt.panicValue)
+			})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	t.Run("abort handler propagates", func(t *testing.T) {
+		e := echo.New()
+		e.Use(recoverMiddleware())
+		e.GET("/", func(c echo.Context) error {
+			panic(http.ErrAbortHandler)
+		})
+
+		defer func() {
+			if r := recover(); r != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want http.ErrAbortHandler", r)
+			}
+		}()
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		t.Error("ServeHTTP returned without panicking")
+	})
+}
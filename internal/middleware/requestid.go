@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"replace-me/internal/logger"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// validRequestID matches inbound request IDs we are willing to trust.
+// Restricting the character set and length prevents log injection
+// (newlines, control characters) and unbounded header values in logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestIDMiddleware returns a middleware that assigns each request an ID.
+//
+// An inbound X-Request-ID (from a client or upstream proxy) is kept when it
+// passes validRequestID, so traces stay continuous across service hops.
+// Invalid inbound IDs are discarded and a fresh ID is generated in the
+// configured format. The chosen ID is echoed back in the response header
+// and is what the request logger records. It is also stored in the request
+// context (logger.WithRequestID), so entries logged with
+// logger.InfoContext(c.Request().Context(), ...) and friends carry it.
+func requestIDMiddleware(format string) echo.MiddlewareFunc {
+	requestID := middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		Generator: requestIDGenerator(format),
+	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withID := requestID(func(c echo.Context) error {
+			req := c.Request()
+			id := c.Response().Header().Get(echo.HeaderXRequestID)
+			c.SetRequest(req.WithContext(logger.WithRequestID(req.Context(), id)))
+			return next(c)
+		})
+		return func(c echo.Context) error {
+			header := c.Request().Header
+			if id := header.Get(echo.HeaderXRequestID); id != "" && !validRequestID.MatchString(id) {
+				header.Del(echo.HeaderXRequestID)
+			}
+			return withID(c)
+		}
+	}
+}
+
+// requestIDGenerator returns the ID generator for the given format:
+//   - "uuid": random (version 4) UUID, e.g. "3b241101-e2bb-4255-8caf-4136c566a962"
+//   - "short": 16 hex characters, e.g. "9f86d081884c7d65"
+//   - anything else: Echo's default 32-character random string
+func requestIDGenerator(format string) func() string {
+	switch format {
+	case "uuid":
+		return func() string {
+			b := randomBytes(16)
+			b[6] = (b[6] & 0x0f) | 0x40 // version 4
+			b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+		}
+	case "short":
+		return func() string {
+			return hex.EncodeToString(randomBytes(8))
+		}
+	default:
+		return nil // RequestIDWithConfig falls back to its default generator
+	}
+}
+
+// randomBytes returns n cryptographically random bytes.
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	// crypto/rand.Read never returns an error on supported platforms.
+	_, _ = rand.Read(b)
+	return b
+}
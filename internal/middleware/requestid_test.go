@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		inbound string
+		want    *regexp.Regexp
+	}{
+		{name: "valid inbound ID is kept", format: "random", inbound: "edge-1:abc.DEF_42", want: regexp.MustCompile(`^edge-1:abc\.DEF_42$`)},
+		{name: "inbound ID with a newline is replaced", format: "short", inbound: "abc\nlevel=error", want: regexp.MustCompile(`^[0-9a-f]{16}$`)},
+		{name: "overlong inbound ID is replaced", format: "short", inbound: strings.Repeat("a", 129), want: regexp.MustCompile(`^[0-9a-f]{16}$`)},
+		{name: "random", format: "random", want: regexp.MustCompile(`^[A-Za-z0-9]{32}$`)},
+		{name: "uuid", format: "uuid", want: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+		{name: "short", format: "short", want: regexp.MustCompile(`^[0-9a-f]{16}$`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(requestIDMiddleware(tt.format))
+			e.GET("/", func(c echo.Context) error {
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.inbound != "" {
+				req.Header.Set(echo.HeaderXRequestID, tt.inbound)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(echo.HeaderXRequestID); !tt.want.MatchString(got) {
+				t.Errorf("X-Request-ID = %q, want match for %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"replace-me/internal/database"
+
+	"github.com/labstack/echo/v4"
+)
+
+// retryBudgetMiddleware gives each request a budget of transaction retries
+// shared by all its database.RetryOnSerialization calls. Under heavy
+// contention every request retrying several times multiplies the load that
+// caused the conflicts; with a budget, a request that keeps conflicting
+// fails instead.
+//
+// Only transactions run with the request context draw on the budget, so
+// handlers should pass c.Request().Context() to services.
+func retryBudgetMiddleware(budget int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			c.SetRequest(req.WithContext(database.WithRetryBudget(req.Context(), budget)))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"replace-me/internal/config"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+func TestServerErrorPage(t *testing.T) {
+	SetServerErrorPage(func(code int, message, requestID string) templ.Component {
+		return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+			_, err := fmt.Fprintf(w, "server error %d: %s (request %s)", code, message, requestID)
+			return err
+		})
+	})
+	t.Cleanup(func() { SetServerErrorPage(nil) })
+
+	handle := customErrorHandler(&config.Config{})
+
+	tests := []struct {
+		name       string
+		err        error
+		accept     string
+		wantStatus int
+		want       string
+		notWant    string
+	}{
+		{
+			name:       "5xx browser gets the server error page",
+			err:        echo.NewHTTPError(http.StatusBadGateway, "Upstream unavailable"),
+			accept:     "text/html",
+			wantStatus: http.StatusBadGateway,
+			want:       "server error 502: Upstream unavailable (request req-1)",
+		},
+		{
+			name:       "4xx browser keeps the generic page",
+			err:        echo.NewHTTPError(http.StatusNotFound, "Not here"),
+			accept:     "text/html",
+			wantStatus: http.StatusNotFound,
+			want:       "Not here",
+			notWant:    "server error",
+		},
+		{
+			name:       "5xx JSON client gets JSON",
+			err:        echo.NewHTTPError(http.StatusBadGateway, "Upstream unavailable"),
+			accept:     "application/json",
+			wantStatus: http.StatusBadGateway,
+			want:       `"request_id":"req-1"`,
+			notWant:    "server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.Response().Header().Set(echo.HeaderXRequestID, "req-1")
+
+			handle(tt.err, c)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, tt.want) {
+				t.Errorf("body %q does not contain %q", body, tt.want)
+			}
+			if tt.notWant != "" && strings.Contains(body, tt.notWant) {
+				t.Errorf("body %q contains %q", body, tt.notWant)
+			}
+		})
+	}
+}
+
+func TestServerErrorPageAfterTimeout(t *testing.T) {
+	SetServerErrorPage(func(code int, message, requestID string) templ.Component {
+		return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, "server error page")
+			return err
+		})
+	})
+	t.Cleanup(func() { SetServerErrorPage(nil) })
+
+	// The Timeout middleware cancels the request context before the error
+	// handler runs; the page must still be rendered.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	customErrorHandler(&config.Config{})(echo.ErrServiceUnavailable, c)
+
+	if got := rec.Body.String(); got != "server error page" {
+		t.Errorf("body = %q, want the server error page", got)
+	}
+}
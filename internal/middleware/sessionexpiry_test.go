@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestSessionExpiry(t *testing.T) {
+	tests := []struct {
+		name        string
+		sliding     bool
+		wantRenewed bool
+	}{
+		{name: "fixed expiry", sliding: false, wantRenewed: false},
+		{name: "sliding expiry", sliding: true, wantRenewed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			Setup(e, &config.Config{
+				Environment:       "development",
+				SessionSecret:     "test-secret-0123456789abcdef0123456789",
+				SessionCookiePath: "/",
+				SessionMaxAge:     time.Hour,
+				SessionSliding:    tt.sliding,
+			})
+			e.GET("/login", func(c echo.Context) error {
+				GetSession(c).Values["user_id"] = 7
+				return c.NoContent(http.StatusOK)
+			})
+			e.GET("/page", func(c echo.Context) error {
+				if GetSession(c).Values["user_id"] != 7 {
+					t.Error("session value lost")
+				}
+				return c.NoContent(http.StatusOK)
+			})
+
+			// Logging in creates the session
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+			cookie := sessionCookie(rec)
+			if cookie == nil {
+				t.Fatal("no session cookie after login")
+			}
+			if cookie.MaxAge < 3590 || cookie.MaxAge > 3600 {
+				t.Errorf("cookie MaxAge = %d, want about 3600", cookie.MaxAge)
+			}
+
+			// A later request that doesn't change the session only rewrites
+			// the cookie with sliding expiry
+			req := httptest.NewRequest(http.MethodGet, "/page", nil)
+			req.AddCookie(cookie)
+			rec = httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if renewed := sessionCookie(rec) != nil; renewed != tt.wantRenewed {
+				t.Errorf("cookie renewed = %v, want %v", renewed, tt.wantRenewed)
+			}
+		})
+	}
+}
+
+// sessionCookie returns the session cookie set by a response, or nil.
+func sessionCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == SessionName {
+			return cookie
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"time"
+
+	"replace-me/internal/config"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+)
+
+// SessionOptions configures a session added with RegisterSession. Zero
+// fields fall back to the default session's settings.
+type SessionOptions struct {
+	// Secret signs and encrypts the cookie. Use a different secret from
+	// SESSION_SECRET so a cookie from one session can never be read as the
+	// other. Falls back to SESSION_SECRET.
+	Secret string
+
+	// Path limits the cookie to part of the site, e.g. "/admin".
+	// Falls back to SESSION_COOKIE_PATH.
+	Path string
+
+	// MaxAge is how long the session lasts. Falls back to SESSION_MAX_AGE.
+	MaxAge time.Duration
+
+	// Sliding renews the session's expiry on every request.
+	Sliding bool
+}
+
+// namedSession is a session registered with RegisterSession.
+type namedSession struct {
+	name string
+	opts SessionOptions
+}
+
+// namedSessions holds the sessions registered with RegisterSession, in
+// registration order.
+var namedSessions []namedSession
+
+// RegisterSession adds a session with its own cookie, stored independently
+// of the default session, for apps with separate authentication contexts
+// (e.g. users and admins). Logging out of one doesn't affect the other.
+// name is the cookie name and must differ from SessionName.
+//
+// Call it during startup, before Setup.
+//
+// Usage:
+//
+//	middleware.RegisterSession("admin_session", middleware.SessionOptions{
+//	    Secret: os.Getenv("ADMIN_SESSION_SECRET"),
+//	    Path:   "/admin",
+//	    MaxAge: 8 * time.Hour,
+//	})
+//	middleware.Setup(e, cfg)
+//
+//	// In admin handlers
+//	session := middleware.GetSessionNamed(c, "admin_session")
+//	session.Values[middleware.SessionUserIDKey] = admin.ID
+func RegisterSession(name string, opts SessionOptions) {
+	namedSessions = append(namedSessions, namedSession{name: name, opts: opts})
+}
+
+// newStore creates the store for the session, using the same backend
+// (SESSION_BACKEND) as the default session, and returns it with its cookie
+// options. Cookie attributes other than Path match the default session's.
+func (s namedSession) newStore(cfg *config.Config) (SessionStore, *sessions.Options) {
+	secret := s.opts.Secret
+	if secret == "" {
+		secret = cfg.SessionSecret
+	}
+	store := newSessionStore(cfg.SessionBackend, secret)
+
+	options := *sessionOptions
+	if s.opts.Path != "" {
+		options.Path = s.opts.Path
+	}
+	setSessionStoreOptions(store, &options, s.maxAge(cfg))
+	return store, &options
+}
+
+// maxAge returns how long the session lasts.
+func (s namedSession) maxAge(cfg *config.Config) time.Duration {
+	if s.opts.MaxAge > 0 {
+		return s.opts.MaxAge
+	}
+	return cfg.SessionMaxAge
+}
+
+// sessionContextKey is the Echo context key holding the named session.
+func sessionContextKey(name string) string {
+	if name == SessionName {
+		return "session"
+	}
+	return "session:" + name
+}
+
+// GetSessionNamed retrieves a session added with RegisterSession from the
+// Echo context. Returns nil if no session with that name is registered.
+func GetSessionNamed(c echo.Context, name string) *sessions.Session {
+	session, ok := c.Get(sessionContextKey(name)).(*sessions.Session)
+	if !ok {
+		return nil
+	}
+	return session
+}
+
+// IsAuthenticatedNamed is IsAuthenticated for a session added with
+// RegisterSession.
+//
+// Usage:
+//
+//	if !middleware.IsAuthenticatedNamed(c, "admin_session") {
+//	    return c.Redirect(http.StatusSeeOther, "/admin/login")
+//	}
+func IsAuthenticatedNamed(c echo.Context, name string) bool {
+	session := GetSessionNamed(c, name)
+	if session == nil {
+		return false
+	}
+	_, ok := session.Values[SessionUserIDKey]
+	return ok
+}
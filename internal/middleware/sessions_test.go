@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRegisterSession(t *testing.T) {
+	const adminSession = "admin_session"
+	RegisterSession(adminSession, SessionOptions{
+		Secret: "admin-secret-0123456789abcdef0123456789",
+		Path:   "/admin",
+		MaxAge: 8 * time.Hour,
+	})
+	t.Cleanup(func() { namedSessions = nil })
+
+	e := echo.New()
+	Setup(e, &config.Config{
+		Environment:       "development",
+		SessionSecret:     "test-secret-0123456789abcdef0123456789",
+		SessionCookiePath: "/",
+		SessionMaxAge:     time.Hour,
+	})
+	e.GET("/admin/login", func(c echo.Context) error {
+		GetSessionNamed(c, adminSession).Values[SessionUserIDKey] = 1
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/forged", func(c echo.Context) error {
+		if IsAuthenticated(c) {
+			t.Error("admin cookie accepted as the default session")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/admin/check", func(c echo.Context) error {
+		if !IsAuthenticatedNamed(c, adminSession) {
+			t.Error("admin session not authenticated after login")
+		}
+		if IsAuthenticated(c) {
+			t.Error("default session authenticated by the admin login")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/login", nil))
+
+	var admin *http.Cookie
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == adminSession {
+			admin = cookie
+		}
+	}
+	if admin == nil {
+		t.Fatal("no admin session cookie")
+	}
+	if admin.Path != "/admin" {
+		t.Errorf("admin cookie Path = %q, want /admin", admin.Path)
+	}
+	if admin.MaxAge < 8*3600-10 || admin.MaxAge > 8*3600 {
+		t.Errorf("admin cookie MaxAge = %d, want about %d", admin.MaxAge, 8*3600)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/check", nil)
+	req.AddCookie(admin)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	// The admin cookie is signed with its own secret, so it can't be
+	// replayed as the default session
+	req = httptest.NewRequest(http.MethodGet, "/forged", nil)
+	req.AddCookie(&http.Cookie{Name: SessionName, Value: admin.Value})
+	e.ServeHTTP(httptest.NewRecorder(), req)
+}
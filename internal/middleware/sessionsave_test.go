@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestSessionSavedBeforeBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+	}{
+		{
+			name: "body written",
+			handler: func(c echo.Context) error {
+				AddFlash(c, FlashSuccess, "saved")
+				return c.String(http.StatusOK, "ok")
+			},
+		},
+		{
+			name: "redirect",
+			handler: func(c echo.Context) error {
+				AddFlash(c, FlashSuccess, "saved")
+				return c.Redirect(http.StatusSeeOther, "/")
+			},
+		},
+		{
+			name: "nothing written",
+			handler: func(c echo.Context) error {
+				AddFlash(c, FlashSuccess, "saved")
+				return nil
+			},
+		},
+		{
+			name: "error returned",
+			handler: func(c echo.Context) error {
+				AddFlash(c, FlashError, "failed")
+				return echo.ErrBadRequest
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			Setup(e, &config.Config{
+				Environment:       "development",
+				SessionSecret:     "test-secret-0123456789abcdef0123456789",
+				SessionCookiePath: "/",
+			})
+			e.GET("/", tt.handler)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var found bool
+			for _, cookie := range rec.Result().Cookies() {
+				found = found || cookie.Name == SessionName
+			}
+			if !found {
+				t.Errorf("no %s cookie in response, headers: %v", SessionName, rec.Header())
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"replace-me/internal/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestSessionCookieSecure(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		setup       func(req *http.Request)
+		wantSecure  bool
+	}{
+		{name: "development over HTTP", environment: "development", wantSecure: false},
+		{
+			name:        "development over HTTPS",
+			environment: "development",
+			setup:       func(req *http.Request) { req.TLS = &tls.ConnectionState{} },
+			wantSecure:  true,
+		},
+		{
+			name:        "development behind a TLS proxy",
+			environment: "development",
+			setup:       func(req *http.Request) { req.Header.Set(echo.HeaderXForwardedProto, "https") },
+			wantSecure:  true,
+		},
+		{name: "production", environment: "production", wantSecure: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			Setup(e, &config.Config{
+				Environment:       tt.environment,
+				SessionSecret:     "test-secret-0123456789abcdef0123456789",
+				SessionCookiePath: "/",
+				SessionMaxAge:     time.Hour,
+			})
+			e.GET("/login", func(c echo.Context) error {
+				GetSession(c).Values["user_id"] = 7
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/login", nil)
+			if tt.setup != nil {
+				tt.setup(req)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			cookie := sessionCookie(rec)
+			if cookie == nil {
+				t.Fatal("no session cookie")
+			}
+			if cookie.Secure != tt.wantSecure {
+				t.Errorf("Secure = %v, want %v", cookie.Secure, tt.wantSecure)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// SessionStore keeps session data between requests. It is the
+// gorilla/sessions Store interface, so GetSession, AddFlash and GetFlashes
+// work the same whichever store is used. SESSION_BACKEND selects one of:
+//   - *sessions.CookieStore ("cookie"): the data is encrypted into the
+//     cookie itself. Nothing to store, but limited to 4KB and sent with
+//     every request.
+//   - *ServerSessionStore over the sessions table ("postgres", see
+//     NewPostgresSessionStore) or over Redis ("redis", see
+//     NewRedisSessionStore): the data is kept server-side and the cookie
+//     only holds a signed session ID.
+//
+// Sessions in the server-side stores can be ended with RevokeSession.
+type SessionStore = sessions.Store
+
+// ErrSessionNotRevocable is returned by RevokeSession when sessions are
+// kept in cookies, which the server can't reach once issued.
+var ErrSessionNotRevocable = errors.New("sessions can't be revoked with the cookie backend")
+
+// RevokeSession ends the session with the given ID (session.ID) in the
+// default session's store, wherever the user is using it: their next
+// request starts a new, empty session. Use it for "log out everywhere" or
+// to cut off a compromised account, keeping track of a user's session IDs
+// as they log in. It works for sessions added with RegisterSession too,
+// which share the store's table or key space.
+//
+// With SESSION_BACKEND=cookie it returns ErrSessionNotRevocable.
+//
+// Usage:
+//
+//	for _, id := range sessionIDs { // recorded at each login
+//	    if err := middleware.RevokeSession(ctx, id); err != nil {
+//	        return err
+//	    }
+//	}
+func RevokeSession(ctx context.Context, id string) error {
+	s, ok := sessionStore.(*ServerSessionStore)
+	if !ok {
+		return ErrSessionNotRevocable
+	}
+	return s.Delete(ctx, id)
+}
+
+// newSessionStore creates the store for a session signed and encrypted with
+// secret, as selected by SESSION_BACKEND.
+func newSessionStore(backend, secret string) SessionStore {
+	switch backend {
+	case "postgres":
+		if sessionDB == nil {
+			panic("middleware: SESSION_BACKEND=postgres requires UsePostgresSessions before Setup")
+		}
+		return NewPostgresSessionStore(sessionDB, []byte(secret))
+	case "redis":
+		if sessionRedis == nil {
+			panic("middleware: SESSION_BACKEND=redis requires UseRedisSessions before Setup")
+		}
+		return NewRedisSessionStore(sessionRedis, []byte(secret))
+	default:
+		return sessions.NewCookieStore([]byte(secret))
+	}
+}
+
+// setSessionStoreOptions applies the cookie options and max age to store.
+func setSessionStoreOptions(store SessionStore, options *sessions.Options, maxAge time.Duration) {
+	switch s := store.(type) {
+	case *sessions.CookieStore:
+		s.Options = options
+		// Sets both the cookie MaxAge and how long the signed value is accepted
+		s.MaxAge(int(maxAge.Seconds()))
+	case *ServerSessionStore:
+		s.Options = options
+		s.MaxAge(int(maxAge.Seconds()))
+	}
+}
+
+// SessionBackend stores encoded session data by session ID for a
+// ServerSessionStore. The postgres and redis backends come with the app;
+// another storage only needs these three methods.
+type SessionBackend interface {
+	// Load returns the data saved for the session, or nil if there is
+	// none or it has expired.
+	Load(ctx context.Context, id string) ([]byte, error)
+	// Save stores the session's data, replacing any previous data, until
+	// ttl from now.
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	// Delete deletes the session. Deleting a missing session is not an
+	// error.
+	Delete(ctx context.Context, id string) error
+}
+
+// ServerSessionStore is a SessionStore that keeps session data in a
+// SessionBackend. The cookie only carries the session ID, signed and
+// encrypted like a CookieStore cookie, so sessions can hold more than 4KB
+// and can be revoked server-side (see RevokeSession).
+type ServerSessionStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+
+	backend SessionBackend
+}
+
+// NewServerSessionStore returns a store keeping sessions in backend.
+// keyPairs sign and encrypt the session ID cookie, as for
+// sessions.NewCookieStore.
+func NewServerSessionStore(backend SessionBackend, keyPairs ...[]byte) *ServerSessionStore {
+	s := &ServerSessionStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: 86400 * 30,
+		},
+		backend: backend,
+	}
+	s.MaxAge(s.Options.MaxAge)
+	return s
+}
+
+// MaxAge sets how long sessions last, in seconds, for the cookie and for
+// the signed session ID.
+func (s *ServerSessionStore) MaxAge(age int) {
+	s.Options.MaxAge = age
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+// Get implements sessions.Store. The session is loaded once per request.
+func (s *ServerSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New implements sessions.Store. It returns the session named by the
+// request's cookie, or a new empty session if there is no cookie or the
+// session has expired or been revoked.
+func (s *ServerSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := *s.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	if err := securecookie.DecodeMulti(name, cookie.Value, &session.ID, s.Codecs...); err != nil {
+		session.ID = ""
+		return session, err
+	}
+
+	data, err := s.backend.Load(r.Context(), session.ID)
+	if err != nil {
+		session.ID = ""
+		return session, err
+	}
+	if data == nil {
+		// Expired or revoked: start over with a fresh ID
+		session.ID = ""
+		return session, nil
+	}
+	if err := (securecookie.GobEncoder{}).Deserialize(data, &session.Values); err != nil {
+		session.ID = ""
+		return session, err
+	}
+	session.IsNew = false
+	return session, nil
+}
+
+// Save implements sessions.Store. It stores the session until its
+// Options.MaxAge from now and sets the session ID cookie. A negative MaxAge
+// deletes the session and its cookie, as for a CookieStore.
+func (s *ServerSessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	ctx := r.Context()
+
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.Delete(ctx, session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = newSessionID()
+	}
+	data, err := (securecookie.GobEncoder{}).Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		// A browser-session cookie: keep the data as long as the store allows
+		maxAge = s.Options.MaxAge
+	}
+	if err := s.backend.Save(ctx, session.ID, data, time.Duration(maxAge)*time.Second); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Delete deletes the session with the given ID, ending it server-side.
+func (s *ServerSessionStore) Delete(ctx context.Context, id string) error {
+	return s.backend.Delete(ctx, id)
+}
+
+// newSessionID returns a random session ID for the server-side stores.
+func newSessionID() string {
+	return strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+}
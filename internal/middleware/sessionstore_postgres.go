@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"replace-me/internal/database"
+	"replace-me/internal/logger"
+
+	"github.com/uptrace/bun"
+)
+
+// sessionDB is the database the postgres session backend uses, set by
+// UsePostgresSessions.
+var sessionDB *bun.DB
+
+// ErrSessionsTableMissing is returned by UsePostgresSessions when the
+// sessions table hasn't been created yet.
+var ErrSessionsTableMissing = errors.New("sessions table does not exist, run the migrations (make migrate-up)")
+
+// UsePostgresSessions makes db the database sessions are stored in when
+// SESSION_BACKEND is "postgres". The sessions table is created by a
+// migration like every other table; UsePostgresSessions checks that it
+// exists so a missing migration fails at startup rather than on the first
+// login. Call it before Setup, and run CleanupSessions in the background to
+// delete expired sessions:
+//
+//	if err := middleware.UsePostgresSessions(ctx, db); err != nil {
+//	    return err
+//	}
+//	go middleware.CleanupSessions(ctx, db, cfg.SessionGCInterval)
+//	middleware.Setup(e, cfg)
+func UsePostgresSessions(ctx context.Context, db *bun.DB) error {
+	// Runs at startup, before any request
+	ctx = database.WithBackgroundContext(ctx)
+	var exists bool
+	if err := db.NewRaw("SELECT to_regclass('sessions') IS NOT NULL").Scan(ctx, &exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrSessionsTableMissing
+	}
+	sessionDB = db
+	return nil
+}
+
+// NewPostgresSessionStore returns a store keeping sessions in the sessions
+// table of db. The cookie only carries the session ID, so sessions can hold
+// more than 4KB and can be revoked by deleting rows. Expired rows are never
+// read, and are deleted by CleanupSessions.
+//
+// keyPairs sign and encrypt the session ID cookie, as for
+// sessions.NewCookieStore. The sessions table must exist (see
+// UsePostgresSessions).
+func NewPostgresSessionStore(db *bun.DB, keyPairs ...[]byte) *ServerSessionStore {
+	return NewServerSessionStore(postgresSessionBackend{db: db}, keyPairs...)
+}
+
+// sessionRow is a session stored by the postgres backend, in the table
+// created by migrations/20261017120000_create_sessions.up.sql.
+type sessionRow struct {
+	bun.BaseModel `bun:"table:sessions"`
+
+	ID        string    `bun:"id,pk"`
+	Data      []byte    `bun:"data,notnull"`
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+}
+
+// postgresSessionBackend is the SessionBackend for the sessions table.
+// Its queries run on the request's connection when there is one (see
+// SetRequestDB).
+type postgresSessionBackend struct {
+	db *bun.DB
+}
+
+// Load implements SessionBackend.
+func (b postgresSessionBackend) Load(ctx context.Context, id string) ([]byte, error) {
+	var row sessionRow
+	err := database.DB(ctx, b.db).NewSelect().
+		Model(&row).
+		Where("id = ?", id).
+		Where("expires_at > ?", time.Now()).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.Data, nil
+}
+
+// Save implements SessionBackend.
+func (b postgresSessionBackend) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	row := &sessionRow{
+		ID:        id,
+		Data:      data,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	_, err := database.DB(ctx, b.db).NewInsert().
+		Model(row).
+		On("CONFLICT (id) DO UPDATE").
+		Set("data = EXCLUDED.data").
+		Set("expires_at = EXCLUDED.expires_at").
+		Exec(ctx)
+	return err
+}
+
+// Delete implements SessionBackend.
+func (b postgresSessionBackend) Delete(ctx context.Context, id string) error {
+	_, err := database.DB(ctx, b.db).NewDelete().Model((*sessionRow)(nil)).Where("id = ?", id).Exec(ctx)
+	return err
+}
+
+// DeleteExpiredSessions deletes the sessions in db that have expired and
+// returns how many were deleted.
+func DeleteExpiredSessions(ctx context.Context, db *bun.DB) (int64, error) {
+	res, err := db.NewDelete().
+		Model((*sessionRow)(nil)).
+		Where("expires_at <= ?", time.Now()).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CleanupSessions deletes expired sessions from db every interval until
+// ctx is cancelled. Run it in the background with the postgres session
+// backend; otherwise the sessions table keeps every session ever created.
+func CleanupSessions(ctx context.Context, db *bun.DB, interval time.Duration) {
+	ctx = database.WithBackgroundContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			n, err := DeleteExpiredSessions(ctx, db)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Error("failed to delete expired sessions", "error", err.Error())
+				}
+				continue
+			}
+			if n > 0 {
+				logger.Debug("deleted expired sessions", "count", n)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
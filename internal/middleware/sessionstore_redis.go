@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisSessionPrefix is prepended to session IDs to form Redis keys.
+const redisSessionPrefix = "session:"
+
+// sessionRedis is the Redis client the redis session backend uses, set by
+// UseRedisSessions.
+var sessionRedis *redis.Client
+
+// UseRedisSessions connects to the Redis server at url (e.g.
+// "redis://:password@localhost:6379/0") and makes it where sessions are
+// stored when SESSION_BACKEND is "redis". Call it before Setup, and close
+// the returned client at shutdown, once requests have stopped:
+//
+//	client, err := middleware.UseRedisSessions(ctx, cfg.RedisURL)
+//	if err != nil {
+//	    return err
+//	}
+//	defer client.Close()
+//	middleware.Setup(e, cfg)
+func UseRedisSessions(ctx context.Context, url string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	sessionRedis = client
+	return client, nil
+}
+
+// NewRedisSessionStore returns a store keeping sessions in Redis under
+// "session:<id>", so every replica behind a load balancer sees the same
+// sessions. keyPairs sign and encrypt the session ID cookie, as for
+// sessions.NewCookieStore.
+//
+// Each save sets the key's TTL to the cookie's MaxAge, so Redis drops a
+// session when its cookie expires; there is nothing to clean up.
+func NewRedisSessionStore(client *redis.Client, keyPairs ...[]byte) *ServerSessionStore {
+	return NewServerSessionStore(redisSessionBackend{client: client}, keyPairs...)
+}
+
+// redisSessionBackend is the SessionBackend for Redis.
+type redisSessionBackend struct {
+	client *redis.Client
+}
+
+// Load implements SessionBackend.
+func (b redisSessionBackend) Load(ctx context.Context, id string) ([]byte, error) {
+	data, err := b.client.Get(ctx, redisSessionPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save implements SessionBackend.
+func (b redisSessionBackend) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return b.client.Set(ctx, redisSessionPrefix+id, data, ttl).Err()
+}
+
+// Delete implements SessionBackend.
+func (b redisSessionBackend) Delete(ctx context.Context, id string) error {
+	return b.client.Del(ctx, redisSessionPrefix+id).Err()
+}
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+)
+
+// memorySessionBackend is a SessionBackend kept in a map.
+type memorySessionBackend struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttls map[string]time.Duration
+}
+
+func newMemorySessionBackend() *memorySessionBackend {
+	return &memorySessionBackend{data: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (b *memorySessionBackend) Load(_ context.Context, id string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data[id], nil
+}
+
+func (b *memorySessionBackend) Save(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data[id] = data
+	b.ttls[id] = ttl
+	return nil
+}
+
+func (b *memorySessionBackend) Delete(_ context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.data, id)
+	delete(b.ttls, id)
+	return nil
+}
+
+func (b *memorySessionBackend) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.data)
+}
+
+var testSessionKey = []byte("0123456789abcdef0123456789abcdef")
+
+// saveSession saves session and returns the cookie it set.
+func saveSession(t *testing.T, store *ServerSessionStore, session *sessions.Session) *http.Cookie {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	if err := store.Save(httptest.NewRequest(http.MethodGet, "/", nil), rec, session); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Save set %d cookies, want 1", len(cookies))
+	}
+	return cookies[0]
+}
+
+func TestServerSessionStore(t *testing.T) {
+	backend := newMemorySessionBackend()
+	store := NewServerSessionStore(backend, testSessionKey)
+	store.MaxAge(3600)
+
+	session, err := store.New(httptest.NewRequest(http.MethodGet, "/", nil), "session")
+	if err != nil {
+		t.Fatalf("New without cookie: %v", err)
+	}
+	if !session.IsNew || session.ID != "" {
+		t.Fatalf("New without cookie: IsNew=%v ID=%q, want a new session without ID", session.IsNew, session.ID)
+	}
+
+	session.Values["user_id"] = 42
+	cookie := saveSession(t, store, session)
+	if session.ID == "" {
+		t.Fatal("Save did not assign a session ID")
+	}
+	if cookie.Value == session.ID {
+		t.Error("cookie holds the raw session ID, want it signed and encrypted")
+	}
+	if got, want := backend.ttls[session.ID], time.Hour; got != want {
+		t.Errorf("saved with TTL %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name       string
+		cookie     *http.Cookie
+		before     func()
+		wantNew    bool
+		wantUserID any
+		wantErr    bool
+	}{
+		{
+			name:       "valid cookie loads the session",
+			cookie:     cookie,
+			wantUserID: 42,
+		},
+		{
+			name:    "tampered cookie starts a new session",
+			cookie:  &http.Cookie{Name: "session", Value: cookie.Value + "x"},
+			wantNew: true,
+			wantErr: true,
+		},
+		{
+			name:    "revoked session starts a new session",
+			cookie:  cookie,
+			before:  func() { store.Delete(context.Background(), session.ID) },
+			wantNew: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.before != nil {
+				tt.before()
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.AddCookie(tt.cookie)
+
+			got, err := store.New(req, "session")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New error = %v, want error %v", err, tt.wantErr)
+			}
+			if got.IsNew != tt.wantNew {
+				t.Errorf("IsNew = %v, want %v", got.IsNew, tt.wantNew)
+			}
+			if tt.wantNew && got.ID != "" {
+				t.Errorf("new session has ID %q, want a fresh one", got.ID)
+			}
+			if got.Values["user_id"] != tt.wantUserID {
+				t.Errorf("user_id = %v, want %v", got.Values["user_id"], tt.wantUserID)
+			}
+		})
+	}
+}
+
+func TestServerSessionStoreDeleteOnNegativeMaxAge(t *testing.T) {
+	backend := newMemorySessionBackend()
+	store := NewServerSessionStore(backend, testSessionKey)
+
+	session := sessions.NewSession(store, "session")
+	session.Options = &sessions.Options{Path: "/", MaxAge: 60}
+	session.Values["user_id"] = 1
+	saveSession(t, store, session)
+
+	session.Options.MaxAge = -1
+	cookie := saveSession(t, store, session)
+	if backend.len() != 0 {
+		t.Errorf("backend holds %d sessions after logout, want 0", backend.len())
+	}
+	if cookie.MaxAge >= 0 {
+		t.Errorf("cookie MaxAge = %d, want it deleted", cookie.MaxAge)
+	}
+}
+
+func TestSessionMiddlewarePersistence(t *testing.T) {
+	tests := []struct {
+		name        string
+		sliding     bool
+		handler     func(c echo.Context) error
+		wantStored  int
+		wantCookies int
+	}{
+		{
+			name:    "untouched new session is not stored",
+			handler: func(c echo.Context) error { return c.NoContent(http.StatusOK) },
+		},
+		{
+			name:    "untouched new session is not stored with sliding expiry",
+			sliding: true,
+			handler: func(c echo.Context) error { return c.String(http.StatusOK, "ok") },
+		},
+		{
+			name: "new session with values is stored",
+			handler: func(c echo.Context) error {
+				GetSession(c).Values[SessionUserIDKey] = 7
+				return c.String(http.StatusOK, "ok")
+			},
+			wantStored:  1,
+			wantCookies: 1,
+		},
+		{
+			name: "new session with a flash is stored",
+			handler: func(c echo.Context) error {
+				AddFlash(c, FlashSuccess, "saved")
+				return c.NoContent(http.StatusOK)
+			},
+			wantStored:  1,
+			wantCookies: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newMemorySessionBackend()
+			store := NewServerSessionStore(backend, testSessionKey)
+			options := &sessions.Options{Path: "/", HttpOnly: true}
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			handler := sessionMiddleware(SessionName, store, options, time.Hour, tt.sliding)(tt.handler)
+			if err := handler(c); err != nil {
+				t.Fatalf("handler: %v", err)
+			}
+
+			if got := backend.len(); got != tt.wantStored {
+				t.Errorf("stored %d sessions, want %d", got, tt.wantStored)
+			}
+			if got := len(rec.Result().Cookies()); got != tt.wantCookies {
+				t.Errorf("set %d cookies, want %d", got, tt.wantCookies)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// streamingRoutes holds the routes registered with SetStreamingRoutes.
+var streamingRoutes map[string]bool
+
+// SetStreamingRoutes declares the routes that stream their response
+// (Server-Sent Events, CSV exports). They skip the global Timeout
+// middleware: it runs handlers behind http.TimeoutHandler, whose writer
+// buffers the whole response and can't flush, so the first Flush would
+// panic. Streaming handlers bound each write with STREAM_WRITE_TIMEOUT
+// instead.
+//
+// Each entry is a route path as registered ("/events"), optionally
+// prefixed with a method ("GET /books/export.csv"). Call it once during
+// startup, before the server starts accepting requests:
+//
+//	middleware.SetStreamingRoutes("/events", "GET /books/export.csv")
+func SetStreamingRoutes(routes ...string) {
+	streamingRoutes = make(map[string]bool, len(routes))
+	for _, route := range routes {
+		streamingRoutes[strings.TrimSpace(route)] = true
+	}
+}
+
+// isStreamingRoute reports whether the matched route was declared with
+// SetStreamingRoutes.
+func isStreamingRoute(c echo.Context) bool {
+	path := c.Path()
+	return streamingRoutes[path] || streamingRoutes[c.Request().Method+" "+path]
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+func TestStreamingRoutesSkipTimeout(t *testing.T) {
+	t.Cleanup(func() { SetStreamingRoutes() })
+
+	tests := []struct {
+		name      string
+		streaming []string
+		method    string
+		wantSkip  bool
+	}{
+		{name: "undeclared route", method: http.MethodGet},
+		{name: "path", streaming: []string{"/events"}, method: http.MethodGet, wantSkip: true},
+		{name: "method and path", streaming: []string{"GET /events"}, method: http.MethodGet, wantSkip: true},
+		{name: "other method", streaming: []string{"POST /events"}, method: http.MethodGet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetStreamingRoutes(tt.streaming...)
+
+			e := echo.New()
+			e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+				Timeout: time.Second,
+				Skipper: isStreamingRoute,
+			}))
+			flushed := false
+			e.Add(tt.method, "/events", func(c echo.Context) error {
+				c.Response().WriteHeader(http.StatusOK)
+				// Only the unwrapped writer can flush; the timeout writer
+				// panics
+				if _, ok := c.Response().Writer.(http.Flusher); ok {
+					c.Response().Flush()
+					flushed = true
+				}
+				return nil
+			})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(tt.method, "/events", nil))
+			if flushed != tt.wantSkip {
+				t.Errorf("handler could flush = %v, want %v", flushed, tt.wantSkip)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+
+	"replace-me/internal/logger"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing returns a middleware that starts an OpenTelemetry server span for
+// each request, named after the route template ("GET /books/:id"). A
+// traceparent header from the caller is honoured, so the span joins the
+// caller's trace.
+//
+// The span context is stored in the request context, so spans started
+// further down (handlers, services, the database query hook) become its
+// children as long as they are given c.Request().Context(). The trace ID is
+// also added to the request's log attributes.
+//
+// Spans go to the global tracer provider, which discards them until
+// tracing.Init enables exporting. serviceName names the tracer.
+func Tracing(serviceName string) echo.MiddlewareFunc {
+	tracer := otel.Tracer(serviceName)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			ctx, span := tracer.Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(req.URL.Path),
+				),
+			)
+			defer span.End()
+
+			if span.SpanContext().HasTraceID() {
+				ctx = logger.WithAttrs(ctx, "trace_id", span.SpanContext().TraceID().String())
+			}
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+
+			status := c.Response().Status
+			// The error handler runs after this middleware returns, so a
+			// handler error hasn't set the response status yet.
+			if err != nil && !c.Response().Committed {
+				status, _ = errorStatus(err, false)
+			}
+			if clientGone(c) {
+				status = StatusClientClosedRequest
+			}
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if err != nil {
+				span.RecordError(err)
+			}
+			// Per the HTTP semantic conventions, 4xx responses are the
+			// client's fault and leave a server span's status unset.
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return err
+		}
+	}
+}
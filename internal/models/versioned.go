@@ -0,0 +1,31 @@
+package models
+
+// Versioned adds a version column for optimistic concurrency control. Embed
+// it in a model and update the model with database.UpdateWithVersion: the
+// update only succeeds if nobody else changed the row since it was loaded.
+//
+//	type Book struct {
+//	    bun.BaseModel `bun:"table:books,alias:b"`
+//	    models.Versioned
+//
+//	    ID    int64  `bun:"id,pk,autoincrement"`
+//	    Title string `bun:"title,notnull"`
+//	}
+//
+// The table needs a matching column:
+//
+//	version BIGINT NOT NULL DEFAULT 1
+type Versioned struct {
+	Version int64 `bun:"version,notnull,default:1" json:"version"`
+}
+
+// CurrentVersion returns the version the model was loaded with.
+func (v *Versioned) CurrentVersion() int64 {
+	return v.Version
+}
+
+// SetVersion sets the model's version. database.UpdateWithVersion uses it to
+// bump the version on a successful update.
+func (v *Versioned) SetVersion(version int64) {
+	v.Version = version
+}
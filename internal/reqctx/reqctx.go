@@ -0,0 +1,59 @@
+// Package reqctx carries request metadata supplied by an upstream gateway
+// (tenant, user role, ...) in the request context.
+//
+// The context headers middleware copies the headers listed in
+// CONTEXT_HEADERS into the context of every request, and adds them to that
+// request's log entries. Handlers and services read them with the typed
+// getters instead of parsing headers themselves:
+//
+//	tenant := reqctx.TenantID(c.Request().Context())
+//
+// Only enable this behind a gateway that sets (and strips client-supplied)
+// copies of these headers; otherwise clients can choose their own values.
+package reqctx
+
+import (
+	"context"
+	"net/http"
+)
+
+// Well-known gateway headers with typed getters.
+const (
+	HeaderTenantID = "X-Tenant-ID"
+	HeaderUserRole = "X-User-Role"
+)
+
+// headersKey is the context key under which the header values are stored.
+type headersKey struct{}
+
+// WithHeaders returns a context carrying the given header values, keyed by
+// header name. Existing values in ctx are kept unless overwritten.
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	merged := make(map[string]string, len(headers))
+	if existing, ok := ctx.Value(headersKey{}).(map[string]string); ok {
+		for name, value := range existing {
+			merged[name] = value
+		}
+	}
+	for name, value := range headers {
+		merged[http.CanonicalHeaderKey(name)] = value
+	}
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// Header returns the value of the named gateway header stored in ctx, or ""
+// if the request didn't carry it (or it isn't in CONTEXT_HEADERS).
+func Header(ctx context.Context, name string) string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers[http.CanonicalHeaderKey(name)]
+}
+
+// TenantID returns the tenant the gateway resolved for the request.
+func TenantID(ctx context.Context) string {
+	return Header(ctx, HeaderTenantID)
+}
+
+// UserRole returns the role the gateway resolved for the request's user.
+func UserRole(ctx context.Context) string {
+	return Header(ctx, HeaderUserRole)
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"replace-me/internal/database"
+
+	"github.com/uptrace/bun"
+)
+
+// List limits: DefaultListLimit applies when ListOptions.Limit is zero or
+// negative, and larger limits are capped at MaxListLimit.
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// ListOptions selects a page of rows for Repository.List.
+type ListOptions struct {
+	// Limit is the maximum number of rows returned (see DefaultListLimit
+	// and MaxListLimit).
+	Limit int
+	// Offset is the number of rows skipped.
+	Offset int
+	// Sort is the column to order by, prefixed with "-" for descending
+	// order ("-created_at"). It must be one of the repository's sortable
+	// columns. Empty orders by primary key.
+	Sort string
+}
+
+// Repository provides the basic CRUD operations for model T, a Bun model
+// with a single primary key column. Services embed or hold one for the plain
+// queries and add their business rules around it.
+//
+// Every method joins the caller's transaction when ctx carries one (see
+// database.RunInTx), and database errors are passed through
+// database.TranslateError.
+//
+// Usage:
+//
+//	type UserService struct {
+//	    users *services.Repository[models.User]
+//	}
+//
+//	func NewUserService(db *bun.DB) *UserService {
+//	    return &UserService{users: services.NewRepository[models.User](db, "name", "created_at")}
+//	}
+//
+//	users, total, err := s.users.List(ctx, services.ListOptions{
+//	    Limit: 20,
+//	    Sort:  c.QueryParam("sort"), // e.g. "-created_at"; checked against the allowlist
+//	})
+type Repository[T any] struct {
+	db       *bun.DB
+	sortable []string
+}
+
+// NewRepository returns a Repository for model T in db. sortable lists the
+// columns List may order by; user input is checked against it, since column
+// names can't be passed as query arguments.
+func NewRepository[T any](db *bun.DB, sortable ...string) *Repository[T] {
+	return &Repository[T]{db: db, sortable: sortable}
+}
+
+// Create inserts model. Columns filled in by the database, such as an
+// auto-incrementing ID, are scanned back into it.
+func (r *Repository[T]) Create(ctx context.Context, model *T) error {
+	_, err := database.DB(ctx, r.db).NewInsert().Model(model).Exec(ctx)
+	return database.TranslateError(err)
+}
+
+// GetByID returns the row with primary key id, or database.ErrNotFound.
+func (r *Repository[T]) GetByID(ctx context.Context, id any) (*T, error) {
+	model := new(T)
+	if err := r.selectByID(ctx, model, id).Scan(ctx); err != nil {
+		return nil, notFound(err)
+	}
+	return model, nil
+}
+
+// selectByID builds GetByID's query, scanning into model.
+func (r *Repository[T]) selectByID(ctx context.Context, model *T, id any) *bun.SelectQuery {
+	return database.DB(ctx, r.db).NewSelect().
+		Model(model).
+		Where("?TablePKs = ?", id)
+}
+
+// Update writes every column of model to the row with its primary key, or
+// returns database.ErrNotFound if there is no such row.
+//
+// Models embedding models.Versioned are updated with
+// database.UpdateWithVersion instead, so a row changed since model was
+// loaded returns database.ErrStaleObject rather than being overwritten.
+// For them a missing row also returns database.ErrStaleObject, not
+// database.ErrNotFound: the versioned update can't tell the two apart.
+// Call GetByID first when the difference matters.
+func (r *Repository[T]) Update(ctx context.Context, model *T) error {
+	db := database.DB(ctx, r.db)
+	if versioned, ok := any(model).(database.VersionedModel); ok {
+		return database.UpdateWithVersion(ctx, db, versioned)
+	}
+
+	res, err := db.NewUpdate().Model(model).WherePK().Exec(ctx)
+	if err != nil {
+		return database.TranslateError(err)
+	}
+	return affected(res)
+}
+
+// Delete deletes the row with primary key id, or returns
+// database.ErrNotFound if there is no such row. Models with a soft_delete
+// column are soft deleted, as Bun does for any delete query.
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	res, err := r.deleteByID(ctx, id).Exec(ctx)
+	if err != nil {
+		return database.TranslateError(err)
+	}
+	return affected(res)
+}
+
+// deleteByID builds Delete's query.
+func (r *Repository[T]) deleteByID(ctx context.Context, id any) *bun.DeleteQuery {
+	return database.DB(ctx, r.db).NewDelete().
+		Model((*T)(nil)).
+		Where("?TablePKs = ?", id)
+}
+
+// List returns the rows selected by opts and the total number of rows, for
+// building pagination. Rows with equal sort values are ordered by primary
+// key, so pages don't overlap. It returns database.ErrInvalidSort if
+// opts.Sort isn't an allowed column.
+func (r *Repository[T]) List(ctx context.Context, opts ListOptions) ([]T, int, error) {
+	var items []T
+	q, err := r.listQuery(ctx, &items, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := q.ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, database.TranslateError(err)
+	}
+	return items, total, nil
+}
+
+// listQuery builds List's query, scanning into items, or returns
+// database.ErrInvalidSort.
+func (r *Repository[T]) listQuery(ctx context.Context, items *[]T, opts ListOptions) (*bun.SelectQuery, error) {
+	column, desc := strings.CutPrefix(opts.Sort, "-")
+	if column != "" && !r.isSortable(column) {
+		return nil, fmt.Errorf("%w: %q", database.ErrInvalidSort, column)
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+
+	q := database.DB(ctx, r.db).NewSelect().
+		Model(items).
+		Limit(limit).
+		Offset(max(opts.Offset, 0))
+	if column != "" {
+		// Safe to interpolate: column is one of the allowlisted names and
+		// is quoted as an identifier
+		direction := "ASC"
+		if desc {
+			direction = "DESC"
+		}
+		q = q.OrderExpr("?TableAlias.? "+direction, bun.Ident(column))
+	}
+	return q.OrderExpr("?TablePKs"), nil
+}
+
+// isSortable reports whether List may order by column.
+func (r *Repository[T]) isSortable(column string) bool {
+	return slices.Contains(r.sortable, column)
+}
+
+// notFound translates err, turning sql.ErrNoRows into database.ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.ErrNotFound
+	}
+	return database.TranslateError(err)
+}
+
+// affected returns database.ErrNotFound if res reports no rows affected.
+func affected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return database.TranslateError(err)
+	}
+	if n == 0 {
+		return database.ErrNotFound
+	}
+	return nil
+}
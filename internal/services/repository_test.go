@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"replace-me/internal/database"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// widget is a model for the repository tests.
+type widget struct {
+	bun.BaseModel `bun:"table:widgets,alias:w"`
+
+	ID   int64  `bun:",pk,autoincrement"`
+	Name string `bun:",notnull"`
+}
+
+// newTestRepository returns a widget repository on a database that is
+// never connected to: the tests only build queries.
+func newTestRepository(t *testing.T) *Repository[widget] {
+	t.Helper()
+	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN("postgres://localhost:1/none")))
+	db := bun.NewDB(sqldb, pgdialect.New())
+	t.Cleanup(func() { db.Close() })
+	return NewRepository[widget](db, "name")
+}
+
+func TestRepositoryQueries(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+
+	tests := []struct {
+		name  string
+		query func() (string, error)
+		want  string
+	}{
+		{
+			name:  "get by ID",
+			query: func() (string, error) { return r.selectByID(ctx, new(widget), 7).String(), nil },
+			want:  `SELECT "w"."id", "w"."name" FROM "widgets" AS "w" WHERE ("w"."id" = 7)`,
+		},
+		{
+			name:  "delete by ID",
+			query: func() (string, error) { return r.deleteByID(ctx, 7).String(), nil },
+			want:  `DELETE FROM "widgets" AS "w" WHERE ("w"."id" = 7)`,
+		},
+		{
+			name:  "list defaults",
+			query: listSQL(ctx, r, ListOptions{}),
+			want:  `SELECT "w"."id", "w"."name" FROM "widgets" AS "w" ORDER BY "w"."id" LIMIT 20`,
+		},
+		{
+			name:  "list sorted ascending",
+			query: listSQL(ctx, r, ListOptions{Limit: 5, Offset: 10, Sort: "name"}),
+			want:  `SELECT "w"."id", "w"."name" FROM "widgets" AS "w" ORDER BY "w"."name" ASC, "w"."id" LIMIT 5 OFFSET 10`,
+		},
+		{
+			name:  "list sorted descending",
+			query: listSQL(ctx, r, ListOptions{Sort: "-name"}),
+			want:  `SELECT "w"."id", "w"."name" FROM "widgets" AS "w" ORDER BY "w"."name" DESC, "w"."id" LIMIT 20`,
+		},
+		{
+			name:  "limit capped, negative offset ignored",
+			query: listSQL(ctx, r, ListOptions{Limit: 1000, Offset: -5}),
+			want:  `SELECT "w"."id", "w"."name" FROM "widgets" AS "w" ORDER BY "w"."id" LIMIT 100`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("query:\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// listSQL returns a query func rendering List's query for opts.
+func listSQL(ctx context.Context, r *Repository[widget], opts ListOptions) func() (string, error) {
+	return func() (string, error) {
+		var items []widget
+		q, err := r.listQuery(ctx, &items, opts)
+		if err != nil {
+			return "", err
+		}
+		return q.String(), nil
+	}
+}
+
+func TestRepositoryListRejectsSort(t *testing.T) {
+	r := newTestRepository(t)
+
+	tests := []struct {
+		name string
+		sort string
+	}{
+		{name: "column not in the allowlist", sort: "id"},
+		{name: "descending column not in the allowlist", sort: "-secret"},
+		{name: "injection attempt", sort: `name; DROP TABLE widgets`},
+		{name: "quoted identifier", sort: `"name"`},
+		{name: "double minus", sort: "--name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Rejected before any query runs, so the unconnected database
+			// is never reached
+			_, _, err := r.List(context.Background(), ListOptions{Sort: tt.sort})
+			if !errors.Is(err, database.ErrInvalidSort) {
+				t.Errorf("List(Sort: %q) error = %v, want database.ErrInvalidSort", tt.sort, err)
+			}
+		})
+	}
+}
+
+// failingResult is a sql.Result whose RowsAffected fails.
+type failingResult struct{ err error }
+
+func (r failingResult) LastInsertId() (int64, error) { return 0, r.err }
+func (r failingResult) RowsAffected() (int64, error) { return 0, r.err }
+
+// rowsResult is a sql.Result reporting n rows affected.
+type rowsResult int64
+
+func (r rowsResult) LastInsertId() (int64, error) { return 0, nil }
+func (r rowsResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestAffected(t *testing.T) {
+	errDriver := errors.New("driver failed")
+
+	tests := []struct {
+		name    string
+		res     sql.Result
+		wantErr error
+	}{
+		{name: "row affected", res: rowsResult(1)},
+		{name: "no row affected", res: rowsResult(0), wantErr: database.ErrNotFound},
+		{name: "RowsAffected fails", res: failingResult{errDriver}, wantErr: errDriver},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := affected(tt.res); !errors.Is(err, tt.wantErr) {
+				t.Errorf("affected = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@
 //
 //	var ErrUserNotFound = errors.New("user not found")
 //	var ErrInvalidEmail = errors.New("invalid email address")
+//	var ErrUserChanged = errors.New("user was changed by someone else")
 //
 //	type UserService struct {
 //	    db *bun.DB
@@ -50,6 +51,20 @@
 //	    return user, nil
 //	}
 //
+//	// Update uses optimistic locking (models.User embeds models.Versioned):
+//	// if the user was changed since it was loaded, nothing is overwritten.
+//	func (s *UserService) Update(ctx context.Context, user *models.User) error {
+//	    err := database.UpdateWithVersion(ctx, database.DB(ctx, s.db), user)
+//	    if errors.Is(err, database.ErrStaleObject) {
+//	        return ErrUserChanged
+//	    }
+//	    return err
+//	}
+//
+// For plain CRUD, a service can hold a Repository instead of writing each
+// query by hand; it returns database.ErrNotFound for missing rows and
+// checks List sort columns against an allowlist.
+//
 // After creating a service:
 // 1. Add it to handlers.Handlers struct in internal/handlers/handlers.go
 // 2. Initialize it in handlers.New()
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// UpstreamChecker is a health.Checker for an HTTP dependency, such as an
+// external API a service calls. It reports unhealthy when the dependency's
+// health URL can't be reached or answers with a 5xx status.
+//
+// Register it with handlers.New so /readyz includes it:
+//
+//	payments := services.NewUpstreamChecker("payments", "https://payments.internal/healthz")
+//	h := handlers.New(db, cfg, monitor, payments)
+//
+// Any type with Name() and Check(ctx) methods can be registered the same way,
+// so a service can also implement health.Checker itself.
+type UpstreamChecker struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// NewUpstreamChecker creates a checker named name that sends GET requests to url.
+func NewUpstreamChecker(name, url string) *UpstreamChecker {
+	return &UpstreamChecker{
+		name:   name,
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Name implements health.Checker.
+func (c *UpstreamChecker) Name() string { return c.name }
+
+// Check implements health.Checker.
+func (c *UpstreamChecker) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%s returned %s", c.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"replace-me/internal/health"
+)
+
+func TestUpstreamChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "healthy", status: http.StatusOK},
+		{name: "client error still means reachable", status: http.StatusNotFound},
+		{name: "server error", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			checker := NewUpstreamChecker("payments", srv.URL)
+			if err := checker.Check(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+
+		if err := NewUpstreamChecker("payments", srv.URL).Check(context.Background()); err == nil {
+			t.Error("Check() succeeded for a closed server")
+		}
+	})
+
+	t.Run("reported by health.Run under its name", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		report := health.Run(context.Background(), NewUpstreamChecker("payments", srv.URL))
+		if report.Healthy() {
+			t.Error("report is healthy with a failing upstream")
+		}
+		if _, ok := report.Checks["payments"]; !ok {
+			t.Errorf("report has no payments check: %+v", report.Checks)
+		}
+	})
+}
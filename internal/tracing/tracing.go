@@ -0,0 +1,88 @@
+// Package tracing exports OpenTelemetry traces over OTLP/HTTP.
+//
+// Tracing is disabled (a no-op) until Init is called with an endpoint. Once
+// enabled, the request spans started by middleware.Tracing and the query
+// spans added by the database package are batched and sent to the collector.
+//
+// The exporter reads the standard OTEL_EXPORTER_OTLP_* variables itself
+// (OTEL_EXPORTER_OTLP_ENDPOINT, _HEADERS, _TIMEOUT, ...), so an endpoint
+// that needs authentication is configured the usual way, e.g.
+// OTEL_EXPORTER_OTLP_HEADERS="authorization=Bearer <token>".
+//
+// Usage:
+//
+//	// Initialize once at startup, before middleware.Setup and database.New
+//	if err := tracing.Init(ctx, cfg.OTelServiceName, cfg.OTelEndpoint); err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer tracing.Shutdown(ctx)
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// provider is the tracer provider installed by Init, nil while disabled.
+var provider *sdktrace.TracerProvider
+
+// Init installs a global tracer provider that exports spans for serviceName
+// to an OTLP/HTTP collector. An empty endpoint disables tracing.
+//
+// Init also installs the W3C Trace Context and Baggage propagators, so an
+// incoming traceparent header continues the caller's trace.
+//
+// Parameters:
+//   - serviceName: the service.name resource attribute shown in the tracing UI
+//   - endpoint: OTEL_EXPORTER_OTLP_ENDPOINT; only checked for being set, the
+//     exporter reads the variable itself
+func Init(ctx context.Context, serviceName, endpoint string) error {
+	if endpoint == "" {
+		return nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil && !errors.Is(err, resource.ErrSchemaURLConflict) {
+		return err
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return nil
+}
+
+// Enabled reports whether Init enabled tracing.
+func Enabled() bool {
+	return provider != nil
+}
+
+// Shutdown exports the spans still buffered and stops the provider, waiting
+// until ctx is done at most. It is a no-op when tracing is disabled.
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
@@ -1,4 +0,0 @@
--- Placeholder migration (down)
--- Delete this file after creating your first real migration.
-
-SELECT 1;
@@ -1,17 +0,0 @@
--- Placeholder migration file
--- This file exists so the migrations package can compile.
--- Delete this file after creating your first real migration.
---
--- To create a migration:
---   make migrate-create name=create_users
---
--- Example migration:
---   CREATE TABLE users (
---       id BIGSERIAL PRIMARY KEY,
---       email VARCHAR(255) NOT NULL UNIQUE,
---       name VARCHAR(255) NOT NULL,
---       created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
---       updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
---   );
-
-SELECT 1;
@@ -0,0 +1,11 @@
+-- Sessions for SESSION_BACKEND=postgres (see middleware.UsePostgresSessions).
+-- The table is created whatever SESSION_BACKEND is set to, so switching to
+-- the postgres backend later needs no extra migration; with the cookie or
+-- redis backend it simply stays empty.
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR NOT NULL PRIMARY KEY,
+    data BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_expires_at_idx ON sessions (expires_at);
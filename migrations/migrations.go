@@ -12,6 +12,25 @@
 //   - migrations/20240101120000_create_users.up.sql
 //   - migrations/20240101120000_create_users.down.sql
 //
+// Go migrations:
+//
+// Migrations that need logic SQL can't express cleanly (e.g. backfilling a
+// computed column in batches) can be written in Go instead:
+//
+//	make migrate-create name=backfill_slugs go=1
+//
+// This creates migrations/20240101120000_backfill_slugs.go, whose init
+// function registers an up and a down function with Migrations.MustRegister.
+// The version comes from the file name, as for SQL files.
+//
+// Go and SQL migrations form one collection, ordered by version (the
+// timestamp prefix) whatever their kind, so a Go migration runs after every
+// SQL migration created before it and before every one created after it.
+// The Go files register themselves first (their names sort before
+// migrations.go, so their init functions run first), then this package's
+// init adds the embedded SQL migrations. A version must have either a Go
+// file or SQL files, not both.
+//
 // Running migrations:
 //
 //	make migrate-up      # Apply all pending migrations
@@ -34,35 +53,154 @@
 package migrations
 
 import (
+	"context"
 	"embed"
+	"encoding/json"
+	"fmt"
 	"io/fs"
+	"time"
 
 	"github.com/uptrace/bun/migrate"
 )
 
-// sqlMigrations embeds SQL files in this directory.
-// Note: go:embed requires at least one matching file, so we keep a .gitkeep
-// or the first real migration. The Discover call filters to only .sql files.
+// sqlMigrations embeds the SQL migrations in this directory. Only .sql files
+// are embedded, so nothing else placed here ends up in the binary; the
+// pattern needs at least one match to compile, which the sessions migration
+// provides.
 //
 //go:embed *.sql
 var sqlMigrations embed.FS
 
 // Migrations is the migration collection used by the migrate command.
-// It discovers all embedded SQL migrations on initialization.
+// It holds the Go migrations registered by this package's .go files and
+// the embedded SQL migrations.
 var Migrations = migrate.NewMigrations()
 
 func init() {
-	// Check if any SQL files exist before discovering.
-	// This handles the edge case where only .gitkeep exists.
-	entries, err := fs.Glob(sqlMigrations, "*.sql")
-	if err != nil {
+	if err := addSQLMigrations(Migrations, sqlMigrations); err != nil {
 		panic(err)
 	}
-	if len(entries) == 0 {
-		return
+}
+
+// addSQLMigrations adds the SQL migrations found in fsys to ms, which may
+// already hold Go migrations. An fsys without migrations adds nothing.
+func addSQLMigrations(ms *migrate.Migrations, fsys fs.FS) error {
+	// Discover into a separate collection and merge, so a version with both
+	// a Go and an SQL migration fails loudly instead of one silently
+	// replacing the other's up or down function
+	discovered := migrate.NewMigrations()
+	if err := discovered.Discover(fsys); err != nil {
+		return err
 	}
 
-	if err := Migrations.Discover(sqlMigrations); err != nil {
-		panic(err)
+	registered := make(map[string]bool)
+	for _, m := range ms.Sorted() {
+		registered[m.Name] = true
+	}
+	for _, m := range discovered.Sorted() {
+		if registered[m.Name] {
+			return fmt.Errorf("migrations: version %s has both a Go and an SQL migration", m.Name)
+		}
+		ms.Add(m)
+	}
+	return nil
+}
+
+// MigrationStatus describes one migration and whether it has been applied.
+// It encodes to JSON as
+//
+//	{"name": "20240101120000", "applied": true, "migrated_at": "2024-01-02T15:04:05Z", "group": 3}
+//
+// with migrated_at null while the migration is pending.
+type MigrationStatus struct {
+	Name       string    `json:"name"`
+	Applied    bool      `json:"applied"`
+	MigratedAt time.Time `json:"migrated_at"` // zero while pending
+	GroupID    int64     `json:"group"`       // batch it was applied in; 0 while pending
+}
+
+// MarshalJSON implements json.Marshaler, writing MigratedAt as an RFC 3339
+// UTC timestamp, or null while pending.
+func (s MigrationStatus) MarshalJSON() ([]byte, error) {
+	var migratedAt *time.Time
+	if !s.MigratedAt.IsZero() {
+		utc := s.MigratedAt.UTC()
+		migratedAt = &utc
+	}
+	return json.Marshal(struct {
+		Name       string     `json:"name"`
+		Applied    bool       `json:"applied"`
+		MigratedAt *time.Time `json:"migrated_at"`
+		GroupID    int64      `json:"group"`
+	}{s.Name, s.Applied, migratedAt, s.GroupID})
+}
+
+// Status returns every known migration, oldest first, with whether it has
+// been applied to the database behind migrator. It is the structured view
+// behind "migrate status" and the readiness check.
+//
+// Usage:
+//
+//	statuses, err := migrations.Status(ctx, migrator)
+//	for _, s := range statuses {
+//	    if !s.Applied {
+//	        fmt.Println("pending:", s.Name)
+//	    }
+//	}
+func Status(ctx context.Context, migrator *migrate.Migrator) ([]MigrationStatus, error) {
+	ms, err := migrator.MigrationsWithStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(ms))
+	for i, m := range ms {
+		statuses[i] = MigrationStatus{
+			Name:       m.Name,
+			Applied:    m.IsApplied(),
+			MigratedAt: m.MigratedAt,
+			GroupID:    m.GroupID,
+		}
+	}
+	return statuses, nil
+}
+
+// Pending returns the number of statuses that have not been applied.
+func Pending(statuses []MigrationStatus) int {
+	n := 0
+	for _, s := range statuses {
+		if !s.Applied {
+			n++
+		}
+	}
+	return n
+}
+
+// SQLFile returns the name and contents of the embedded SQL file that
+// migration m runs when applied (up) or rolled back (!up). It is how
+// "migrate up --dry-run" shows the SQL without running it.
+//
+// A migration without a file for that direction returns an error wrapping
+// fs.ErrNotExist.
+func SQLFile(m migrate.Migration, up bool) (name string, contents []byte, err error) {
+	suffix := ".down.sql"
+	if up {
+		suffix = ".up.sql"
+	}
+
+	// Discover names a migration by its version, so match the rest of the
+	// file name (comment, optional ".tx") with a wildcard
+	matches, err := fs.Glob(sqlMigrations, m.Name+"_*"+suffix)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(matches) == 0 {
+		return "", nil, fmt.Errorf("no %s file for migration %s: %w", suffix, m.Name, fs.ErrNotExist)
+	}
+
+	contents, err = fs.ReadFile(sqlMigrations, matches[0])
+	if err != nil {
+		return "", nil, err
 	}
+	return matches[0], contents, nil
 }
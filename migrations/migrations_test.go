@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"replace-me/internal/database/dbtest"
+
+	"github.com/uptrace/bun/migrate"
+)
+
+func TestStatus(t *testing.T) {
+	migratedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	srv := &dbtest.Server{Respond: func(ctx context.Context, query string) (dbtest.Result, error) {
+		return dbtest.Rows([]string{"id", "name", "group_id", "migrated_at"},
+			[]any{1, "20260101000000", 1, migratedAt},
+		), nil
+	}}
+	db := dbtest.Open(t, srv)
+
+	collection := migrate.NewMigrations()
+	for _, name := range []string{"20260201000000", "20260101000000"} {
+		collection.Add(migrate.Migration{Name: name})
+	}
+
+	statuses, err := Status(context.Background(), migrate.NewMigrator(db, collection))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []MigrationStatus{
+		{Name: "20260101000000", Applied: true, MigratedAt: migratedAt, GroupID: 1},
+		{Name: "20260201000000"},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("Status() = %+v, want %+v", statuses, want)
+	}
+	if got := Pending(statuses); got != 1 {
+		t.Errorf("Pending() = %d, want 1", got)
+	}
+}
+
+func TestAddSQLMigrations(t *testing.T) {
+	sqlFiles := fstest.MapFS{
+		"20260101000000_create_books.up.sql":   {Data: []byte("CREATE TABLE books (id BIGSERIAL PRIMARY KEY);")},
+		"20260101000000_create_books.down.sql": {Data: []byte("DROP TABLE books;")},
+		"README.md":                            {Data: []byte("not a migration")},
+	}
+
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		// goMigrations are registered before the SQL files are added
+		goMigrations []string
+		wantNames    []string
+		wantErr      bool
+	}{
+		{name: "no migrations", fsys: fstest.MapFS{}},
+		{name: "sql migrations", fsys: sqlFiles, wantNames: []string{"20260101000000"}},
+		{name: "alongside go migrations", fsys: sqlFiles, goMigrations: []string{"20260201000000"}, wantNames: []string{"20260101000000", "20260201000000"}},
+		{name: "version with go and sql", fsys: sqlFiles, goMigrations: []string{"20260101000000"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := migrate.NewMigrations()
+			for _, name := range tt.goMigrations {
+				ms.Add(migrate.Migration{Name: name})
+			}
+
+			err := addSQLMigrations(ms, tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("addSQLMigrations error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var names []string
+			for _, m := range ms.Sorted() {
+				names = append(names, m.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("migrations = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}